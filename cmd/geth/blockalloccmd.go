@@ -0,0 +1,192 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	blockAllocCommand = cli.Command{
+		Name:      "blockalloc",
+		Usage:     "Inspect the genesis contract upgrades scheduled in the bor config",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			blockAllocListCmd,
+			blockAllocDiffCmd,
+		},
+	}
+	blockAllocListCmd = cli.Command{
+		Action:    utils.MigrateFlags(blockAllocList),
+		Name:      "list",
+		Usage:     "List the scheduled block allocs and whether they were applied",
+		ArgsUsage: "",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.BttcMainnetFlag,
+			utils.BttcDonauFlag,
+		},
+		Description: `
+This command lists the block allocs of the bor config stored in the database,
+along with the accounts each of them overrides.`,
+	}
+	blockAllocDiffCmd = cli.Command{
+		Action:    utils.MigrateFlags(blockAllocDiff),
+		Name:      "diff",
+		Usage:     "Show the code and storage changes made by a scheduled block alloc",
+		ArgsUsage: "<blockNum>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.BttcMainnetFlag,
+			utils.BttcDonauFlag,
+		},
+		Description: `
+This command applies the block alloc scheduled at the given block on top of the
+state it will be (or was) applied to, and prints the resulting code, balance and
+storage changes of every overridden account.
+
+Upgrades already part of the chain are diffed against the state of their parent
+block, upcoming ones against the state of the current head.`,
+	}
+)
+
+// readBlockAllocs loads the block allocs scheduled by the chain config stored in
+// the database.
+func readBlockAllocs(db ethdb.Reader) (map[uint64]*bor.BlockAlloc, error) {
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil || config.Bor == nil {
+		return nil, errors.New("database does not contain a bor chain")
+	}
+	return bor.ParseBlockAllocs(config.Bor.BlockAlloc)
+}
+
+func blockAllocList(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	allocs, err := readBlockAllocs(db)
+	if err != nil {
+		return err
+	}
+	head := rawdb.ReadHeadHeader(db)
+	if head == nil {
+		return errors.New("no head block found")
+	}
+	numbers := make([]uint64, 0, len(allocs))
+	for number := range allocs {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	for _, number := range numbers {
+		status := "pending"
+		if number <= head.Number.Uint64() {
+			status = "applied"
+		}
+		fmt.Printf("Block %d (%s)\n", number, status)
+		for _, addr := range allocs[number].Addresses() {
+			fmt.Printf("  %s\n", addr.Hex())
+		}
+	}
+	return nil
+}
+
+func blockAllocDiff(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	number, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	allocs, err := readBlockAllocs(db)
+	if err != nil {
+		return err
+	}
+	alloc, ok := allocs[number]
+	if !ok {
+		return fmt.Errorf("no block alloc scheduled at block %d", number)
+	}
+	// Diff against the parent if the upgrade is already in, the head otherwise
+	header := rawdb.ReadHeadHeader(db)
+	if header == nil {
+		return errors.New("no head block found")
+	}
+	if number > 0 && number <= header.Number.Uint64() {
+		header = rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, number-1), number-1)
+		if header == nil {
+			return fmt.Errorf("header for block %d not found", number-1)
+		}
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(db), nil)
+	if err != nil {
+		return fmt.Errorf("state of block %d unavailable: %v", header.Number, err)
+	}
+	before := statedb.Copy()
+	alloc.Apply(statedb)
+
+	fmt.Printf("Block alloc at block %d, applied on top of block %d (root %x)\n", number, header.Number, header.Root)
+	for _, addr := range alloc.Addresses() {
+		account := alloc.Accounts[addr]
+
+		fmt.Printf("\n%s\n", addr.Hex())
+		if oldHash, newHash := before.GetCodeHash(addr), statedb.GetCodeHash(addr); oldHash == newHash {
+			fmt.Printf("  code:    unchanged %x (%d bytes)\n", newHash, statedb.GetCodeSize(addr))
+		} else {
+			fmt.Printf("  code:    %x (%d bytes) -> %x (%d bytes)\n", oldHash, before.GetCodeSize(addr), newHash, statedb.GetCodeSize(addr))
+		}
+		if account.SetBalance != nil {
+			fmt.Printf("  balance: %v -> %v\n", before.GetBalance(addr), statedb.GetBalance(addr))
+		}
+		keys := make([]common.Hash, 0, len(account.SetStorage))
+		for key := range account.SetStorage {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+
+		for _, key := range keys {
+			if oldValue, newValue := before.GetState(addr, key), statedb.GetState(addr, key); oldValue == newValue {
+				fmt.Printf("  slot %x: unchanged %x\n", key, newValue)
+			} else {
+				fmt.Printf("  slot %x: %x -> %x\n", key, oldValue, newValue)
+			}
+		}
+	}
+	return nil
+}
@@ -116,27 +116,7 @@ func remoteConsole(ctx *cli.Context) error {
 	// Attach to a remotely running geth instance and start the JavaScript console
 	endpoint := ctx.Args().First()
 	if endpoint == "" {
-		path := node.DefaultDataDir()
-		if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
-			path = ctx.GlobalString(utils.DataDirFlag.Name)
-		}
-		if path != "" {
-			if ctx.GlobalBool(utils.RopstenFlag.Name) {
-				// Maintain compatibility with older Geth configurations storing the
-				// Ropsten database in `testnet` instead of `ropsten`.
-				legacyPath := filepath.Join(path, "testnet")
-				if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
-					path = legacyPath
-				} else {
-					path = filepath.Join(path, "ropsten")
-				}
-			} else if ctx.GlobalBool(utils.RinkebyFlag.Name) {
-				path = filepath.Join(path, "rinkeby")
-			} else if ctx.GlobalBool(utils.GoerliFlag.Name) {
-				path = filepath.Join(path, "goerli")
-			}
-		}
-		endpoint = fmt.Sprintf("%s/geth.ipc", path)
+		endpoint = defaultIPCEndpoint(ctx)
 	}
 	client, err := dialRPC(endpoint)
 	if err != nil {
@@ -167,6 +147,32 @@ func remoteConsole(ctx *cli.Context) error {
 	return nil
 }
 
+// defaultIPCEndpoint returns the IPC endpoint of the node running in the data directory
+// selected by the flags.
+func defaultIPCEndpoint(ctx *cli.Context) string {
+	path := node.DefaultDataDir()
+	if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
+		path = ctx.GlobalString(utils.DataDirFlag.Name)
+	}
+	if path != "" {
+		if ctx.GlobalBool(utils.RopstenFlag.Name) {
+			// Maintain compatibility with older Geth configurations storing the
+			// Ropsten database in `testnet` instead of `ropsten`.
+			legacyPath := filepath.Join(path, "testnet")
+			if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+				path = legacyPath
+			} else {
+				path = filepath.Join(path, "ropsten")
+			}
+		} else if ctx.GlobalBool(utils.RinkebyFlag.Name) {
+			path = filepath.Join(path, "rinkeby")
+		} else if ctx.GlobalBool(utils.GoerliFlag.Name) {
+			path = filepath.Join(path, "goerli")
+		}
+	}
+	return fmt.Sprintf("%s/geth.ipc", path)
+}
+
 // dialRPC returns a RPC client which connects to the given endpoint.
 // The check for empty endpoint implements the defaulting logic
 // for "geth attach" with no argument.
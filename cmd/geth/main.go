@@ -81,6 +81,8 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolRemoteJournalFlag,
+		utils.TxPoolRemoteJournalSizeFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -88,6 +90,14 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrivateLifetimeFlag,
+		utils.TxPoolPrivatePublishFlag,
+		utils.TxPoolQuotasFlag,
+		utils.TxPoolSpamHalfLifeFlag,
+		utils.TxPoolSpamThresholdFlag,
+		utils.TxPoolPreExecFlag,
+		utils.TxPoolPreExecGasFlag,
+		utils.TxPoolPreExecTimeoutFlag,
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
@@ -126,6 +136,9 @@ var (
 		utils.MinerEtherbaseFlag,
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
+		utils.MinerSlotMarginFlag,
+		utils.MinerFillBudgetFlag,
+		utils.MinerTxOrderingFlag,
 		utils.MinerNoVerifyFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
@@ -137,20 +150,32 @@ var (
 		utils.MainnetFlag,
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
+		utils.DeveloperBorFlag,
 		utils.RopstenFlag,
 		utils.RinkebyFlag,
 		utils.GoerliFlag,
 		utils.BttcDonauFlag,
 		utils.BttcMainnetFlag,
 		utils.VMEnableDebugFlag,
+		utils.TraceIndexFlag,
+		utils.TraceCacheFlag,
+		utils.TraceCacheTracersFlag,
+		utils.TraceCacheBlocksFlag,
+		utils.TraceCacheTxBlocksFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoSlowPercentileFlag,
+		utils.GpoFastPercentileFlag,
 		utils.GpoMaxGasPriceFlag,
 		utils.GpoIgnoreGasPriceFlag,
+		utils.GpoMinTipFlag,
+		utils.GpoFeeIndexFlag,
+		utils.GpoFeeIndexGranularityFlag,
+		utils.GpoFeeIndexHistoryFlag,
 		utils.MinerNotifyFullFlag,
 		configFileFlag,
 		utils.CatalystFlag,
@@ -220,6 +245,10 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		// See blockalloccmd.go:
+		blockAllocCommand,
+		// See tracecmd.go:
+		traceCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -0,0 +1,222 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	traceProfileMetricFlag = cli.StringFlag{
+		Name:  "metric",
+		Usage: `Metric to profile, "gas" or "time" (nanoseconds)`,
+		Value: "gas",
+	}
+	traceProfileOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write the folded stacks into (default = stdout)",
+	}
+	traceProfileReexecFlag = cli.Uint64Flag{
+		Name:  "reexec",
+		Usage: "Number of blocks the node may re-execute to regenerate a missing historical state",
+		Value: 128,
+	}
+
+	traceCommand = cli.Command{
+		Name:      "trace",
+		Usage:     "Analyze the execution of the transactions of a running node",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			traceProfileCommand,
+		},
+	}
+	traceProfileCommand = cli.Command{
+		Action:    utils.MigrateFlags(traceProfile),
+		Name:      "profile",
+		Usage:     "Profile the gas used by the transactions of a block range",
+		ArgsUsage: "<firstBlock> <lastBlock> [endpoint]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			traceProfileMetricFlag,
+			traceProfileOutputFlag,
+			traceProfileReexecFlag,
+		},
+		Description: `
+This command traces the transactions of the given inclusive block range on a
+running node with the gasProfiler tracer, and aggregates the gas used or the time
+spent by each contract, function selector and opcode.
+
+The profile is written in the folded stack format, from which flamegraph tools
+render a flame graph, e.g.:
+
+    geth trace profile 1000 2000 | flamegraph.pl > profile.svg
+
+The node is attached to over IPC by default, and needs the debug API enabled on
+other endpoints.`,
+	}
+)
+
+// profiledBlock is a block trace result of the gas profiler.
+type profiledBlock struct {
+	Block  hexutil.Uint64 `json:"block"`
+	Traces []struct {
+		Result *tracers.GasProfile `json:"result"`
+		Error  string              `json:"error"`
+	} `json:"traces"`
+}
+
+// traceProfile traces a block range with the gas profiler, writing the folded
+// stacks of all its transactions.
+func traceProfile(ctx *cli.Context) error {
+	if ctx.NArg() < 2 || ctx.NArg() > 3 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	first, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		return err
+	}
+	last, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		return err
+	}
+	if first == 0 || first > last {
+		return errors.New("invalid block range, the first block must be positive and at most the last one")
+	}
+	metric := ctx.String(traceProfileMetricFlag.Name)
+	if metric != "gas" && metric != "time" {
+		return fmt.Errorf("unknown metric %q", metric)
+	}
+	endpoint := ctx.Args().Get(2)
+	if endpoint == "" {
+		endpoint = defaultIPCEndpoint(ctx)
+	}
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		return fmt.Errorf("unable to attach to geth: %v", err)
+	}
+	defer client.Close()
+
+	// Trace the chain from the parent of the first block
+	var (
+		tracer  = "gasProfiler"
+		reexec  = ctx.Uint64(traceProfileReexecFlag.Name)
+		results = make(chan *profiledBlock)
+	)
+	sub, err := client.Subscribe(context.Background(), "debug", results, "traceChain", hexutil.Uint64(first-1), hexutil.Uint64(last), &tracers.TraceConfig{Tracer: &tracer, Reexec: &reexec})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	var (
+		samples = make(map[string]uint64)
+		traced  int
+		logged  = time.Now()
+	)
+	for {
+		select {
+		case res := <-results:
+			for i, trace := range res.Traces {
+				if trace.Error != "" {
+					log.Warn("Failed to profile transaction", "block", uint64(res.Block), "index", i, "err", trace.Error)
+					continue
+				}
+				if trace.Result == nil {
+					continue
+				}
+				folded := trace.Result.Gas
+				if metric == "time" {
+					folded = trace.Result.Time
+				}
+				if err := addFoldedStacks(samples, folded); err != nil {
+					return err
+				}
+				traced++
+			}
+			if uint64(res.Block) >= last {
+				log.Info("Profiled block range", "first", first, "last", last, "transactions", traced)
+				return writeProfile(ctx.String(traceProfileOutputFlag.Name), samples)
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Profiling block range", "first", first, "last", last, "current", uint64(res.Block), "transactions", traced)
+				logged = time.Now()
+			}
+		case err := <-sub.Err():
+			return err
+		}
+	}
+}
+
+// addFoldedStacks adds the values of folded stacks to the samples by stack.
+func addFoldedStacks(samples map[string]uint64, folded string) error {
+	for _, line := range strings.Split(folded, "\n") {
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			return fmt.Errorf("invalid folded stack %q", line)
+		}
+		value, err := strconv.ParseUint(line[i+1:], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid folded stack %q: %v", line, err)
+		}
+		samples[line[:i]] += value
+	}
+	return nil
+}
+
+// writeProfile writes the samples by stack in the folded stack format into the
+// given file, or the standard output.
+func writeProfile(path string, samples map[string]uint64) error {
+	var out io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	stacks := make([]string, 0, len(samples))
+	for stack := range samples {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(out, "%s %d\n", stack, samples[stack]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
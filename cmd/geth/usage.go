@@ -76,6 +76,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Flags: []cli.Flag{
 			utils.DeveloperFlag,
 			utils.DeveloperPeriodFlag,
+			utils.DeveloperBorFlag,
 		},
 	},
 	{
@@ -98,6 +99,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolRemoteJournalFlag,
+			utils.TxPoolRemoteJournalSizeFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -105,6 +108,14 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolPrivateLifetimeFlag,
+			utils.TxPoolPrivatePublishFlag,
+			utils.TxPoolQuotasFlag,
+			utils.TxPoolSpamHalfLifeFlag,
+			utils.TxPoolSpamThresholdFlag,
+			utils.TxPoolPreExecFlag,
+			utils.TxPoolPreExecGasFlag,
+			utils.TxPoolPreExecTimeoutFlag,
 		},
 	},
 	{
@@ -187,6 +198,9 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerEtherbaseFlag,
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
+			utils.MinerSlotMarginFlag,
+			utils.MinerFillBudgetFlag,
+			utils.MinerTxOrderingFlag,
 			utils.MinerNoVerifyFlag,
 		},
 	},
@@ -195,14 +209,25 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoSlowPercentileFlag,
+			utils.GpoFastPercentileFlag,
 			utils.GpoMaxGasPriceFlag,
 			utils.GpoIgnoreGasPriceFlag,
+			utils.GpoMinTipFlag,
+			utils.GpoFeeIndexFlag,
+			utils.GpoFeeIndexGranularityFlag,
+			utils.GpoFeeIndexHistoryFlag,
 		},
 	},
 	{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.TraceIndexFlag,
+			utils.TraceCacheFlag,
+			utils.TraceCacheTracersFlag,
+			utils.TraceCacheBlocksFlag,
+			utils.TraceCacheTxBlocksFlag,
 		},
 	},
 	{
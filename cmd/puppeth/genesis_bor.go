@@ -0,0 +1,138 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// borValidatorSetABI is the subset of the BorValidatorSet genesis contract ABI
+// needed to inspect the validators baked into its bytecode.
+const borValidatorSetABI = `[{"constant":true,"inputs":[],"name":"getInitialValidators","outputs":[{"internalType":"address[]","name":"","type":"address[]"},{"internalType":"uint256[]","name":"","type":"uint256[]"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// parseBorGenesisContracts decodes the allocation of the Bor genesis contracts.
+// Both a plain address to account mapping and a full genesis spec (from which
+// the alloc section is taken) are accepted, matching the outputs of the
+// genesis-contracts tooling.
+func parseBorGenesisContracts(blob []byte) (core.GenesisAlloc, error) {
+	var alloc core.GenesisAlloc
+	if err := json.Unmarshal(blob, &alloc); err == nil && len(alloc) > 0 {
+		return alloc, nil
+	}
+	var genesis core.Genesis
+	if err := json.Unmarshal(blob, &genesis); err != nil {
+		return nil, err
+	}
+	if len(genesis.Alloc) == 0 {
+		return nil, errors.New("no genesis contracts allocated")
+	}
+	return genesis.Alloc, nil
+}
+
+// verifyBorGenesisContracts ensures that the system contracts referenced by the
+// Bor config all have code in the given allocation.
+func verifyBorGenesisContracts(config *params.BorConfig, alloc core.GenesisAlloc) error {
+	for name, contract := range map[string]string{
+		"validator set":  config.ValidatorContract,
+		"state receiver": config.StateReceiverContract,
+	} {
+		if account, ok := alloc[common.HexToAddress(contract)]; !ok || len(account.Code) == 0 {
+			return fmt.Errorf("missing %s contract code at %s", name, contract)
+		}
+	}
+	return nil
+}
+
+// borGenesisValidators executes the validator set contract of an allocation in
+// an ephemeral state and returns the initial validators compiled into it.
+func borGenesisValidators(config *params.ChainConfig, alloc core.GenesisAlloc) ([]*bor.Validator, error) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		return nil, err
+	}
+	for addr, account := range alloc {
+		statedb.SetCode(addr, account.Code)
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	vABI, err := abi.JSON(strings.NewReader(borValidatorSetABI))
+	if err != nil {
+		return nil, err
+	}
+	input, err := vABI.Pack("getInitialValidators")
+	if err != nil {
+		return nil, err
+	}
+	output, _, err := runtime.Call(common.HexToAddress(config.Bor.ValidatorContract), input, &runtime.Config{
+		ChainConfig: config,
+		State:       statedb,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		addrs  = new([]common.Address)
+		powers = new([]*big.Int)
+	)
+	if err := vABI.UnpackIntoInterface(&[]interface{}{addrs, powers}, "getInitialValidators", output); err != nil {
+		return nil, err
+	}
+	if len(*addrs) != len(*powers) {
+		return nil, errors.New("validator and power count mismatch")
+	}
+	validators := make([]*bor.Validator, len(*addrs))
+	for i, addr := range *addrs {
+		validators[i] = bor.NewValidator(addr, (*powers)[i].Int64())
+	}
+	return validators, nil
+}
+
+// matchBorValidators checks that the validator set collected from the user is
+// exactly the one compiled into the genesis contracts, irrespective of order.
+func matchBorValidators(want, have []*bor.Validator) error {
+	if len(want) != len(have) {
+		return fmt.Errorf("validator count mismatch: have %d, want %d", len(have), len(want))
+	}
+	powers := make(map[common.Address]int64)
+	for _, val := range have {
+		powers[val.Address] = val.VotingPower
+	}
+	for _, val := range want {
+		power, ok := powers[val.Address]
+		if !ok {
+			return fmt.Errorf("validator %s missing from genesis contracts", val.Address.Hex())
+		}
+		if power != val.VotingPower {
+			return fmt.Errorf("validator %s power mismatch: have %d, want %d", val.Address.Hex(), power, val.VotingPower)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ import (
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
 	"github.com/ethereum/go-ethereum/core"
 )
 
@@ -93,3 +95,40 @@ func TestParitySturebyConverter(t *testing.T) {
 		t.Fatalf("chainspec mismatch")
 	}
 }
+
+// Tests that the validator set compiled into the Bor genesis contracts can be
+// extracted and cross checked against a user supplied one.
+func TestBorGenesisValidators(t *testing.T) {
+	blob, err := ioutil.ReadFile("../../tests/bor/testdata/genesis.json")
+	if err != nil {
+		t.Fatalf("could not read file: %v", err)
+	}
+	alloc, err := parseBorGenesisContracts(blob)
+	if err != nil {
+		t.Fatalf("failed parsing genesis contracts: %v", err)
+	}
+	var genesis core.Genesis
+	if err := json.Unmarshal(blob, &genesis); err != nil {
+		t.Fatalf("failed parsing genesis: %v", err)
+	}
+	if err := verifyBorGenesisContracts(genesis.Config.Bor, alloc); err != nil {
+		t.Fatalf("failed verifying genesis contracts: %v", err)
+	}
+	validators, err := borGenesisValidators(genesis.Config, alloc)
+	if err != nil {
+		t.Fatalf("failed retrieving genesis validators: %v", err)
+	}
+	want := []*bor.Validator{bor.NewValidator(common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7"), 10)}
+	if err := matchBorValidators(want, validators); err != nil {
+		t.Errorf("validator set mismatch: %v", err)
+	}
+	want[0].VotingPower = 1
+	if err := matchBorValidators(want, validators); err == nil {
+		t.Errorf("mismatching voting power accepted")
+	}
+	// Stripping the system contracts must be detected
+	delete(alloc, common.HexToAddress(genesis.Config.Bor.StateReceiverContract))
+	if err := verifyBorGenesisContracts(genesis.Config.Bor, alloc); err == nil {
+		t.Errorf("missing state receiver contract accepted")
+	}
+}
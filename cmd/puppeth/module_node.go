@@ -32,7 +32,7 @@ import (
 
 // nodeDockerfile is the Dockerfile required to run an Ethereum node.
 var nodeDockerfile = `
-FROM ethereum/client-go:latest
+FROM {{.Image}}
 
 ADD genesis.json /genesis.json
 {{if .Unlock}}
@@ -40,9 +40,9 @@ ADD genesis.json /genesis.json
 	ADD signer.pass /signer.pass
 {{end}}
 RUN \
-  echo 'geth --cache 512 init /genesis.json' > geth.sh && \{{if .Unlock}}
+  echo '{{.Binary}} --cache 512 init /genesis.json' > geth.sh && \{{if .Unlock}}
 	echo 'mkdir -p /root/.ethereum/keystore/ && cp /signer.json /root/.ethereum/keystore/' >> geth.sh && \{{end}}
-	echo $'exec geth --networkid {{.NetworkID}} --cache 512 --port {{.Port}} --nat extip:{{.IP}} --maxpeers {{.Peers}} {{.LightFlag}} --ethstats \'{{.Ethstats}}\' {{if .Bootnodes}}--bootnodes {{.Bootnodes}}{{end}} {{if .Etherbase}}--miner.etherbase {{.Etherbase}} --mine --miner.threads 1{{end}} {{if .Unlock}}--unlock 0 --password /signer.pass --mine{{end}} --miner.gastarget {{.GasTarget}} --miner.gaslimit {{.GasLimit}} --miner.gasprice {{.GasPrice}}' >> geth.sh
+	echo $'exec {{.Binary}} --networkid {{.NetworkID}} --cache 512 --port {{.Port}} --nat extip:{{.IP}} --maxpeers {{.Peers}} {{.LightFlag}} --ethstats \'{{.Ethstats}}\' {{if .Bootnodes}}--bootnodes {{.Bootnodes}}{{end}} {{if .Etherbase}}--miner.etherbase {{.Etherbase}} --mine --miner.threads 1{{end}} {{if .Unlock}}--unlock 0 --password /signer.pass --mine{{end}} --miner.gastarget {{.GasTarget}} --miner.gaslimit {{.GasLimit}} --miner.gasprice {{.GasPrice}}{{if .Heimdall}} --bor.heimdall {{.Heimdall}}{{end}}{{if .WithoutHeimdall}} --bor.withoutheimdall{{end}}' >> geth.sh
 
 ENTRYPOINT ["/bin/sh", "geth.sh"]
 `
@@ -70,7 +70,10 @@ services:
       - MINER_NAME={{.Etherbase}}
       - GAS_TARGET={{.GasTarget}}
       - GAS_LIMIT={{.GasLimit}}
-      - GAS_PRICE={{.GasPrice}}
+      - GAS_PRICE={{.GasPrice}}{{if .Image}}
+      - BOR_IMAGE={{.Image}}
+      - HEIMDALL_URL={{.Heimdall}}
+      - WITHOUT_HEIMDALL={{.WithoutHeimdall}}{{end}}
     logging:
       driver: "json-file"
       options:
@@ -96,38 +99,50 @@ func deployNode(client *sshClient, network string, bootnodes []string, config *n
 	if config.peersLight > 0 {
 		lightFlag = fmt.Sprintf("--light.maxpeers=%d --light.serve=50", config.peersLight)
 	}
+	// Bor networks run a bttc image against a Heimdall node instead of stock geth
+	image, binary := "ethereum/client-go:latest", "geth"
+	if config.borImage != "" {
+		image, binary = config.borImage, "bttc"
+	}
 	dockerfile := new(bytes.Buffer)
 	template.Must(template.New("").Parse(nodeDockerfile)).Execute(dockerfile, map[string]interface{}{
-		"NetworkID": config.network,
-		"Port":      config.port,
-		"IP":        client.address,
-		"Peers":     config.peersTotal,
-		"LightFlag": lightFlag,
-		"Bootnodes": strings.Join(bootnodes, ","),
-		"Ethstats":  config.ethstats,
-		"Etherbase": config.etherbase,
-		"GasTarget": uint64(1000000 * config.gasTarget),
-		"GasLimit":  uint64(1000000 * config.gasLimit),
-		"GasPrice":  uint64(1000000000 * config.gasPrice),
-		"Unlock":    config.keyJSON != "",
+		"Image":           image,
+		"Binary":          binary,
+		"NetworkID":       config.network,
+		"Port":            config.port,
+		"IP":              client.address,
+		"Peers":           config.peersTotal,
+		"LightFlag":       lightFlag,
+		"Bootnodes":       strings.Join(bootnodes, ","),
+		"Ethstats":        config.ethstats,
+		"Etherbase":       config.etherbase,
+		"GasTarget":       uint64(1000000 * config.gasTarget),
+		"GasLimit":        uint64(1000000 * config.gasLimit),
+		"GasPrice":        uint64(1000000000 * config.gasPrice),
+		"Unlock":          config.keyJSON != "",
+		"Heimdall":        config.heimdallURL,
+		"WithoutHeimdall": config.withoutHeimdall,
 	})
 	files[filepath.Join(workdir, "Dockerfile")] = dockerfile.Bytes()
 
 	composefile := new(bytes.Buffer)
 	template.Must(template.New("").Parse(nodeComposefile)).Execute(composefile, map[string]interface{}{
-		"Type":       kind,
-		"Datadir":    config.datadir,
-		"Ethashdir":  config.ethashdir,
-		"Network":    network,
-		"Port":       config.port,
-		"TotalPeers": config.peersTotal,
-		"Light":      config.peersLight > 0,
-		"LightPeers": config.peersLight,
-		"Ethstats":   config.ethstats[:strings.Index(config.ethstats, ":")],
-		"Etherbase":  config.etherbase,
-		"GasTarget":  config.gasTarget,
-		"GasLimit":   config.gasLimit,
-		"GasPrice":   config.gasPrice,
+		"Type":            kind,
+		"Datadir":         config.datadir,
+		"Ethashdir":       config.ethashdir,
+		"Network":         network,
+		"Port":            config.port,
+		"TotalPeers":      config.peersTotal,
+		"Light":           config.peersLight > 0,
+		"LightPeers":      config.peersLight,
+		"Ethstats":        config.ethstats[:strings.Index(config.ethstats, ":")],
+		"Etherbase":       config.etherbase,
+		"GasTarget":       config.gasTarget,
+		"GasLimit":        config.gasLimit,
+		"GasPrice":        config.gasPrice,
+		"Image":           config.borImage,
+		"Heimdall":        config.heimdallURL,
+		"WithoutHeimdall": config.withoutHeimdall,
 	})
 	files[filepath.Join(workdir, "docker-compose.yaml")] = composefile.Bytes()
 
@@ -167,6 +182,10 @@ type nodeInfos struct {
 	gasTarget  float64
 	gasLimit   float64
 	gasPrice   float64
+
+	borImage        string // Docker image containing the bttc binary (bor networks only)
+	heimdallURL     string // Heimdall endpoint to fetch spans and state syncs from
+	withoutHeimdall bool   // Whether to run bor without a Heimdall node (testing only)
 }
 
 // Report converts the typed struct into a plain string->string map, containing
@@ -179,6 +198,15 @@ func (info *nodeInfos) Report() map[string]string {
 		"Peer count (light nodes)": strconv.Itoa(info.peersLight),
 		"Ethstats username":        info.ethstats,
 	}
+	if info.borImage != "" {
+		// Bor node, report the Heimdall connectivity
+		report["Bor docker image"] = info.borImage
+		if info.withoutHeimdall {
+			report["Heimdall URL"] = "disabled"
+		} else {
+			report["Heimdall URL"] = info.heimdallURL
+		}
+	}
 	if info.gasTarget > 0 {
 		// Miner or signer node
 		report["Gas price (minimum accepted)"] = fmt.Sprintf("%0.3f GWei", info.gasPrice)
@@ -191,7 +219,7 @@ func (info *nodeInfos) Report() map[string]string {
 			report["Miner account"] = info.etherbase
 		}
 		if info.keyJSON != "" {
-			// Clique proof-of-authority signer
+			// Clique proof-of-authority or Bor proof-of-stake signer
 			var key struct {
 				Address string `json:"address"`
 			}
@@ -226,10 +254,16 @@ func checkNode(client *sshClient, network string, boot bool) (*nodeInfos, error)
 	gasTarget, _ := strconv.ParseFloat(infos.envvars["GAS_TARGET"], 64)
 	gasLimit, _ := strconv.ParseFloat(infos.envvars["GAS_LIMIT"], 64)
 	gasPrice, _ := strconv.ParseFloat(infos.envvars["GAS_PRICE"], 64)
+	withoutHeimdall, _ := strconv.ParseBool(infos.envvars["WITHOUT_HEIMDALL"])
+
+	binary := "geth"
+	if infos.envvars["BOR_IMAGE"] != "" {
+		binary = "bttc"
+	}
 
 	// Container available, retrieve its node ID and its genesis json
 	var out []byte
-	if out, err = client.Run(fmt.Sprintf("docker exec %s_%s_1 geth --exec admin.nodeInfo.enode --cache=16 attach", network, kind)); err != nil {
+	if out, err = client.Run(fmt.Sprintf("docker exec %s_%s_1 %s --exec admin.nodeInfo.enode --cache=16 attach", network, kind, binary)); err != nil {
 		return nil, ErrServiceUnreachable
 	}
 	enode := bytes.Trim(bytes.TrimSpace(out), "\"")
@@ -253,19 +287,22 @@ func checkNode(client *sshClient, network string, boot bool) (*nodeInfos, error)
 	}
 	// Assemble and return the useful infos
 	stats := &nodeInfos{
-		genesis:    genesis,
-		datadir:    infos.volumes["/root/.ethereum"],
-		ethashdir:  infos.volumes["/root/.ethash"],
-		port:       port,
-		peersTotal: totalPeers,
-		peersLight: lightPeers,
-		ethstats:   infos.envvars["STATS_NAME"],
-		etherbase:  infos.envvars["MINER_NAME"],
-		keyJSON:    keyJSON,
-		keyPass:    keyPass,
-		gasTarget:  gasTarget,
-		gasLimit:   gasLimit,
-		gasPrice:   gasPrice,
+		genesis:         genesis,
+		datadir:         infos.volumes["/root/.ethereum"],
+		ethashdir:       infos.volumes["/root/.ethash"],
+		port:            port,
+		peersTotal:      totalPeers,
+		peersLight:      lightPeers,
+		ethstats:        infos.envvars["STATS_NAME"],
+		etherbase:       infos.envvars["MINER_NAME"],
+		keyJSON:         keyJSON,
+		keyPass:         keyPass,
+		gasTarget:       gasTarget,
+		gasLimit:        gasLimit,
+		gasPrice:        gasPrice,
+		borImage:        infos.envvars["BOR_IMAGE"],
+		heimdallURL:     infos.envvars["HEIMDALL_URL"],
+		withoutHeimdall: withoutHeimdall,
 	}
 	stats.enode = string(enode)
 
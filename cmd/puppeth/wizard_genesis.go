@@ -25,11 +25,13 @@ import (
 	"math/big"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -59,6 +61,7 @@ func (w *wizard) makeGenesis() {
 	fmt.Println("Which consensus engine to use? (default = clique)")
 	fmt.Println(" 1. Ethash - proof-of-work")
 	fmt.Println(" 2. Clique - proof-of-authority")
+	fmt.Println(" 3. Bor - proof-of-stake (Heimdall backed)")
 
 	choice := w.read()
 	switch {
@@ -105,6 +108,12 @@ func (w *wizard) makeGenesis() {
 			copy(genesis.ExtraData[32+i*common.AddressLength:], signer[:])
 		}
 
+	case choice == "3":
+		// In the case of bor, configure the engine and genesis contracts
+		if !w.makeBorGenesis(genesis) {
+			return
+		}
+
 	default:
 		log.Crit("Invalid consensus engine choice", "choice", choice)
 	}
@@ -146,46 +155,132 @@ func (w *wizard) importGenesis() {
 	// Request the genesis JSON spec URL from the user
 	fmt.Println()
 	fmt.Println("Where's the genesis file? (local file or http/https url)")
-	url := w.readURL()
+	reader, err := openURL(w.readURL())
+	if err != nil {
+		log.Error("Failed to open genesis", "err", err)
+		return
+	}
+	defer reader.Close()
+
+	// Parse the genesis file and inject it successful
+	var genesis core.Genesis
+	if err := json.NewDecoder(reader).Decode(&genesis); err != nil {
+		log.Error("Invalid genesis spec", "err", err)
+		return
+	}
+	log.Info("Imported genesis block")
 
-	// Convert the various allowed URLs to a reader stream
-	var reader io.Reader
+	w.conf.Genesis = &genesis
+	w.conf.flush()
+}
 
+// makeBorGenesis configures the bor consensus parameters of a genesis block,
+// collects the initial validator set and allocates the system contracts. It
+// returns false if the genesis could not be assembled.
+func (w *wizard) makeBorGenesis(genesis *core.Genesis) bool {
+	genesis.Difficulty = big.NewInt(1)
+	genesis.GasLimit = 10000000
+	genesis.ExtraData = make([]byte, 32+65)
+	genesis.Config.Bor = &params.BorConfig{
+		Period:                2,
+		ProducerDelay:         6,
+		Sprint:                64,
+		BackupMultiplier:      2,
+		ValidatorContract:     "0x0000000000000000000000000000000000001000",
+		StateReceiverContract: "0x0000000000000000000000000000000000001001",
+	}
+	fmt.Println()
+	fmt.Printf("How many seconds should blocks take? (default = %d)\n", genesis.Config.Bor.Period)
+	genesis.Config.Bor.Period = uint64(w.readDefaultInt(int(genesis.Config.Bor.Period)))
+
+	fmt.Println()
+	fmt.Printf("How many seconds should the first block of a sprint be delayed? (default = %d)\n", genesis.Config.Bor.ProducerDelay)
+	genesis.Config.Bor.ProducerDelay = uint64(w.readDefaultInt(int(genesis.Config.Bor.ProducerDelay)))
+
+	fmt.Println()
+	fmt.Printf("How many blocks should a sprint last? (default = %d)\n", genesis.Config.Bor.Sprint)
+	genesis.Config.Bor.Sprint = uint64(w.readDefaultInt(int(genesis.Config.Bor.Sprint)))
+
+	fmt.Println()
+	fmt.Printf("How many seconds should each backup producer wait per turn? (default = %d)\n", genesis.Config.Bor.BackupMultiplier)
+	genesis.Config.Bor.BackupMultiplier = uint64(w.readDefaultInt(int(genesis.Config.Bor.BackupMultiplier)))
+
+	// We also need the initial list of validators along with their voting power
+	fmt.Println()
+	fmt.Println("Which accounts are the initial validators? (mandatory at least one)")
+
+	var validators []*bor.Validator
+	for {
+		if address := w.readAddress(); address != nil {
+			validators = append(validators, bor.NewValidator(*address, 0))
+			continue
+		}
+		if len(validators) > 0 {
+			break
+		}
+	}
+	for _, validator := range validators {
+		fmt.Println()
+		fmt.Printf("What's the voting power of %s? (default = 1)\n", validator.Address.Hex())
+		validator.VotingPower = int64(w.readDefaultInt(1))
+	}
+	// The validator set is compiled into the genesis contracts, load and cross check them
+	fmt.Println()
+	fmt.Println("Where are the compiled genesis contracts? (local file or http/https url)")
+	reader, err := openURL(w.readURL())
+	if err != nil {
+		log.Error("Failed to open genesis contracts", "err", err)
+		return false
+	}
+	defer reader.Close()
+
+	blob, err := ioutil.ReadAll(reader)
+	if err != nil {
+		log.Error("Failed to read genesis contracts", "err", err)
+		return false
+	}
+	alloc, err := parseBorGenesisContracts(blob)
+	if err != nil {
+		log.Error("Invalid genesis contracts", "err", err)
+		return false
+	}
+	if err := verifyBorGenesisContracts(genesis.Config.Bor, alloc); err != nil {
+		log.Error("Invalid genesis contracts", "err", err)
+		return false
+	}
+	compiled, err := borGenesisValidators(genesis.Config, alloc)
+	if err != nil {
+		log.Error("Failed to retrieve genesis validators", "err", err)
+		return false
+	}
+	if err := matchBorValidators(validators, compiled); err != nil {
+		log.Error("Genesis contracts don't match the validator set", "err", err)
+		return false
+	}
+	for addr, account := range alloc {
+		genesis.Alloc[addr] = account
+	}
+	return true
+}
+
+// openURL opens a local file or http/https URL for reading.
+func openURL(url *url.URL) (io.ReadCloser, error) {
 	switch url.Scheme {
 	case "http", "https":
 		// Remote web URL, retrieve it via an HTTP client
 		res, err := http.Get(url.String())
 		if err != nil {
-			log.Error("Failed to retrieve remote genesis", "err", err)
-			return
+			return nil, err
 		}
-		defer res.Body.Close()
-		reader = res.Body
+		return res.Body, nil
 
 	case "":
 		// Schemaless URL, interpret as a local file
-		file, err := os.Open(url.String())
-		if err != nil {
-			log.Error("Failed to open local genesis", "err", err)
-			return
-		}
-		defer file.Close()
-		reader = file
+		return os.Open(url.String())
 
 	default:
-		log.Error("Unsupported genesis URL scheme", "scheme", url.Scheme)
-		return
+		return nil, fmt.Errorf("unsupported URL scheme %q", url.Scheme)
 	}
-	// Parse the genesis file and inject it successful
-	var genesis core.Genesis
-	if err := json.NewDecoder(reader).Decode(&genesis); err != nil {
-		log.Error("Invalid genesis spec", "err", err)
-		return
-	}
-	log.Info("Imported genesis block")
-
-	w.conf.Genesis = &genesis
-	w.conf.flush()
 }
 
 // manageGenesis permits the modification of chain configuration parameters in
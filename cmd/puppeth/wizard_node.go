@@ -101,6 +101,37 @@ func (w *wizard) deployNode(boot bool) {
 		fmt.Printf("What should the node be called on the stats page? (default = %s)\n", infos.ethstats)
 		infos.ethstats = w.readDefaultString(infos.ethstats) + ":" + w.conf.ethstats
 	}
+	// Bor nodes need a bttc image and a Heimdall node to follow spans and state syncs
+	if w.conf.Genesis.Config.Bor != nil {
+		fmt.Println()
+		if infos.borImage == "" {
+			fmt.Printf("Which docker image contains the bttc binary?\n")
+			infos.borImage = w.readString()
+		} else {
+			fmt.Printf("Which docker image contains the bttc binary? (default = %s)\n", infos.borImage)
+			infos.borImage = w.readDefaultString(infos.borImage)
+		}
+		fmt.Println()
+		if infos.withoutHeimdall {
+			fmt.Printf("Should the node run without Heimdall, for testing only (y/n)? (default = yes)\n")
+		} else {
+			fmt.Printf("Should the node run without Heimdall, for testing only (y/n)? (default = no)\n")
+		}
+		infos.withoutHeimdall = w.readDefaultYesNo(infos.withoutHeimdall)
+
+		if infos.withoutHeimdall {
+			infos.heimdallURL = ""
+		} else {
+			fmt.Println()
+			if infos.heimdallURL == "" {
+				fmt.Printf("What's the URL of the Heimdall node?\n")
+				infos.heimdallURL = w.readString()
+			} else {
+				fmt.Printf("What's the URL of the Heimdall node? (default = %s)\n", infos.heimdallURL)
+				infos.heimdallURL = w.readDefaultString(infos.heimdallURL)
+			}
+		}
+	}
 	// If the node is a miner/signer, load up needed credentials
 	if !boot {
 		if w.conf.Genesis.Config.Ethash != nil {
@@ -118,7 +149,7 @@ func (w *wizard) deployNode(boot bool) {
 				fmt.Printf("What address should the miner use? (default = %s)\n", infos.etherbase)
 				infos.etherbase = w.readDefaultAddress(common.HexToAddress(infos.etherbase)).Hex()
 			}
-		} else if w.conf.Genesis.Config.Clique != nil {
+		} else if w.conf.Genesis.Config.Clique != nil || w.conf.Genesis.Config.Bor != nil {
 			// If a previous signer was already set, offer to reuse it
 			if infos.keyJSON != "" {
 				if key, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass); err != nil {
@@ -131,7 +162,7 @@ func (w *wizard) deployNode(boot bool) {
 					}
 				}
 			}
-			// Clique based signers need a keyfile and unlock password, ask if unavailable
+			// Clique and Bor based signers need a keyfile and unlock password, ask if unavailable
 			if infos.keyJSON == "" {
 				fmt.Println()
 				fmt.Println("Please paste the signer's key JSON:")
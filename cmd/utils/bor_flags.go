@@ -5,7 +5,12 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
@@ -37,6 +42,41 @@ var (
 	}
 )
 
+// developerBorKey is the private key of core.DeveloperBorValidator
+// (0x71562b71999873DB5b286dF957af199Ec94617F7). It is a publicly known test key,
+// anyone can sign with it: it must never control funds or seal blocks outside
+// of ephemeral developer networks.
+const developerBorKey = "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291"
+
+// makeBorDeveloper imports the bor developer validator into the keystore, or
+// reuses it if it was imported by an earlier run. As the validator key is public,
+// it refuses to do so for a data directory holding any chain other than the bor
+// developer one.
+func makeBorDeveloper(ctx *cli.Context, stack *node.Node, ks *keystore.KeyStore, passphrase string) accounts.Account {
+	if ctx.GlobalIsSet(DataDirFlag.Name) {
+		chaindb := MakeChainDatabase(ctx, stack, false)
+		stored := rawdb.ReadCanonicalHash(chaindb, 0)
+		chaindb.Close()
+
+		genesis := core.DeveloperBorGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name))).ToBlock(nil).Hash()
+		if stored != (common.Hash{}) && stored != genesis {
+			Fatalf("--%s requires a fresh data directory or a bor developer chain, have genesis %x", DeveloperBorFlag.Name, stored)
+		}
+	}
+	developer := accounts.Account{Address: core.DeveloperBorValidator}
+	if ks.HasAddress(developer.Address) {
+		return developer
+	}
+	key, err := crypto.HexToECDSA(developerBorKey)
+	if err != nil {
+		Fatalf("Failed to load bor developer key: %v", err)
+	}
+	if developer, err = ks.ImportECDSA(key, passphrase); err != nil {
+		Fatalf("Failed to import bor developer account: %v", err)
+	}
+	return developer
+}
+
 func getGenesis(genesisPath string) (*core.Genesis, error) {
 	log.Info("Reading genesis at ", "file", genesisPath)
 	file, err := os.Open(genesisPath)
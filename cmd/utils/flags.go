@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bor"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -171,6 +172,10 @@ var (
 		Name:  "dev.period",
 		Usage: "Block period to use in developer mode (0 = mine only if transaction pending)",
 	}
+	DeveloperBorFlag = cli.BoolFlag{
+		Name:  "dev.bor",
+		Usage: "Use the bor engine with a single local validator and an in-process Heimdall in developer mode",
+	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
 		Usage: "Custom node name",
@@ -347,6 +352,15 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk snapshot of remote transactions to survive node restarts (disabled if empty)",
+	}
+	TxPoolRemoteJournalSizeFlag = cli.Uint64Flag{
+		Name:  "txpool.remotejournalsize",
+		Usage: "Maximum size in bytes of the remote transaction snapshot",
+		Value: ethconfig.Defaults.TxPool.RemoteJournalSize,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -382,6 +396,43 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: ethconfig.Defaults.TxPool.Lifetime,
 	}
+	TxPoolPrivateLifetimeFlag = cli.DurationFlag{
+		Name:  "txpool.privatelifetime",
+		Usage: "Maximum amount of time private transactions are kept out of the network",
+		Value: ethconfig.Defaults.TxPool.PrivateLifetime,
+	}
+	TxPoolPrivatePublishFlag = cli.BoolFlag{
+		Name:  "txpool.privatepublish",
+		Usage: "Broadcast private transactions past their lifetime instead of dropping them",
+	}
+	TxPoolQuotasFlag = cli.StringFlag{
+		Name:  "txpool.quotas",
+		Usage: "Comma separated limits on pooled transactions by contract and selector (<contract|*>[:<selector>]=<limit>)",
+	}
+	TxPoolSpamHalfLifeFlag = cli.DurationFlag{
+		Name:  "txpool.spamhalflife",
+		Usage: "Half-life of the sender spam scores raised by replacements and evictions (0 = disabled)",
+		Value: ethconfig.Defaults.TxPool.SpamHalfLife,
+	}
+	TxPoolSpamThresholdFlag = cli.Float64Flag{
+		Name:  "txpool.spamthreshold",
+		Usage: "Sender spam score above which its transactions are evicted ahead of cheaper ones",
+		Value: ethconfig.Defaults.TxPool.SpamThreshold,
+	}
+	TxPoolPreExecFlag = cli.StringFlag{
+		Name:  "txpool.preexec",
+		Usage: `Pre-execute remote transactions, flagging ("flag") or rejecting ("reject") the ones bound to fail`,
+	}
+	TxPoolPreExecGasFlag = cli.Uint64Flag{
+		Name:  "txpool.preexecgas",
+		Usage: "Maximum gas to pre-execute a remote transaction with, the earlier pending ones of its sender included",
+		Value: ethconfig.Defaults.TxPool.PreExecGas,
+	}
+	TxPoolPreExecTimeoutFlag = cli.DurationFlag{
+		Name:  "txpool.preexectimeout",
+		Usage: "Maximum time to pre-execute a remote transaction for",
+		Value: ethconfig.Defaults.TxPool.PreExecTimeout,
+	}
 	// Performance tuning settings
 	BorLogsFlag = cli.BoolFlag{
 		Name:  "bor.logs",
@@ -472,6 +523,21 @@ var (
 		Usage: "Time interval to recreate the block being mined",
 		Value: ethconfig.Defaults.Miner.Recommit,
 	}
+	MinerSlotMarginFlag = cli.DurationFlag{
+		Name:  "miner.slotmargin",
+		Usage: "Time reserved at the end of a bor producer slot to seal and propagate the block",
+		Value: ethconfig.Defaults.Miner.SlotMargin,
+	}
+	MinerFillBudgetFlag = cli.DurationFlag{
+		Name:  "miner.fillbudget",
+		Usage: "Time allowed to fill a bor block prepared past its slot deadline",
+		Value: ethconfig.Defaults.Miner.FillBudget,
+	}
+	MinerTxOrderingFlag = cli.StringFlag{
+		Name:  "miner.txordering",
+		Usage: "Order transactions are included in mined blocks in (price, fifo, fair)",
+		Value: miner.PriceNonceOrdering,
+	}
 	MinerNoVerifyFlag = cli.BoolFlag{
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
@@ -496,6 +562,28 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	TraceIndexFlag = cli.BoolFlag{
+		Name:  "trace.index",
+		Usage: "Index the addresses in the call traces of each block for trace_filter (requires --gcmode=archive)",
+	}
+	TraceCacheFlag = cli.BoolFlag{
+		Name:  "trace.cache",
+		Usage: "Cache the block trace results of the named built-in tracers on disk",
+	}
+	TraceCacheTracersFlag = cli.StringFlag{
+		Name:  "trace.cache.tracers",
+		Usage: "Comma separated tracers to trace each new block with in the background, caching the results",
+		Value: "",
+	}
+	TraceCacheBlocksFlag = cli.Uint64Flag{
+		Name:  "trace.cache.blocks",
+		Usage: "Number of recent blocks to retain cached trace results for (0 = all)",
+		Value: ethconfig.Defaults.TraceCacheBlocks,
+	}
+	TraceCacheTxBlocksFlag = cli.BoolFlag{
+		Name:  "trace.cache.txblocks",
+		Usage: "Trace the whole block of a transaction traced on a cache miss, caching the results of its other transactions",
+	}
 	InsecureUnlockAllowedFlag = cli.BoolFlag{
 		Name:  "allow-insecure-unlock",
 		Usage: "Allow insecure account unlocking when account-related RPCs are exposed by http",
@@ -691,6 +779,16 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: ethconfig.Defaults.GPO.Percentile,
 	}
+	GpoSlowPercentileFlag = cli.IntFlag{
+		Name:  "gpo.slowpercentile",
+		Usage: "Gas price recommended for slow inclusion is the given percentile of a set of recent transaction gas prices",
+		Value: ethconfig.Defaults.GPO.SlowPercentile,
+	}
+	GpoFastPercentileFlag = cli.IntFlag{
+		Name:  "gpo.fastpercentile",
+		Usage: "Gas price recommended for fast inclusion is the given percentile of a set of recent transaction gas prices",
+		Value: ethconfig.Defaults.GPO.FastPercentile,
+	}
 	GpoMaxGasPriceFlag = cli.Int64Flag{
 		Name:  "gpo.maxprice",
 		Usage: "Maximum gas price will be recommended by gpo",
@@ -701,6 +799,24 @@ var (
 		Usage: "Gas price below which gpo will ignore transactions",
 		Value: ethconfig.Defaults.GPO.IgnorePrice.Int64(),
 	}
+	GpoMinTipFlag = cli.Int64Flag{
+		Name:  "gpo.mintip",
+		Usage: "Minimum gas tip recommended by gpo, overriding the one of the chain config (0 = chain config)",
+	}
+	GpoFeeIndexFlag = cli.BoolFlag{
+		Name:  "gpo.feeindex",
+		Usage: "Index the fee history data of each block, permitting eth_feeHistory to span long ranges",
+	}
+	GpoFeeIndexGranularityFlag = cli.Float64Flag{
+		Name:  "gpo.feeindex.granularity",
+		Usage: "Step between the reward percentiles precomputed by the fee index, requested ones being rounded up",
+		Value: ethconfig.Defaults.GPO.FeeIndexGranularity,
+	}
+	GpoFeeIndexHistoryFlag = cli.IntFlag{
+		Name:  "gpo.feeindex.maxhistory",
+		Usage: "Maximum number of blocks eth_feeHistory serves from the fee index",
+		Value: ethconfig.Defaults.GPO.MaxIndexedHistory,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = cli.BoolFlag{
@@ -1331,12 +1447,30 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoSlowPercentileFlag.Name) {
+		cfg.SlowPercentile = ctx.GlobalInt(GpoSlowPercentileFlag.Name)
+	}
+	if ctx.GlobalIsSet(GpoFastPercentileFlag.Name) {
+		cfg.FastPercentile = ctx.GlobalInt(GpoFastPercentileFlag.Name)
+	}
 	if ctx.GlobalIsSet(GpoMaxGasPriceFlag.Name) {
 		cfg.MaxPrice = big.NewInt(ctx.GlobalInt64(GpoMaxGasPriceFlag.Name))
 	}
 	if ctx.GlobalIsSet(GpoIgnoreGasPriceFlag.Name) {
 		cfg.IgnorePrice = big.NewInt(ctx.GlobalInt64(GpoIgnoreGasPriceFlag.Name))
 	}
+	if ctx.GlobalIsSet(GpoMinTipFlag.Name) {
+		cfg.MinTipCap = big.NewInt(ctx.GlobalInt64(GpoMinTipFlag.Name))
+	}
+	if ctx.GlobalIsSet(GpoFeeIndexFlag.Name) {
+		cfg.FeeIndex = ctx.GlobalBool(GpoFeeIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(GpoFeeIndexGranularityFlag.Name) {
+		cfg.FeeIndexGranularity = ctx.GlobalFloat64(GpoFeeIndexGranularityFlag.Name)
+	}
+	if ctx.GlobalIsSet(GpoFeeIndexHistoryFlag.Name) {
+		cfg.MaxIndexedHistory = ctx.GlobalInt(GpoFeeIndexHistoryFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -1359,6 +1493,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalSizeFlag.Name) {
+		cfg.RemoteJournalSize = ctx.GlobalUint64(TxPoolRemoteJournalSizeFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1380,6 +1520,40 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPrivateLifetimeFlag.Name) {
+		cfg.PrivateLifetime = ctx.GlobalDuration(TxPoolPrivateLifetimeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPrivatePublishFlag.Name) {
+		cfg.PrivatePublish = ctx.GlobalBool(TxPoolPrivatePublishFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolQuotasFlag.Name) {
+		cfg.Quotas = nil
+		for _, rule := range strings.Split(ctx.GlobalString(TxPoolQuotasFlag.Name), ",") {
+			quota, err := core.ParseTxQuota(strings.TrimSpace(rule))
+			if err != nil {
+				Fatalf("Invalid quota in --txpool.quotas: %v", err)
+			}
+			cfg.Quotas = append(cfg.Quotas, quota)
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolSpamHalfLifeFlag.Name) {
+		cfg.SpamHalfLife = ctx.GlobalDuration(TxPoolSpamHalfLifeFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolSpamThresholdFlag.Name) {
+		cfg.SpamThreshold = ctx.GlobalFloat64(TxPoolSpamThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPreExecFlag.Name) {
+		cfg.PreExec = ctx.GlobalString(TxPoolPreExecFlag.Name)
+		if cfg.PreExec != core.PreExecFlag && cfg.PreExec != core.PreExecReject {
+			Fatalf("Invalid --txpool.preexec mode %q, want %q or %q", cfg.PreExec, core.PreExecFlag, core.PreExecReject)
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolPreExecGasFlag.Name) {
+		cfg.PreExecGas = ctx.GlobalUint64(TxPoolPreExecGasFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolPreExecTimeoutFlag.Name) {
+		cfg.PreExecTimeout = ctx.GlobalDuration(TxPoolPreExecTimeoutFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1426,6 +1600,18 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerRecommitIntervalFlag.Name) {
 		cfg.Recommit = ctx.GlobalDuration(MinerRecommitIntervalFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerSlotMarginFlag.Name) {
+		cfg.SlotMargin = ctx.GlobalDuration(MinerSlotMarginFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerFillBudgetFlag.Name) {
+		cfg.FillBudget = ctx.GlobalDuration(MinerFillBudgetFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerTxOrderingFlag.Name) {
+		cfg.TxOrdering = ctx.GlobalString(MinerTxOrderingFlag.Name)
+		if _, err := miner.NewTxOrderingPolicy(cfg.TxOrdering); err != nil {
+			Fatalf("Option %q: %v", MinerTxOrderingFlag.Name, err)
+		}
+	}
 	if ctx.GlobalIsSet(MinerNoVerifyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerifyFlag.Name)
 	}
@@ -1504,6 +1690,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, RopstenFlag, RinkebyFlag, GoerliFlag, BttcDonauFlag, BttcMainnetFlag)
 	CheckExclusive(ctx, LightServeFlag, SyncModeFlag, "light")
 	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, DeveloperBorFlag, WithoutHeimdallFlag)
+	if ctx.GlobalBool(DeveloperBorFlag.Name) && !ctx.GlobalBool(DeveloperFlag.Name) {
+		Fatalf("Flag --%s is only available in developer mode (--%s)", DeveloperBorFlag.Name, DeveloperFlag.Name)
+	}
 	if ctx.GlobalString(GCModeFlag.Name) == "archive" && ctx.GlobalUint64(TxLookupLimitFlag.Name) != 0 {
 		ctx.GlobalSet(TxLookupLimitFlag.Name, "0")
 		log.Warn("Disable transaction unindexing for archive node")
@@ -1610,6 +1800,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(TraceIndexFlag.Name) {
+		cfg.TraceIndex = ctx.GlobalBool(TraceIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(TraceCacheFlag.Name) {
+		cfg.TraceCache = ctx.GlobalBool(TraceCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(TraceCacheTracersFlag.Name) {
+		cfg.TraceCacheTracers = SplitAndTrim(ctx.GlobalString(TraceCacheTracersFlag.Name))
+	}
+	if ctx.GlobalIsSet(TraceCacheBlocksFlag.Name) {
+		cfg.TraceCacheBlocks = ctx.GlobalUint64(TraceCacheBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(TraceCacheTxBlocksFlag.Name) {
+		cfg.TraceCacheTxBlocks = ctx.GlobalBool(TraceCacheTxBlocksFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.GlobalUint64(RPCGlobalGasCapFlag.Name)
@@ -1688,7 +1893,11 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 			passphrase = list[0]
 		}
 		// setEtherbase has been called above, configuring the miner address from command line flags.
-		if cfg.Miner.Etherbase != (common.Address{}) {
+		if ctx.GlobalBool(DeveloperBorFlag.Name) {
+			// Bor developer chains can only be sealed by the validator in the genesis contracts
+			developer = makeBorDeveloper(ctx, stack, ks, passphrase)
+			cfg.Miner.Etherbase = developer.Address
+		} else if cfg.Miner.Etherbase != (common.Address{}) {
 			developer = accounts.Account{Address: cfg.Miner.Etherbase}
 		} else if accs := ks.Accounts(); len(accs) > 0 {
 			developer = ks.Accounts()[0]
@@ -1704,7 +1913,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Info("Using developer account", "address", developer.Address)
 
 		// Create a new developer genesis block or reuse existing one
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+		if ctx.GlobalBool(DeveloperBorFlag.Name) {
+			cfg.Genesis = core.DeveloperBorGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)))
+			cfg.HeimdallClient = bor.NewDevHeimdallClient(cfg.Genesis.Config.ChainID, cfg.Genesis.Config.Bor.Sprint, []*bor.Validator{
+				bor.NewValidator(developer.Address, 10), // Same power as in the genesis contracts
+			})
+		} else {
+			cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+		}
 		if ctx.GlobalIsSet(DataDirFlag.Name) {
 			// Check if we have an already initialized chain and fall back to
 			// that if so. Otherwise we need to generate a new genesis spec.
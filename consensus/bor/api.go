@@ -1,6 +1,7 @@
 package bor
 
 import (
+	"context"
 	"encoding/hex"
 	"math"
 	"math/big"
@@ -123,6 +124,17 @@ func (api *API) GetCurrentValidators() ([]*Validator, error) {
 	return snap.ValidatorSet.Validators, nil
 }
 
+// SimulateSprintEnd runs the span and state-sync commits of the next sprint
+// start block on top of the given block (pending if none requested), returning
+// the system calls made and the state changes, without persisting anything.
+func (api *API) SimulateSprintEnd(ctx context.Context, number *rpc.BlockNumber) (*SprintEndSimulation, error) {
+	if number == nil {
+		pending := rpc.PendingBlockNumber
+		number = &pending
+	}
+	return api.bor.SimulateSprintEnd(ctx, api.chain, *number)
+}
+
 // GetRootHash returns the merkle root of the start to end block headers
 func (api *API) GetRootHash(start uint64, end uint64) (string, error) {
 	if err := api.initializeRootHashCache(); err != nil {
@@ -0,0 +1,158 @@
+package bor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// BlockAllocAccount is the override of a single account scheduled through the
+// bor config's block alloc section.
+//
+// The code is always replaced, even if empty, as the block alloc section has
+// always been a plain code swap. The storage slots and the balance are only
+// overridden through the dedicated setStorage and setBalance fields: allocs have
+// long been written in the genesis alloc format, whose storage and balance were
+// never applied, so honouring them would change the state of existing chains.
+type BlockAllocAccount struct {
+	Code       hexutil.Bytes               `json:"code"`
+	SetStorage map[common.Hash]common.Hash `json:"setStorage,omitempty"`
+	SetBalance *math.HexOrDecimal256       `json:"setBalance,omitempty"`
+}
+
+// knownBlockAllocFields are the fields of account overrides, including those of
+// the genesis alloc format that are accepted but ignored.
+var knownBlockAllocFields = map[string]bool{
+	"code":       true,
+	"setStorage": true,
+	"setBalance": true,
+	"storage":    true,
+	"balance":    true,
+	"nonce":      true,
+	"secretKey":  true,
+}
+
+// BlockAlloc is the set of account overrides applied when finalizing a block.
+type BlockAlloc struct {
+	Number   uint64
+	Accounts map[common.Address]*BlockAllocAccount
+}
+
+// ParseBlockAllocs decodes the block alloc section of the bor config. Entries
+// keyed by anything but a canonical decimal block number never matched a block,
+// so they are skipped with a warning rather than rejected, as are unknown fields
+// of the overrides. Only allocs that can't be applied are reported as errors.
+func ParseBlockAllocs(config map[string]interface{}) (map[uint64]*BlockAlloc, error) {
+	allocs := make(map[uint64]*BlockAlloc, len(config))
+	for key, raw := range config {
+		number, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || strconv.FormatUint(number, 10) != key {
+			log.Warn("Ignoring block alloc not keyed by a block number", "key", key)
+			continue
+		}
+		accounts, err := decodeBlockAlloc(number, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block alloc for block %d: %v", number, err)
+		}
+		allocs[number] = &BlockAlloc{Number: number, Accounts: accounts}
+	}
+	return allocs, nil
+}
+
+// decodeBlockAlloc converts a generically decoded (or hand written) alloc into
+// account overrides, warning about the fields it doesn't know of.
+func decodeBlockAlloc(number uint64, raw interface{}) (map[common.Address]*BlockAllocAccount, error) {
+	blob, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var accounts map[common.UnprefixedAddress]map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &accounts); err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("no accounts")
+	}
+	overrides := make(map[common.Address]*BlockAllocAccount, len(accounts))
+	for addr, fields := range accounts {
+		if fields == nil {
+			return nil, fmt.Errorf("missing override for %s", common.Address(addr).Hex())
+		}
+		for name := range fields {
+			if !knownBlockAllocFields[name] {
+				log.Warn("Ignoring unknown block alloc field", "number", number, "address", common.Address(addr), "field", name)
+			}
+		}
+		var account BlockAllocAccount
+		if err := decodeBlockAllocField(fields, "code", &account.Code); err != nil {
+			return nil, err
+		}
+		if err := decodeBlockAllocField(fields, "setStorage", &account.SetStorage); err != nil {
+			return nil, err
+		}
+		if err := decodeBlockAllocField(fields, "setBalance", &account.SetBalance); err != nil {
+			return nil, err
+		}
+		overrides[common.Address(addr)] = &account
+	}
+	return overrides, nil
+}
+
+// decodeBlockAllocField decodes a field of an account override, if present.
+func decodeBlockAllocField(fields map[string]json.RawMessage, name string, v interface{}) error {
+	blob, ok := fields[name]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		return fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return nil
+}
+
+// Addresses returns the overridden accounts in ascending order, which is the
+// order the overrides are applied in.
+func (a *BlockAlloc) Addresses() []common.Address {
+	addrs := make([]common.Address, 0, len(a.Accounts))
+	for addr := range a.Accounts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	return addrs
+}
+
+// Apply writes the account overrides into the given state.
+func (a *BlockAlloc) Apply(statedb *state.StateDB) {
+	for _, addr := range a.Addresses() {
+		account := a.Accounts[addr]
+
+		log.Info("Change contract code", "number", a.Number, "address", addr, "slots", len(account.SetStorage), "balance", account.SetBalance != nil)
+		statedb.SetCode(addr, account.Code)
+
+		keys := make([]common.Hash, 0, len(account.SetStorage))
+		for key := range account.SetStorage {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return bytes.Compare(keys[i][:], keys[j][:]) < 0
+		})
+		for _, key := range keys {
+			statedb.SetState(addr, key, account.SetStorage[key])
+		}
+		if account.SetBalance != nil {
+			statedb.SetBalance(addr, (*big.Int)(account.SetBalance))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package bor
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBlockAllocs(t *testing.T) {
+	account := map[string]interface{}{"code": "0x01"}
+
+	tests := []struct {
+		config map[string]interface{}
+		count  int
+		fail   bool
+	}{
+		{config: nil},
+		{config: map[string]interface{}{"10": map[string]interface{}{"0000000000000000000000000000000000001010": account}}, count: 1},
+		// Keys never matching a block are skipped
+		{config: map[string]interface{}{"0x10": map[string]interface{}{"0000000000000000000000000000000000001010": account}}},
+		{config: map[string]interface{}{"010": map[string]interface{}{"0000000000000000000000000000000000001010": account}}},
+		{config: map[string]interface{}{"-1": map[string]interface{}{"0000000000000000000000000000000000001010": account}}},
+		// Unknown and legacy fields are ignored
+		{config: map[string]interface{}{"10": map[string]interface{}{"0000000000000000000000000000000000001010": map[string]interface{}{"code": "0x01", "storgae": map[string]interface{}{}}}}, count: 1},
+		{config: map[string]interface{}{"10": map[string]interface{}{"0000000000000000000000000000000000001010": map[string]interface{}{"code": "0x01", "storage": map[string]interface{}{"0x01": "0x02"}}}}, count: 1},
+		// Overrides that can't be applied are rejected
+		{config: map[string]interface{}{"10": map[string]interface{}{}}, fail: true},
+		{config: map[string]interface{}{"10": map[string]interface{}{"0x1010": account}}, fail: true},
+		{config: map[string]interface{}{"10": map[string]interface{}{"0000000000000000000000000000000000001010": nil}}, fail: true},
+		{config: map[string]interface{}{"10": map[string]interface{}{"0000000000000000000000000000000000001010": map[string]interface{}{"code": "0x0g"}}}, fail: true},
+		{config: map[string]interface{}{"10": map[string]interface{}{"0000000000000000000000000000000000001010": map[string]interface{}{"code": "0x01", "setStorage": map[string]interface{}{"0x01": "0x02"}}}}, fail: true},
+	}
+	for i, tt := range tests {
+		allocs, err := ParseBlockAllocs(tt.config)
+		if tt.fail && err == nil {
+			t.Errorf("test %d: expected failure", i)
+		}
+		if !tt.fail && err != nil {
+			t.Errorf("test %d: unexpected failure: %v", i, err)
+		}
+		if !tt.fail && len(allocs) != tt.count {
+			t.Errorf("test %d: alloc count mismatch: have %d, want %d", i, len(allocs), tt.count)
+		}
+	}
+}
+
+// Tests that the upgrades scheduled on the live networks keep decoding.
+func TestBuiltinBlockAllocs(t *testing.T) {
+	for _, config := range []*params.ChainConfig{params.BttcMainnetChainConfig, params.BttcDonauChainConfig} {
+		allocs, err := ParseBlockAllocs(config.Bor.BlockAlloc)
+		if err != nil {
+			t.Fatalf("chain %v: %v", config.ChainID, err)
+		}
+		if len(allocs) != len(config.Bor.BlockAlloc) {
+			t.Fatalf("chain %v: alloc count mismatch: have %d, want %d", config.ChainID, len(allocs), len(config.Bor.BlockAlloc))
+		}
+	}
+}
+
+func TestBlockAllocOverrides(t *testing.T) {
+	var (
+		addr0 = common.HexToAddress("0x1010")
+		addr1 = common.HexToAddress("0x1011")
+		slot0 = common.HexToHash("0x00")
+		slot1 = common.HexToHash("0x01")
+	)
+	allocs, err := ParseBlockAllocs(map[string]interface{}{
+		"5": map[string]interface{}{
+			addr0.Hex(): map[string]interface{}{
+				"code":    "0x6001",
+				"balance": "0x1000", // placeholder, must not be applied
+				"storage": map[string]interface{}{ // genesis format, must not be applied
+					slot0.Hex(): "0x000000000000000000000000000000000000000000000000000000000000002b",
+				},
+				"setStorage": map[string]interface{}{
+					slot1.Hex(): "0x000000000000000000000000000000000000000000000000000000000000002a",
+				},
+			},
+			addr1.Hex(): map[string]interface{}{
+				"code":       "0x6002",
+				"setBalance": "0x1000",
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []common.Address{addr0, addr1}, allocs[5].Addresses())
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetBalance(addr0, big.NewInt(7))
+	statedb.SetState(addr0, slot0, common.HexToHash("0x07"))
+
+	allocs[5].Apply(statedb)
+
+	assert.Equal(t, []byte{0x60, 0x01}, statedb.GetCode(addr0))
+	assert.Equal(t, big.NewInt(7), statedb.GetBalance(addr0))
+	assert.Equal(t, common.HexToHash("0x07"), statedb.GetState(addr0, slot0))
+	assert.Equal(t, common.HexToHash("0x2a"), statedb.GetState(addr0, slot1))
+
+	assert.Equal(t, []byte{0x60, 0x02}, statedb.GetCode(addr1))
+	assert.Equal(t, big.NewInt(0x1000), statedb.GetBalance(addr1))
+}
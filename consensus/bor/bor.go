@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -65,6 +66,12 @@ var (
 	systemAddress              = common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE")
 )
 
+var (
+	sealDelayTimer = metrics.NewRegisteredTimer("bor/seal/delay", nil) // Time blocks wait after sealing for their slot
+	sealLateTimer  = metrics.NewRegisteredTimer("bor/seal/late", nil)  // Time blocks are sealed past their slot
+	sealLateMeter  = metrics.NewRegisteredMeter("bor/seal/latecount", nil)
+)
+
 // Various error messages to mark blocks invalid. These should be private to
 // prevent engine specific errors from being referenced in the remainder of the
 // codebase, inherently breaking if the engine is swapped out. Please put common
@@ -230,6 +237,8 @@ type Bor struct {
 	HeimdallClient         IHeimdallClient
 	WithoutHeimdall        bool
 
+	blockAllocs map[uint64]*BlockAlloc // Account overrides scheduled by the config, keyed by block number
+
 	scope event.SubscriptionScope
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
@@ -272,12 +281,12 @@ func New(
 		WithoutHeimdall:        withoutHeimdall,
 	}
 
-	// make sure we can decode all the GenesisAlloc in the BorConfig.
-	for key, genesisAlloc := range c.config.BlockAlloc {
-		if _, err := decodeGenesisAlloc(genesisAlloc); err != nil {
-			panic(fmt.Sprintf("BUG: Block alloc '%s' in genesis is not correct: %v", key, err))
-		}
+	// make sure we can decode all the block allocs in the BorConfig.
+	blockAllocs, err := ParseBlockAllocs(c.config.BlockAlloc)
+	if err != nil {
+		panic(fmt.Sprintf("BUG: Block alloc in genesis is not correct: %v", err))
 	}
+	c.blockAllocs = blockAllocs
 
 	return c
 }
@@ -712,10 +721,7 @@ func (c *Bor) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 		}
 	}
 
-	if err = c.changeContractCodeIfNeeded(headerNumber, state); err != nil {
-		log.Error("Error changing contract code", "error", err)
-		return
-	}
+	c.changeContractCodeIfNeeded(headerNumber, state)
 
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
@@ -726,32 +732,10 @@ func (c *Bor) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 	bc.SetStateSync(stateSyncData)
 }
 
-func decodeGenesisAlloc(i interface{}) (core.GenesisAlloc, error) {
-	var alloc core.GenesisAlloc
-	b, err := json.Marshal(i)
-	if err != nil {
-		return nil, err
-	}
-	if err := json.Unmarshal(b, &alloc); err != nil {
-		return nil, err
-	}
-	return alloc, nil
-}
-
-func (c *Bor) changeContractCodeIfNeeded(headerNumber uint64, state *state.StateDB) error {
-	for blockNumber, genesisAlloc := range c.config.BlockAlloc {
-		if blockNumber == strconv.FormatUint(headerNumber, 10) {
-			allocs, err := decodeGenesisAlloc(genesisAlloc)
-			if err != nil {
-				return fmt.Errorf("failed to decode genesis alloc: %v", err)
-			}
-			for addr, account := range allocs {
-				log.Info("change contract code", "address", addr)
-				state.SetCode(addr, account.Code)
-			}
-		}
+func (c *Bor) changeContractCodeIfNeeded(headerNumber uint64, state *state.StateDB) {
+	if alloc, ok := c.blockAllocs[headerNumber]; ok {
+		alloc.Apply(state)
 	}
-	return nil
 }
 
 // FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
@@ -780,10 +764,7 @@ func (c *Bor) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *typ
 		}
 	}
 
-	if err := c.changeContractCodeIfNeeded(headerNumber, state); err != nil {
-		log.Error("Error changing contract code", "error", err)
-		return nil, err
-	}
+	c.changeContractCodeIfNeeded(headerNumber, state)
 
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
@@ -857,6 +838,13 @@ func (c *Bor) Seal(chain consensus.ChainHeaderReader, block *types.Block, result
 	}
 	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
 
+	if delay >= 0 {
+		sealDelayTimer.Update(delay)
+	} else {
+		sealLateTimer.Update(-delay)
+		sealLateMeter.Mark(1)
+		log.Warn("Sealing block past its slot", "number", number, "late", common.PrettyDuration(-delay))
+	}
 	// Wait until sealing is terminated or delay timeout.
 	log.Trace("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(delay))
 	go func() {
@@ -905,15 +893,36 @@ func (c *Bor) SealHash(header *types.Header) common.Hash {
 	return SealHash(header)
 }
 
+// SlotDeadline implements consensus.Slotted, returning the header time: Prepare
+// already offsets it by the producer delay and backup wiggle of the local signer,
+// so that is when Seal broadcasts the block. Zero period chains seal blocks as
+// soon as they contain transactions, so they don't have one.
+func (c *Bor) SlotDeadline(header *types.Header) (time.Time, bool) {
+	if c.config.Period == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(header.Time), 0), true
+}
+
 // APIs implements consensus.Engine, returning the user facing RPC API to allow
 // controlling the signer voting.
 func (c *Bor) APIs(chain consensus.ChainHeaderReader) []rpc.API {
-	return []rpc.API{{
+	apis := []rpc.API{{
 		Namespace: "bor",
 		Version:   "1.0",
 		Service:   &API{chain: chain, bor: c},
 		Public:    false,
 	}}
+	// Developer networks also expose the controls of their fake heimdall
+	if heimdall, ok := c.HeimdallClient.(*DevHeimdallClient); ok {
+		apis = append(apis, rpc.API{
+			Namespace: "dev",
+			Version:   "1.0",
+			Service:   &DevAPI{heimdall: heimdall},
+			Public:    false,
+		})
+	}
+	return apis
 }
 
 // StopClient implements consensus.Engine. It will close any information fetching client before closing engine.
@@ -950,7 +959,7 @@ func (c *Bor) GetCurrentSpan(headerHash common.Hash) (*Span, error) {
 		Gas:  &gas,
 		To:   &toAddress,
 		Data: &msgData,
-	}, blockNr, nil)
+	}, blockNr, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1000,7 +1009,7 @@ func (c *Bor) GetCurrentValidators(headerHash common.Hash, blockNumber uint64) (
 		Gas:  &gas,
 		To:   &toAddress,
 		Data: &msgData,
-	}, blockNr, nil)
+	}, blockNr, nil, nil)
 	if err != nil {
 		panic(err)
 		// return nil, err
@@ -1053,7 +1062,7 @@ func (c *Bor) GetCurrentValidatorsByBlockNrOrHash(ctx context.Context, blockNrOr
 		Gas:  &gas,
 		To:   &toAddress,
 		Data: &msgData,
-	}, blockNrOrHash, nil)
+	}, blockNrOrHash, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1209,15 +1218,26 @@ func (c *Bor) CommitStates(
 	header *types.Header,
 	chain chainContext,
 ) ([]*types.StateSyncData, error) {
-	stateSyncs := make([]*types.StateSyncData, 0)
 	number := header.Number.Uint64()
 	_lastStateID, err := c.GenesisContractsClient.LastStateId(number - 1)
 	if err != nil {
 		return nil, err
 	}
+	return c.commitStatesSince(state, header, chain, _lastStateID.Uint64())
+}
+
+// commitStatesSince commits the state-sync events following lastStateID which
+// were recorded on Heimdall before the start of the previous sprint.
+func (c *Bor) commitStatesSince(
+	state *state.StateDB,
+	header *types.Header,
+	chain chainContext,
+	lastStateID uint64,
+) ([]*types.StateSyncData, error) {
+	stateSyncs := make([]*types.StateSyncData, 0)
+	number := header.Number.Uint64()
 
 	to := time.Unix(int64(chain.Chain.GetHeaderByNumber(number-c.config.Sprint).Time), 0)
-	lastStateID := _lastStateID.Uint64()
 	log.Info(
 		"Fetching state updates from Heimdall",
 		"fromID", lastStateID+1,
@@ -1327,6 +1347,8 @@ func (c *Bor) getNextHeimdallSpanForTest(
 type chainContext struct {
 	Chain consensus.ChainHeaderReader
 	Bor   consensus.Engine
+
+	recorder *systemCallRecorder // Records the system calls made, set by simulations only
 }
 
 func (c chainContext) Engine() consensus.Engine {
@@ -1371,15 +1393,21 @@ func applyMessage(
 	state *state.StateDB,
 	header *types.Header,
 	chainConfig *params.ChainConfig,
-	chainContext core.ChainContext,
+	chainCtx core.ChainContext,
 ) error {
+	// Trace the call if it's part of a simulation
+	var vmConfig vm.Config
+	recorder := systemCallRecorderOf(chainCtx)
+	if recorder != nil {
+		vmConfig = vm.Config{Debug: true, Tracer: recorder.tracer}
+	}
 	// Create a new context to be used in the EVM environment
-	blockContext := core.NewEVMBlockContext(header, chainContext, &header.Coinbase)
+	blockContext := core.NewEVMBlockContext(header, chainCtx, &header.Coinbase)
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
-	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, state, chainConfig, vm.Config{})
+	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, state, chainConfig, vmConfig)
 	// Apply the transaction to the current state (included in the env)
-	_, _, err := vmenv.Call(
+	ret, leftOverGas, err := vmenv.Call(
 		vm.AccountRef(msg.From()),
 		*msg.To(),
 		msg.Data(),
@@ -1390,7 +1418,9 @@ func applyMessage(
 	if err != nil {
 		state.Finalise(true)
 	}
-
+	if recorder != nil {
+		recorder.record(msg, ret, msg.Gas()-leftOverGas, err)
+	}
 	return nil
 }
 
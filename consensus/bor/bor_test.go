@@ -18,26 +18,27 @@ import (
 func TestGenesisContractChange(t *testing.T) {
 	addr0 := common.Address{0x1}
 
-	b := &Bor{
-		config: &params.BorConfig{
-			Sprint: 10, // skip sprint transactions in sprint
-			BlockAlloc: map[string]interface{}{
-				// write as interface since that is how it is decoded in genesis
-				"2": map[string]interface{}{
-					addr0.Hex(): map[string]interface{}{
-						"code":    hexutil.Bytes{0x1, 0x2},
-						"balance": "0",
-					},
+	borConfig := &params.BorConfig{
+		Sprint: 10, // skip sprint transactions in sprint
+		BlockAlloc: map[string]interface{}{
+			// write as interface since that is how it is decoded in genesis
+			"2": map[string]interface{}{
+				addr0.Hex(): map[string]interface{}{
+					"code":    hexutil.Bytes{0x1, 0x2},
+					"balance": "0",
 				},
-				"4": map[string]interface{}{
-					addr0.Hex(): map[string]interface{}{
-						"code":    hexutil.Bytes{0x1, 0x3},
-						"balance": "0x1000",
-					},
+			},
+			"4": map[string]interface{}{
+				addr0.Hex(): map[string]interface{}{
+					"code":    hexutil.Bytes{0x1, 0x3},
+					"balance": "0x1000",
 				},
 			},
 		},
 	}
+	allocs, err := ParseBlockAllocs(borConfig.BlockAlloc)
+	assert.NoError(t, err)
+	b := &Bor{config: borConfig, blockAllocs: allocs}
 
 	genspec := &core.Genesis{
 		Alloc: map[common.Address]core.GenesisAccount{
@@ -0,0 +1,140 @@
+package bor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	devFirstSpanStart = 256 // First block of the first span committed by the dev heimdall
+	devSpanSprints    = 100 // Number of sprints in each span served by the dev heimdall
+)
+
+// DevHeimdallClient is an in-process stand-in for Heimdall used by developer
+// networks. It serves spans made up of a fixed validator set and the state-sync
+// events injected through the dev RPC API.
+type DevHeimdallClient struct {
+	chainID    string
+	sprint     uint64
+	validators []*Validator
+
+	events []*EventRecordWithTime // State-sync events injected so far, ordered by ID
+	lock   sync.RWMutex           // Protects the events
+}
+
+// NewDevHeimdallClient creates a fake Heimdall for the given chain, producing
+// spans that always select the given validators.
+func NewDevHeimdallClient(chainID *big.Int, sprint uint64, validators []*Validator) *DevHeimdallClient {
+	return &DevHeimdallClient{
+		chainID:    chainID.String(),
+		sprint:     sprint,
+		validators: validators,
+	}
+}
+
+// Fetch implements IHeimdallClient, serving the span endpoint only.
+func (h *DevHeimdallClient) Fetch(path string, query string) (*ResponseWithHeight, error) {
+	var id uint64
+	if _, err := fmt.Sscanf(path, "bor/span/%d", &id); err != nil || id == 0 {
+		return nil, fmt.Errorf("unsupported dev heimdall path %q", path)
+	}
+	length := devSpanSprints * h.sprint
+	start := devFirstSpanStart + (id-1)*length
+
+	validators := make([]*Validator, len(h.validators))
+	producers := make([]Validator, len(h.validators))
+	for i, val := range h.validators {
+		validators[i] = val.Copy()
+		producers[i] = *val.Copy()
+	}
+	span := &HeimdallSpan{
+		Span: Span{
+			ID:         id,
+			StartBlock: start,
+			EndBlock:   start + length - 1,
+		},
+		ValidatorSet:      *NewValidatorSet(validators),
+		SelectedProducers: producers,
+		ChainID:           h.chainID,
+	}
+	result, err := json.Marshal(span)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseWithHeight{Height: "0", Result: result}, nil
+}
+
+// FetchWithRetry implements IHeimdallClient. The dev heimdall never fails
+// transiently, so it's the same as Fetch.
+func (h *DevHeimdallClient) FetchWithRetry(path string, query string) (*ResponseWithHeight, error) {
+	return h.Fetch(path, query)
+}
+
+// FetchStateSyncEvents implements IHeimdallClient, returning the injected events
+// starting at fromID that were recorded before the given time.
+func (h *DevHeimdallClient) FetchStateSyncEvents(fromID uint64, to int64) ([]*EventRecordWithTime, error) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	events := make([]*EventRecordWithTime, 0)
+	for _, event := range h.events {
+		if event.ID >= fromID && event.Time.Unix() < to {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// Close implements IHeimdallClient. There's nothing to release.
+func (h *DevHeimdallClient) Close() {}
+
+// InjectStateSync records a new state-sync event for the given receiver, to be
+// committed by the first sprint whose reference block is newer than it.
+func (h *DevHeimdallClient) InjectStateSync(contract common.Address, data []byte) *EventRecordWithTime {
+	return h.InjectStateSyncAt(contract, data, time.Now())
+}
+
+// InjectStateSyncAt records a new state-sync event for the given receiver as if
+// Heimdall had observed it at the given time. The returned event is a copy, the
+// recorded one can't be changed anymore.
+func (h *DevHeimdallClient) InjectStateSyncAt(contract common.Address, data []byte, at time.Time) *EventRecordWithTime {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	event := &EventRecordWithTime{
+		EventRecord: EventRecord{
+			ID:       uint64(len(h.events)) + 1,
+			Contract: contract,
+			Data:     common.CopyBytes(data),
+			ChainID:  h.chainID,
+		},
+		Time: at,
+	}
+	h.events = append(h.events, event)
+
+	log.Info("Injected dev state-sync event", "id", event.ID, "contract", contract)
+
+	injected := *event
+	injected.Data = common.CopyBytes(event.Data)
+	return &injected
+}
+
+// DevAPI is the RPC API of developer networks, allowing state-sync events to be
+// fed into the chain through the in-process Heimdall.
+type DevAPI struct {
+	heimdall *DevHeimdallClient
+}
+
+// InjectStateSync queues a state-sync event carrying data for the receiver
+// contract and returns its ID. The event is committed at a subsequent sprint
+// start, exactly as an event relayed by Heimdall would be.
+func (api *DevAPI) InjectStateSync(contract common.Address, data hexutil.Bytes) hexutil.Uint64 {
+	return hexutil.Uint64(api.heimdall.InjectStateSync(contract, data).ID)
+}
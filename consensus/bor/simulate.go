@@ -0,0 +1,327 @@
+package bor
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// SystemCall is the outcome of a call made by the system address while
+// finalizing a sprint start block.
+type SystemCall struct {
+	To      common.Address `json:"to"`
+	Method  string         `json:"method"`
+	Input   hexutil.Bytes  `json:"input"`
+	Output  hexutil.Bytes  `json:"output"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Error   string         `json:"error,omitempty"`
+
+	// Failed is set if the call reverted, or if it was a state commit whose
+	// receiver rejected the event (the state receiver contract returning false).
+	Failed bool `json:"failed"`
+}
+
+// BalanceDiff is the change of an account balance.
+type BalanceDiff struct {
+	From *hexutil.Big `json:"from"`
+	To   *hexutil.Big `json:"to"`
+}
+
+// NonceDiff is the change of an account nonce.
+type NonceDiff struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// HashDiff is the change of a storage slot or of a code hash.
+type HashDiff struct {
+	From common.Hash `json:"from"`
+	To   common.Hash `json:"to"`
+}
+
+// AccountDiff is the set of changes made to an account, unchanged fields
+// being omitted.
+type AccountDiff struct {
+	Balance  *BalanceDiff              `json:"balance,omitempty"`
+	Nonce    *NonceDiff                `json:"nonce,omitempty"`
+	CodeHash *HashDiff                 `json:"codeHash,omitempty"`
+	Storage  map[common.Hash]*HashDiff `json:"storage,omitempty"`
+}
+
+// SprintEndSimulation is the outcome of finalizing the next sprint start block
+// on top of the pending state, without committing anything.
+type SprintEndSimulation struct {
+	Number      hexutil.Uint64                  `json:"number"`
+	ParentHash  common.Hash                     `json:"parentHash"`
+	Span        *Span                           `json:"span,omitempty"` // Span committed, if any
+	StateSyncs  []*types.StateSyncData          `json:"stateSyncs"`
+	SystemCalls []*SystemCall                   `json:"systemCalls"`
+	StateDiff   map[common.Address]*AccountDiff `json:"stateDiff"`
+}
+
+// systemCallRecorder collects the system calls of a simulated block, along with
+// the accounts and storage slots they touched.
+type systemCallRecorder struct {
+	abis   []abi.ABI
+	calls  []*SystemCall
+	tracer *touchTracer
+}
+
+// systemCallRecorderOf returns the recorder of a simulation's chain context, or
+// nil when finalizing blocks for real.
+func systemCallRecorderOf(chain core.ChainContext) *systemCallRecorder {
+	if cx, ok := chain.(chainContext); ok {
+		return cx.recorder
+	}
+	return nil
+}
+
+// record stores the outcome of a system call.
+func (r *systemCallRecorder) record(msg callmsg, ret []byte, gasUsed uint64, err error) {
+	call := &SystemCall{
+		To:      *msg.To(),
+		Input:   common.CopyBytes(msg.Data()),
+		Output:  common.CopyBytes(ret),
+		GasUsed: hexutil.Uint64(gasUsed),
+		Failed:  err != nil,
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	if len(call.Input) >= 4 {
+		for _, contractABI := range r.abis {
+			method, lookupErr := contractABI.MethodById(call.Input[:4])
+			if lookupErr != nil {
+				continue
+			}
+			call.Method = method.Name
+			// A state receiver swallows failing receivers, reporting it in its result
+			if method.Name == "commitState" && !call.Failed {
+				if out, unpackErr := method.Outputs.Unpack(ret); unpackErr == nil && len(out) == 1 {
+					if success, ok := out[0].(bool); ok && !success {
+						call.Failed = true
+					}
+				}
+			}
+			break
+		}
+	}
+	r.calls = append(r.calls, call)
+}
+
+// touchTracer is an EVM tracer recording the accounts and storage slots which
+// may have been modified during execution.
+type touchTracer struct {
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+func newTouchTracer() *touchTracer {
+	return &touchTracer{accounts: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+func (t *touchTracer) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := t.accounts[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		t.accounts[addr] = slots
+	}
+	return slots
+}
+
+func (t *touchTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.touch(from)
+	t.touch(to)
+}
+
+func (t *touchTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	stack := scope.Stack.Data()
+	back := func(n int) *big.Int {
+		return stack[len(stack)-1-n].ToBig()
+	}
+	caller := scope.Contract.Address()
+
+	switch {
+	case op == vm.SSTORE && len(stack) >= 1:
+		t.touch(caller)[common.BigToHash(back(0))] = struct{}{}
+	case (op == vm.CALL || op == vm.CALLCODE) && len(stack) >= 2:
+		t.touch(common.BigToAddress(back(1)))
+	case op == vm.SELFDESTRUCT && len(stack) >= 1:
+		t.touch(caller)
+		t.touch(common.BigToAddress(back(0)))
+	case op == vm.CREATE:
+		t.touch(caller)
+		t.touch(crypto.CreateAddress(caller, env.StateDB.GetNonce(caller)))
+	case op == vm.CREATE2 && len(stack) >= 4:
+		code := scope.Memory.GetCopy(back(1).Int64(), back(2).Int64())
+		salt := common.BigToHash(back(3))
+		t.touch(caller)
+		t.touch(crypto.CreateAddress2(caller, salt, crypto.Keccak256(code)))
+	}
+}
+
+func (t *touchTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *touchTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {}
+
+// diff compares the touched accounts between two states.
+func (t *touchTracer) diff(before, after *state.StateDB) map[common.Address]*AccountDiff {
+	diffs := make(map[common.Address]*AccountDiff)
+	for addr, slots := range t.accounts {
+		diff := new(AccountDiff)
+		if from, to := before.GetBalance(addr), after.GetBalance(addr); from.Cmp(to) != 0 {
+			diff.Balance = &BalanceDiff{From: (*hexutil.Big)(from), To: (*hexutil.Big)(to)}
+		}
+		if from, to := before.GetNonce(addr), after.GetNonce(addr); from != to {
+			diff.Nonce = &NonceDiff{From: hexutil.Uint64(from), To: hexutil.Uint64(to)}
+		}
+		if from, to := before.GetCodeHash(addr), after.GetCodeHash(addr); from != to {
+			diff.CodeHash = &HashDiff{From: from, To: to}
+		}
+		for slot := range slots {
+			if from, to := before.GetState(addr, slot), after.GetState(addr, slot); from != to {
+				if diff.Storage == nil {
+					diff.Storage = make(map[common.Hash]*HashDiff)
+				}
+				diff.Storage[slot] = &HashDiff{From: from, To: to}
+			}
+		}
+		if diff.Balance != nil || diff.Nonce != nil || diff.CodeHash != nil || diff.Storage != nil {
+			diffs[addr] = diff
+		}
+	}
+	return diffs
+}
+
+// SimulateSprintEnd finalizes the first sprint start block following the given
+// one (pending by default) on top of a copy of its state: the span commit (if
+// due) and the state-sync commits are executed as they would be when sealing
+// the block, but nothing is persisted.
+//
+// Blocks in between are assumed empty, so if the sprint start isn't the next
+// block the simulation may differ from what the block will actually do.
+func (c *Bor) SimulateSprintEnd(ctx context.Context, chain consensus.ChainHeaderReader, number rpc.BlockNumber) (*SprintEndSimulation, error) {
+	statedb, base, err := ethapi.StateAndHeaderByNumber(ctx, c.ethAPI, number)
+	if err != nil {
+		return nil, err
+	}
+	// Find the parent of the block to simulate, the base block itself unless
+	// it's the (yet unsealed) pending one
+	parent, first := base, base.Number.Uint64()+1
+	if chain.GetHeader(base.Hash(), base.Number.Uint64()) == nil {
+		if parent = chain.GetHeader(base.ParentHash, base.Number.Uint64()-1); parent == nil {
+			return nil, errUnknownBlock
+		}
+		first = base.Number.Uint64()
+	}
+	target := first
+	if rem := target % c.config.Sprint; rem != 0 {
+		target += c.config.Sprint - rem
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).SetUint64(target),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + (target-parent.Number.Uint64())*c.config.Period,
+		Difficulty: big.NewInt(1),
+		BaseFee:    base.BaseFee,
+	}
+	log.Debug("Simulating sprint end", "number", target, "parent", parent.Number)
+
+	recorder := &systemCallRecorder{
+		abis:   []abi.ABI{c.validatorSetABI, c.stateReceiverABI},
+		tracer: newTouchTracer(),
+	}
+	cx := chainContext{Chain: chain, Bor: c, recorder: recorder}
+	simulated := statedb.Copy()
+
+	if err := c.checkAndCommitSpan(simulated, header, cx); err != nil {
+		return nil, err
+	}
+	stateSyncs := make([]*types.StateSyncData, 0)
+	if !c.WithoutHeimdall {
+		// Only sprint starts commit states, so the parent sees the same last
+		// state ID as the block before the sprint start will
+		lastStateID, err := c.GenesisContractsClient.LastStateId(parent.Number.Uint64())
+		if err != nil {
+			return nil, err
+		}
+		if stateSyncs, err = c.commitStatesSince(simulated, header, cx, lastStateID.Uint64()); err != nil {
+			return nil, err
+		}
+	}
+	result := &SprintEndSimulation{
+		Number:      hexutil.Uint64(target),
+		ParentHash:  parent.Hash(),
+		StateSyncs:  stateSyncs,
+		SystemCalls: recorder.calls,
+		StateDiff:   recorder.tracer.diff(statedb, simulated),
+	}
+	for _, call := range recorder.calls {
+		if call.Method != "commitSpan" {
+			continue
+		}
+		args, err := c.validatorSetABI.Methods["commitSpan"].Inputs.Unpack(call.Input[4:])
+		if err != nil {
+			return nil, err
+		}
+		result.Span = &Span{
+			ID:         args[0].(*big.Int).Uint64(),
+			StartBlock: args[1].(*big.Int).Uint64(),
+			EndBlock:   args[2].(*big.Int).Uint64(),
+		}
+	}
+	return result, nil
+}
+
+// FinalizeSimulated implements ethapi.SimulatedFinalizer, running the system
+// logic of Finalize on a simulated block: sprint start blocks commit the given
+// state-sync events, as if fetched from Heimdall, and scheduled account
+// overrides are applied. Span commits are not simulated, the simulated parent
+// being unknown to the validator set contract queries.
+func (c *Bor) FinalizeSimulated(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, events []*ethapi.StateSyncEvent) error {
+	number := header.Number.Uint64()
+	if number%c.config.Sprint != 0 {
+		if len(events) > 0 {
+			return fmt.Errorf("state-sync events in block %d, not a sprint start", number)
+		}
+	} else {
+		cx := chainContext{Chain: chain, Bor: c}
+		chainID := c.chainConfig.ChainID.String()
+		for _, event := range events {
+			record := &EventRecordWithTime{
+				EventRecord: EventRecord{
+					ID:       uint64(event.ID),
+					Contract: event.Contract,
+					Data:     event.Data,
+					TxHash:   event.TxHash,
+					LogIndex: uint64(event.LogIndex),
+					ChainID:  chainID,
+				},
+				Time: time.Unix(int64(header.Time), 0),
+			}
+			if event.Time != nil {
+				record.Time = time.Unix(int64(*event.Time), 0)
+			}
+			if err := c.GenesisContractsClient.CommitState(record, state, header, cx); err != nil {
+				return err
+			}
+		}
+	}
+	c.changeContractCodeIfNeeded(number, state)
+	return nil
+}
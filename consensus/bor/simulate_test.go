@@ -0,0 +1,78 @@
+package bor
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that simulated sprint start blocks commit the given state-sync events to
+// the state receiver contract, their logs being collected as the ones of the
+// bor transaction, while other blocks reject them.
+func TestFinalizeSimulated(t *testing.T) {
+	receiver := common.HexToAddress("0x0000000000000000000000000000000000001001")
+
+	chainConfig := *params.TestChainConfig
+	chainConfig.Bor = &params.BorConfig{Sprint: 4, StateReceiverContract: receiver.Hex()}
+	b := &Bor{
+		chainConfig:            &chainConfig,
+		config:                 chainConfig.Bor,
+		GenesisContractsClient: NewGenesisContractsClient(&chainConfig, "", receiver.Hex(), nil),
+	}
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	assert.NoError(t, err)
+
+	// The receiver logs its calldata, and returns true
+	statedb.SetCode(receiver, common.FromHex("0x366000600037366000a0600160005260206000f3"))
+
+	eventTime := hexutil.Uint64(500)
+	events := []*ethapi.StateSyncEvent{
+		{ID: 1, Contract: common.Address{0x1}, Data: []byte{0x1}},
+		{ID: 2, Contract: common.Address{0x2}, Data: []byte{0x2}, Time: &eventTime},
+	}
+	// Blocks other than sprint starts don't commit state-syncs
+	header := &types.Header{Number: big.NewInt(3), Time: 1000, Difficulty: big.NewInt(1)}
+	assert.Error(t, b.FinalizeSimulated(nil, header, statedb, events))
+	assert.NoError(t, b.FinalizeSimulated(nil, header, statedb, nil))
+
+	// Sprint starts commit them, in order
+	header = &types.Header{Number: big.NewInt(4), Time: 1000, Difficulty: big.NewInt(1)}
+	txHash := types.GetDerivedBorTxHash(types.BorReceiptKey(4, header.ParentHash))
+	statedb.Prepare(txHash, 0)
+	assert.NoError(t, b.FinalizeSimulated(nil, header, statedb, events))
+
+	logs := statedb.GetLogs(txHash, common.Hash{})
+	assert.Len(t, logs, len(events))
+
+	method := b.GenesisContractsClient.stateReceiverABI.Methods["commitState"]
+	for i, log := range logs {
+		assert.Equal(t, receiver, log.Address)
+		assert.Equal(t, method.ID, log.Data[:4])
+
+		args, err := method.Inputs.Unpack(log.Data[4:])
+		assert.NoError(t, err)
+
+		// Events are committed at the block time, unless overridden
+		syncTime := uint64(header.Time)
+		if events[i].Time != nil {
+			syncTime = uint64(*events[i].Time)
+		}
+		assert.Equal(t, syncTime, args[0].(*big.Int).Uint64())
+
+		var record EventRecord
+		assert.NoError(t, rlp.DecodeBytes(args[1].([]byte), &record))
+		assert.Equal(t, uint64(events[i].ID), record.ID)
+		assert.Equal(t, events[i].Contract, record.Contract)
+		assert.Equal(t, []byte(events[i].Data), []byte(record.Data))
+		assert.Equal(t, chainConfig.ChainID.String(), record.ChainID)
+	}
+}
@@ -0,0 +1,125 @@
+package bor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// proposerTest is a validator set evolution along with the proposers expected
+// to be selected by Heimdall along the way.
+type proposerTest struct {
+	Name       string             `json:"name"`
+	Validators []proposerTestVal  `json:"validators"`
+	Proposer   common.Address     `json:"proposer"` // Selected upon creating the set
+	Steps      []proposerTestStep `json:"steps"`
+}
+
+// proposerTestStep is a changeset applied to the validator set, followed by a
+// proposer selection round for every expected proposer.
+type proposerTestStep struct {
+	Changes    []proposerTestVal `json:"changes,omitempty"`
+	Proposers  []common.Address  `json:"proposers"`
+	Priorities []int64           `json:"priorities,omitempty"` // Expected after the last round, by address
+}
+
+type proposerTestVal struct {
+	Address common.Address `json:"address"`
+	Power   int64          `json:"power"`
+}
+
+func makeValidators(vals []proposerTestVal) []*Validator {
+	validators := make([]*Validator, len(vals))
+	for i, val := range vals {
+		validators[i] = NewValidator(val.Address, val.Power)
+	}
+	return validators
+}
+
+// Tests the proposer selection against a corpus of validator changesets and the
+// proposer sequences they yield, guarding against diverging from Heimdall. The
+// first vector is Tendermint's TestProposerSelection1, the rest pin down the
+// current behaviour, differentially checked in tests/fuzzers/validatorset.
+func TestProposerSelectionCorpus(t *testing.T) {
+	blob, err := ioutil.ReadFile("testdata/validator_set_proposers.json")
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+	var tests []proposerTest
+	if err := json.Unmarshal(blob, &tests); err != nil {
+		t.Fatalf("failed to decode corpus: %v", err)
+	}
+	for _, tt := range tests {
+		set := NewValidatorSet(makeValidators(tt.Validators))
+		if have := set.GetProposer().Address; have != tt.Proposer {
+			t.Errorf("%s: initial proposer mismatch: have %x, want %x", tt.Name, have, tt.Proposer)
+			continue
+		}
+	steps:
+		for i, step := range tt.Steps {
+			if len(step.Changes) > 0 {
+				if err := set.UpdateWithChangeSet(makeValidators(step.Changes)); err != nil {
+					t.Errorf("%s: step %d: failed to apply changes: %v", tt.Name, i, err)
+					break
+				}
+			}
+			for j, want := range step.Proposers {
+				set.IncrementProposerPriority(1)
+				if have := set.GetProposer().Address; have != want {
+					t.Errorf("%s: step %d: round %d: proposer mismatch: have %x, want %x", tt.Name, i, j, have, want)
+					break steps
+				}
+			}
+			if step.Priorities == nil {
+				continue
+			}
+			if len(step.Priorities) != len(set.Validators) {
+				t.Errorf("%s: step %d: validator count mismatch: have %d, want %d", tt.Name, i, len(set.Validators), len(step.Priorities))
+				break
+			}
+			for j, val := range set.Validators {
+				if val.ProposerPriority != step.Priorities[j] {
+					t.Errorf("%s: step %d: validator %x priority mismatch: have %d, want %d", tt.Name, i, val.Address, val.ProposerPriority, step.Priorities[j])
+					break steps
+				}
+			}
+		}
+	}
+}
+
+// Tests that failing changesets leave the validator set untouched.
+func TestUpdateWithChangeSetErrors(t *testing.T) {
+	var (
+		addr1 = common.HexToAddress("0x01")
+		addr2 = common.HexToAddress("0x02")
+		addr3 = common.HexToAddress("0x03")
+	)
+	tests := [][]*Validator{
+		{NewValidator(addr1, 10), NewValidator(addr1, 20)}, // duplicate entry
+		{NewValidator(addr1, -1)},                          // negative power
+		{NewValidator(addr3, MaxTotalVotingPower)},         // total power overflow
+		{NewValidator(addr3, 0)},                           // removing unknown validator
+		{NewValidator(addr1, 0), NewValidator(addr2, 0)},   // empty resulting set
+		{NewValidator(common.Address{}, 10)},               // zero address
+		{NewValidator(addr3, MaxTotalVotingPower+1)},       // single power overflow
+	}
+	for i, changes := range tests {
+		set := NewValidatorSet([]*Validator{NewValidator(addr1, 10), NewValidator(addr2, 20)})
+		set.IncrementProposerPriority(3)
+		want := set.Copy()
+
+		if err := set.UpdateWithChangeSet(changes); err == nil {
+			t.Errorf("test %d: expected failure", i)
+		}
+		if len(set.Validators) != len(want.Validators) {
+			t.Fatalf("test %d: validator count changed: have %d, want %d", i, len(set.Validators), len(want.Validators))
+		}
+		for j, val := range set.Validators {
+			if *val != *want.Validators[j] {
+				t.Errorf("test %d: validator %d changed: have %v, want %v", i, j, val, want.Validators[j])
+			}
+		}
+	}
+}
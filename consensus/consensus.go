@@ -19,6 +19,7 @@ package consensus
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -122,6 +123,16 @@ type Engine interface {
 	Close() error
 }
 
+// Slotted is a consensus engine assigning time slots to block producers, with
+// blocks due to be sealed and broadcast by the end of their producer's slot.
+type Slotted interface {
+	Engine
+
+	// SlotDeadline returns the time by which the block of a prepared header is
+	// due to be broadcast, or false if the engine doesn't impose one.
+	SlotDeadline(header *types.Header) (time.Time, bool)
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
@@ -0,0 +1,275 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// ErrBundleEmpty is returned if a bundle has no transactions.
+	ErrBundleEmpty = errors.New("empty bundle")
+
+	// ErrBundleTooLarge is returned if a bundle has more transactions than
+	// allowed by the pool configuration.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleStale is returned if a bundle targets a block which was already
+	// produced, or a timestamp range which can't be met any more.
+	ErrBundleStale = errors.New("stale bundle")
+
+	// ErrBundlePoolFull is returned if the bundle pool has no room left.
+	ErrBundlePoolFull = errors.New("bundle pool is full")
+)
+
+var (
+	bundleAddMeter   = metrics.NewRegisteredMeter("bundlepool/add", nil)
+	bundleStaleMeter = metrics.NewRegisteredMeter("bundlepool/stale", nil) // Dropped as the target block passed
+	bundleMinedMeter = metrics.NewRegisteredMeter("bundlepool/mined", nil) // Dropped as some transactions were already mined
+	bundleGauge      = metrics.NewRegisteredGauge("bundlepool/bundles", nil)
+)
+
+// Bundle is a list of transactions to be included atomically and in order into
+// a given block, or not at all.
+type Bundle struct {
+	Txs          types.Transactions
+	BlockNumber  *big.Int // Number of the block to include the bundle into
+	MinTimestamp uint64   // Minimum block timestamp, zero if unbounded
+	MaxTimestamp uint64   // Maximum block timestamp, zero if unbounded
+
+	// RevertingTxHashes are the transactions allowed to fail execution without
+	// the whole bundle being dropped.
+	RevertingTxHashes []common.Hash
+}
+
+// Hash returns the hash of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// CanRevert returns whether the given transaction of the bundle is allowed to
+// fail execution.
+func (b *Bundle) CanRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// includable returns whether the bundle can be included into a block with the
+// given number and timestamp.
+func (b *Bundle) includable(number *big.Int, time uint64) bool {
+	if b.BlockNumber.Cmp(number) != 0 {
+		return false
+	}
+	if b.MinTimestamp != 0 && time < b.MinTimestamp {
+		return false
+	}
+	return b.MaxTimestamp == 0 || time <= b.MaxTimestamp
+}
+
+// stale returns whether the bundle can't be included on top of the given head
+// any more.
+func (b *Bundle) stale(head *types.Header) bool {
+	if b.BlockNumber.Cmp(head.Number) <= 0 {
+		return true
+	}
+	return b.MaxTimestamp != 0 && head.Time >= b.MaxTimestamp
+}
+
+// BundlePoolConfig are the configuration parameters of the bundle pool.
+type BundlePoolConfig struct {
+	MaxBundles   int // Maximum number of bundles waiting for inclusion
+	MaxBundleTxs int // Maximum number of transactions in a bundle
+}
+
+// DefaultBundlePoolConfig contains the default configurations for the bundle
+// pool.
+var DefaultBundlePoolConfig = BundlePoolConfig{
+	MaxBundles:   1024,
+	MaxBundleTxs: 16,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *BundlePoolConfig) sanitize() BundlePoolConfig {
+	conf := *config
+	if conf.MaxBundles < 1 {
+		log.Warn("Sanitizing invalid bundlepool max bundles", "provided", conf.MaxBundles, "updated", DefaultBundlePoolConfig.MaxBundles)
+		conf.MaxBundles = DefaultBundlePoolConfig.MaxBundles
+	}
+	if conf.MaxBundleTxs < 1 {
+		log.Warn("Sanitizing invalid bundlepool max bundle size", "provided", conf.MaxBundleTxs, "updated", DefaultBundlePoolConfig.MaxBundleTxs)
+		conf.MaxBundleTxs = DefaultBundlePoolConfig.MaxBundleTxs
+	}
+	return conf
+}
+
+// BundlePool holds the bundles waiting to be included into the block they
+// target. Bundles are only checked for well-formedness when added, they are
+// executed by the miner when building the target block.
+type BundlePool struct {
+	config BundlePoolConfig
+	signer types.Signer
+	chain  blockChain
+
+	bundles []*Bundle // Bundles in arrival order
+	known   map[common.Hash]struct{}
+	head    common.Hash // Head the mined transactions were last checked against
+	mu      sync.RWMutex
+}
+
+// NewBundlePool creates a new bundle pool on top of the given chain.
+func NewBundlePool(config BundlePoolConfig, chainconfig *params.ChainConfig, chain blockChain) *BundlePool {
+	return &BundlePool{
+		config: (&config).sanitize(),
+		signer: types.LatestSigner(chainconfig),
+		chain:  chain,
+		known:  make(map[common.Hash]struct{}),
+	}
+}
+
+// Add validates a bundle and queues it for inclusion into its target block.
+func (p *BundlePool) Add(bundle *Bundle) error {
+	if len(bundle.Txs) == 0 {
+		return ErrBundleEmpty
+	}
+	if len(bundle.Txs) > p.config.MaxBundleTxs {
+		return fmt.Errorf("%w: %d transactions, max %d", ErrBundleTooLarge, len(bundle.Txs), p.config.MaxBundleTxs)
+	}
+	if bundle.BlockNumber == nil {
+		return errors.New("missing bundle target block")
+	}
+	for i, tx := range bundle.Txs {
+		if _, err := types.Sender(p.signer, tx); err != nil {
+			return fmt.Errorf("transaction %d: %w", i, ErrInvalidSender)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	head := p.chain.CurrentBlock().Header()
+	if bundle.stale(head) {
+		return fmt.Errorf("%w: targets block %v, head is %v", ErrBundleStale, bundle.BlockNumber, head.Number)
+	}
+	hash := bundle.Hash()
+	if _, ok := p.known[hash]; ok {
+		return ErrAlreadyKnown
+	}
+	p.prune(head)
+	if len(p.bundles) >= p.config.MaxBundles {
+		return ErrBundlePoolFull
+	}
+	p.bundles = append(p.bundles, bundle)
+	p.known[hash] = struct{}{}
+
+	bundleAddMeter.Mark(1)
+	bundleGauge.Update(int64(len(p.bundles)))
+	log.Trace("Added bundle to pool", "hash", hash, "block", bundle.BlockNumber, "txs", len(bundle.Txs))
+	return nil
+}
+
+// Bundles returns the bundles which can be included into a block with the given
+// number and timestamp, in arrival order.
+func (p *BundlePool) Bundles(number *big.Int, time uint64) []*Bundle {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prune(p.chain.CurrentBlock().Header())
+
+	var bundles []*Bundle
+	for _, bundle := range p.bundles {
+		if bundle.includable(number, time) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// Len returns the number of bundles in the pool.
+func (p *BundlePool) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.bundles)
+}
+
+// prune drops all the bundles which can't be included on top of the given head
+// any more, either as their target passed or as some of their transactions were
+// already mined. The caller must hold the lock.
+func (p *BundlePool) prune(head *types.Header) {
+	// Mined transactions only need checking once per head
+	var nonces func(common.Address) uint64
+	if hash := head.Hash(); hash != p.head {
+		statedb, err := p.chain.StateAt(head.Root)
+		if err != nil {
+			log.Debug("Failed to check bundles against the head state", "number", head.Number, "hash", hash, "err", err)
+		} else {
+			p.head, nonces = hash, statedb.GetNonce
+		}
+	}
+	var stale, mined int
+	kept := p.bundles[:0]
+	for _, bundle := range p.bundles {
+		switch {
+		case bundle.stale(head):
+			stale++
+		case nonces != nil && p.mined(bundle, nonces):
+			mined++
+		default:
+			kept = append(kept, bundle)
+			continue
+		}
+		delete(p.known, bundle.Hash())
+	}
+	for i := len(kept); i < len(p.bundles); i++ {
+		p.bundles[i] = nil
+	}
+	bundleStaleMeter.Mark(int64(stale))
+	bundleMinedMeter.Mark(int64(mined))
+
+	p.bundles = kept
+	bundleGauge.Update(int64(len(p.bundles)))
+}
+
+// mined returns whether any transaction of the bundle was already mined, given
+// the account nonces on top of the head.
+func (p *BundlePool) mined(bundle *Bundle, nonces func(common.Address) uint64) bool {
+	for _, tx := range bundle.Txs {
+		from, _ := types.Sender(p.signer, tx) // already validated on Add
+		if tx.Nonce() < nonces(from) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that bundles are validated when added, and only returned for the block
+// they target.
+func TestBundlePool(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewBundlePool(BundlePoolConfig{MaxBundles: 2, MaxBundleTxs: 2}, params.TestChainConfig, blockchain)
+	key, _ := crypto.GenerateKey()
+
+	bundle := func(number int64, min, max uint64, txs ...*types.Transaction) *Bundle {
+		return &Bundle{Txs: txs, BlockNumber: big.NewInt(number), MinTimestamp: min, MaxTimestamp: max}
+	}
+	var (
+		tx0 = transaction(0, 100000, key)
+		tx1 = transaction(1, 100000, key)
+		tx2 = transaction(2, 100000, key)
+	)
+	tests := []struct {
+		bundle *Bundle
+		err    error
+	}{
+		{bundle(1, 0, 0), ErrBundleEmpty},
+		{bundle(1, 0, 0, tx0, tx1, tx2), ErrBundleTooLarge},
+		{bundle(0, 0, 0, tx0), ErrBundleStale},
+		{bundle(1, 0, 0, tx0, tx1), nil},
+		{bundle(1, 0, 0, tx0, tx1), ErrAlreadyKnown},
+		{bundle(2, 10, 20, tx1, tx2), nil},
+		{bundle(3, 0, 0, tx2), ErrBundlePoolFull},
+	}
+	for i, tt := range tests {
+		if err := pool.Add(tt.bundle); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if n := pool.Len(); n != 2 {
+		t.Fatalf("bundle count mismatch: have %d, want %d", n, 2)
+	}
+	// Only the bundles matching the block are returned
+	if bundles := pool.Bundles(big.NewInt(1), 100); len(bundles) != 1 || bundles[0].Hash() != bundle(1, 0, 0, tx0, tx1).Hash() {
+		t.Errorf("block 1 bundles mismatch: have %v", bundles)
+	}
+	for _, time := range []uint64{9, 21} {
+		if bundles := pool.Bundles(big.NewInt(2), time); len(bundles) != 0 {
+			t.Errorf("block 2 at time %d: bundles returned out of the timestamp range", time)
+		}
+	}
+	if bundles := pool.Bundles(big.NewInt(2), 15); len(bundles) != 1 {
+		t.Errorf("block 2 at time 15: bundle count mismatch: have %d, want %d", len(bundles), 1)
+	}
+	// Bundles with mined transactions are dropped once the head moves
+	statedb.SetNonce(crypto.PubkeyToAddress(key.PublicKey), 1)
+	blockchain.gasLimit++
+
+	if bundles := pool.Bundles(big.NewInt(1), 100); len(bundles) != 0 {
+		t.Errorf("block 1: mined bundle returned")
+	}
+	if n := pool.Len(); n != 1 {
+		t.Fatalf("bundle count mismatch after mining: have %d, want %d", n, 1)
+	}
+	if bundles := pool.Bundles(big.NewInt(2), 15); len(bundles) != 1 {
+		t.Errorf("block 2 at time 15: bundle count mismatch: have %d, want %d", len(bundles), 1)
+	}
+}
@@ -24,6 +24,26 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxDropReason is the reason a transaction was removed from the transaction pool
+// without being included into a block.
+type TxDropReason string
+
+const (
+	TxDropReplaced    TxDropReason = "replaced"    // Replaced by another one with the same nonce
+	TxDropUnderpriced TxDropReason = "underpriced" // Evicted by better priced ones, or below the price limit
+	TxDropEvicted     TxDropReason = "evicted"     // Evicted as its account or the pool exceeded its slots
+	TxDropExpired     TxDropReason = "expired"     // Lifetime or inclusion deadline passed
+	TxDropInvalidated TxDropReason = "invalidated" // Nonce used by another transaction, or out of funds
+)
+
+// TxDropEvent is posted when a transaction is removed from the transaction pool
+// without being included into a block.
+type TxDropEvent struct {
+	Tx          *types.Transaction
+	Reason      TxDropReason
+	Replacement *types.Transaction // Transaction replacing it, if any
+}
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
@@ -470,6 +470,50 @@ func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
 	}
 }
 
+// DeveloperBorValidator is the only validator compiled into the genesis contracts
+// of bor developer networks, so it is the account sealing all their blocks.
+var DeveloperBorValidator = common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")
+
+// DeveloperBorGenesisBlock returns the 'geth --dev --dev.bor' genesis block, which
+// runs the bor engine with DeveloperBorValidator as its single validator.
+func DeveloperBorGenesisBlock(period uint64) *Genesis {
+	config := &params.ChainConfig{
+		ChainID:             big.NewInt(1337),
+		HomesteadBlock:      big.NewInt(0),
+		EIP150Block:         big.NewInt(0),
+		EIP155Block:         big.NewInt(0),
+		EIP158Block:         big.NewInt(0),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+		IstanbulBlock:       big.NewInt(0),
+		MuirGlacierBlock:    big.NewInt(0),
+		BerlinBlock:         big.NewInt(0),
+		Bor: &params.BorConfig{
+			Period:                period,
+			ProducerDelay:         period,
+			Sprint:                4, // Must match the sprint compiled into the genesis contracts
+			BackupMultiplier:      1,
+			ValidatorContract:     "0x0000000000000000000000000000000000001000",
+			StateReceiverContract: "0x0000000000000000000000000000000000001001",
+		},
+	}
+	// Assemble the genesis contracts along with the precompiles and the validator pre-funded
+	alloc := readPrealloc("allocs/bor-dev.json")
+	for i := byte(1); i <= 9; i++ {
+		alloc[common.BytesToAddress([]byte{i})] = GenesisAccount{Balance: big.NewInt(1)}
+	}
+	alloc[DeveloperBorValidator] = GenesisAccount{Balance: new(big.Int).Lsh(big.NewInt(1), 255)}
+
+	return &Genesis{
+		Config:     config,
+		ExtraData:  make([]byte, 32+crypto.SignatureLength),
+		GasLimit:   11500000,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+}
+
 func decodePrealloc(data string) GenesisAlloc {
 	var p []struct{ Addr, Balance *big.Int }
 	if err := rlp.NewStream(strings.NewReader(data), 0).Decode(&p); err != nil {
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -175,3 +176,50 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// WriteTraceIndexEntry stores that the given address appears in the call traces
+// of the block with the given number.
+func WriteTraceIndexEntry(db ethdb.KeyValueWriter, addr common.Address, number uint64) {
+	if err := db.Put(traceIndexKey(addr, number), []byte{}); err != nil {
+		log.Crit("Failed to store trace index entry", "err", err)
+	}
+}
+
+// ReadTraceIndexBlocks retrieves the numbers of the blocks in the given inclusive
+// range whose call traces the given address appears in, in ascending order.
+func ReadTraceIndexBlocks(db ethdb.Iteratee, addr common.Address, from uint64, to uint64) []uint64 {
+	prefix := append(append([]byte{}, traceIndexPrefix...), addr.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var numbers []uint64
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+		if number > to {
+			break
+		}
+		numbers = append(numbers, number)
+	}
+	if it.Error() != nil {
+		log.Error("Failed to iterate trace index", "err", it.Error())
+	}
+	return numbers
+}
+
+// ReadFeeIndexEntry retrieves the fee index entry of the canonical block with the
+// given number.
+func ReadFeeIndexEntry(db ethdb.KeyValueReader, number uint64) []byte {
+	data, _ := db.Get(feeIndexKey(number))
+	return data
+}
+
+// WriteFeeIndexEntry stores the fee index entry of the canonical block with the
+// given number.
+func WriteFeeIndexEntry(db ethdb.KeyValueWriter, number uint64, entry []byte) {
+	if err := db.Put(feeIndexKey(number), entry); err != nil {
+		log.Crit("Failed to store fee index entry", "err", err)
+	}
+}
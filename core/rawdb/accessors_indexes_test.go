@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"hash"
 	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -177,3 +178,43 @@ func TestDeleteBloomBits(t *testing.T) {
 	check(1, 1, params.MainnetGenesisHash, true)
 	check(1, 1, params.RinkebyGenesisHash, true)
 }
+
+func TestTraceIndex(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	a, b := common.Address{0x01}, common.Address{0x02}
+	for _, number := range []uint64{1, 5, 256, 1 << 40} {
+		WriteTraceIndexEntry(db, a, number)
+	}
+	WriteTraceIndexEntry(db, b, 5)
+
+	check := func(addr common.Address, from, to uint64, want []uint64) {
+		t.Helper()
+		if have := ReadTraceIndexBlocks(db, addr, from, to); !reflect.DeepEqual(have, want) {
+			t.Errorf("blocks of %x in [%d, %d] mismatch: have %v, want %v", addr, from, to, have, want)
+		}
+	}
+	check(a, 0, 1<<50, []uint64{1, 5, 256, 1 << 40})
+	check(a, 2, 256, []uint64{5, 256})
+	check(a, 6, 255, nil)
+	check(b, 0, 10, []uint64{5})
+	check(common.Address{0x03}, 0, 10, nil)
+}
+
+func TestFeeIndex(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if entry := ReadFeeIndexEntry(db, 1); entry != nil {
+		t.Fatalf("non existent entry returned: %x", entry)
+	}
+	WriteFeeIndexEntry(db, 1, []byte{0x01})
+	WriteFeeIndexEntry(db, 1<<40, []byte{0x02})
+	WriteFeeIndexEntry(db, 1, []byte{0x03})
+
+	if entry := ReadFeeIndexEntry(db, 1); !bytes.Equal(entry, []byte{0x03}) {
+		t.Errorf("entry of block 1 mismatch: have %x, want 03", entry)
+	}
+	if entry := ReadFeeIndexEntry(db, 1<<40); !bytes.Equal(entry, []byte{0x02}) {
+		t.Errorf("entry of block 1<<40 mismatch: have %x, want 02", entry)
+	}
+}
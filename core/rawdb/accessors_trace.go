@@ -0,0 +1,82 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReadTraceResults retrieves the cached trace results of a block, produced by
+// the tracer configuration with the given hash.
+func ReadTraceResults(db ethdb.KeyValueReader, number uint64, hash common.Hash, config common.Hash) []byte {
+	data, _ := db.Get(traceResultsKey(number, hash, config))
+	return data
+}
+
+// WriteTraceResults stores the trace results of a block, produced by the tracer
+// configuration with the given hash.
+func WriteTraceResults(db ethdb.KeyValueWriter, number uint64, hash common.Hash, config common.Hash, results []byte) {
+	if err := db.Put(traceResultsKey(number, hash, config), results); err != nil {
+		log.Crit("Failed to store trace results", "err", err)
+	}
+}
+
+// DeleteTraceResults removes the cached trace results of a block, produced by
+// any tracer configuration.
+func DeleteTraceResults(db ethdb.Database, number uint64, hash common.Hash) {
+	prefix := append(append(append([]byte{}, traceResultsPrefix...), encodeBlockNumber(number)...), hash.Bytes()...)
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+common.HashLength {
+			continue
+		}
+		if err := db.Delete(it.Key()); err != nil {
+			log.Crit("Failed to delete trace results", "err", err)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to delete trace results", "err", it.Error())
+	}
+}
+
+// DeleteTraceResultsBelow removes the cached trace results of all the blocks
+// below the given number.
+func DeleteTraceResultsBelow(db ethdb.Database, number uint64) {
+	it := db.NewIterator(traceResultsPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(traceResultsPrefix)+8+2*common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(it.Key()[len(traceResultsPrefix):]) >= number {
+			break
+		}
+		if err := db.Delete(it.Key()); err != nil {
+			log.Crit("Failed to delete trace results", "err", err)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to delete trace results", "err", it.Error())
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that trace results can be stored, retrieved and evicted by block.
+func TestTraceResultsStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		hashA, hashB     = common.HexToHash("0x0a"), common.HexToHash("0x0b")
+		configA, configB = common.HexToHash("0x01"), common.HexToHash("0x02")
+	)
+	WriteTraceResults(db, 1, hashA, configA, []byte("1a1"))
+	WriteTraceResults(db, 1, hashA, configB, []byte("1a2"))
+	WriteTraceResults(db, 1, hashB, configA, []byte("1b1"))
+	WriteTraceResults(db, 2, hashA, configA, []byte("2a1"))
+	WriteTraceResults(db, 3, hashA, configA, []byte("3a1"))
+
+	if have := ReadTraceResults(db, 1, hashA, configB); !bytes.Equal(have, []byte("1a2")) {
+		t.Fatalf("trace results mismatch: have %s, want 1a2", have)
+	}
+	// Evicting a block must leave its siblings alone
+	DeleteTraceResults(db, 1, hashA)
+	if have := ReadTraceResults(db, 1, hashA, configA); have != nil {
+		t.Errorf("evicted trace results returned: %s", have)
+	}
+	if have := ReadTraceResults(db, 1, hashA, configB); have != nil {
+		t.Errorf("evicted trace results returned: %s", have)
+	}
+	if have := ReadTraceResults(db, 1, hashB, configA); !bytes.Equal(have, []byte("1b1")) {
+		t.Errorf("sibling trace results mismatch: have %s, want 1b1", have)
+	}
+	// Pruning old blocks must leave the newer ones alone
+	DeleteTraceResultsBelow(db, 3)
+	if have := ReadTraceResults(db, 1, hashB, configA); have != nil {
+		t.Errorf("pruned trace results returned: %s", have)
+	}
+	if have := ReadTraceResults(db, 2, hashA, configA); have != nil {
+		t.Errorf("pruned trace results returned: %s", have)
+	}
+	if have := ReadTraceResults(db, 3, hashA, configA); !bytes.Equal(have, []byte("3a1")) {
+		t.Errorf("retained trace results mismatch: have %s, want 3a1", have)
+	}
+}
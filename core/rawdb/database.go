@@ -307,6 +307,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		traceIndex      stat
+		traceResults    stat
+		feeIndex        stat
 		cliqueSnaps     stat
 
 		// Ancient store statistics
@@ -365,6 +368,16 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, traceIndexPrefix) && len(key) == (len(traceIndexPrefix)+common.AddressLength+8):
+			traceIndex.Add(size)
+		case bytes.HasPrefix(key, TraceIndexPrefix):
+			traceIndex.Add(size)
+		case bytes.HasPrefix(key, traceResultsPrefix) && len(key) == (len(traceResultsPrefix)+8+2*common.HashLength):
+			traceResults.Add(size)
+		case bytes.HasPrefix(key, feeIndexPrefix) && len(key) == (len(feeIndexPrefix)+8):
+			feeIndex.Add(size)
+		case bytes.HasPrefix(key, FeeIndexPrefix):
+			feeIndex.Add(size)
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
 		case bytes.HasPrefix(key, []byte("cht-")) ||
@@ -422,6 +435,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Trace index", traceIndex.Size(), traceIndex.Count()},
+		{"Key-Value store", "Trace results", traceResults.Size(), traceResults.Count()},
+		{"Key-Value store", "Fee index", feeIndex.Size(), feeIndex.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
@@ -89,12 +89,17 @@ var (
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
+	traceIndexPrefix      = []byte("T") // traceIndexPrefix + address + num (uint64 big endian) -> nothing
+	traceResultsPrefix    = []byte("R") // traceResultsPrefix + num (uint64 big endian) + hash + config hash -> trace results
+	feeIndexPrefix        = []byte("F") // feeIndexPrefix + num (uint64 big endian) -> fee index entry
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	TraceIndexPrefix     = []byte("iT") // TraceIndexPrefix is the data table of the trace indexer to track its progress
+	FeeIndexPrefix       = []byte("iF") // FeeIndexPrefix is the data table of the fee indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -208,6 +213,21 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// traceIndexKey = traceIndexPrefix + address + num (uint64 big endian)
+func traceIndexKey(addr common.Address, number uint64) []byte {
+	return append(append(traceIndexPrefix, addr.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// feeIndexKey = feeIndexPrefix + num (uint64 big endian)
+func feeIndexKey(number uint64) []byte {
+	return append(feeIndexPrefix, encodeBlockNumber(number)...)
+}
+
+// traceResultsKey = traceResultsPrefix + num (uint64 big endian) + hash + config hash
+func traceResultsKey(number uint64, hash common.Hash, config common.Hash) []byte {
+	return append(append(append(traceResultsPrefix, encodeBlockNumber(number)...), hash.Bytes()...), config.Bytes()...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
@@ -38,6 +38,7 @@ type journalEntry interface {
 type journal struct {
 	entries []journalEntry         // Current changes tracked by the journal
 	dirties map[common.Address]int // Dirty accounts and the number of changes
+	txStart int                    // Index of the first change of the current transaction
 }
 
 // newJournal create a new initialized journal.
@@ -70,6 +71,22 @@ func (j *journal) revert(statedb *StateDB, snapshot int) {
 		}
 	}
 	j.entries = j.entries[:snapshot]
+	if j.txStart > snapshot {
+		j.txStart = snapshot
+	}
+}
+
+// endTx marks the end of a transaction whose changes are kept in the journal.
+// The access list changes of the transaction are dropped, as the access list is
+// replaced by the one of the next transaction and can't be reverted any more.
+func (j *journal) endTx() {
+	for i := j.txStart; i < len(j.entries); i++ {
+		switch j.entries[i].(type) {
+		case accessListAddAccountChange, accessListAddSlotChange:
+			j.entries[i] = droppedChange{}
+		}
+	}
+	j.txStart = len(j.entries)
 }
 
 // dirty explicitly sets an address to dirty, even if the change entries would
@@ -130,6 +147,23 @@ type (
 	touchChange struct {
 		account *common.Address
 	}
+	// Changes made by finalising a transaction while a snapshot spanning several
+	// transactions is open.
+	finaliseChange struct {
+		account      *common.Address
+		addrHash     common.Hash
+		prevdeleted  bool                         // Whether the account was already deleted
+		pending      bool                         // Whether the account was already pending
+		dirty        bool                         // Whether the account was already dirty
+		destructed   bool                         // Whether finalising destructed the account
+		prevdestruct bool                         // Whether the account was already destructed in the snapshot
+		snapAccount  []byte                       // Snapshot account data dropped by the destruction
+		snapStorage  map[common.Hash][]byte       // Snapshot storage data dropped by the destruction
+		prevpending  map[common.Hash]*common.Hash // Pending slots overwritten by finalising, nil if unset
+	}
+	// A change which can't be reverted any more, and doesn't need to be.
+	droppedChange struct{}
+
 	// Changes to the access list
 	accessListAddAccountChange struct {
 		address *common.Address
@@ -243,6 +277,47 @@ func (ch addPreimageChange) dirtied() *common.Address {
 	return nil
 }
 
+func (ch finaliseChange) revert(s *StateDB) {
+	if obj := s.stateObjects[*ch.account]; obj != nil {
+		obj.deleted = ch.prevdeleted
+		for key, prev := range ch.prevpending {
+			if prev == nil {
+				delete(obj.pendingStorage, key)
+			} else {
+				obj.pendingStorage[key] = *prev
+			}
+		}
+	}
+	if !ch.pending {
+		delete(s.stateObjectsPending, *ch.account)
+	}
+	if !ch.dirty {
+		delete(s.stateObjectsDirty, *ch.account)
+	}
+	if ch.destructed && s.snap != nil {
+		if !ch.prevdestruct {
+			delete(s.snapDestructs, ch.addrHash)
+		}
+		if ch.snapAccount != nil {
+			s.snapAccounts[ch.addrHash] = ch.snapAccount
+		}
+		if ch.snapStorage != nil {
+			s.snapStorage[ch.addrHash] = ch.snapStorage
+		}
+	}
+}
+
+func (ch finaliseChange) dirtied() *common.Address {
+	return nil
+}
+
+func (ch droppedChange) revert(s *StateDB) {
+}
+
+func (ch droppedChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch accessListAddAccountChange) revert(s *StateDB) {
 	/*
 		One important invariant here, is that whenever a (addr, slot) is added, if the
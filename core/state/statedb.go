@@ -38,6 +38,7 @@ import (
 type revision struct {
 	id           int
 	journalIndex int
+	multiTx      bool // Whether the revision survives the end of transactions
 }
 
 var (
@@ -101,9 +102,10 @@ type StateDB struct {
 
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
-	journal        *journal
-	validRevisions []revision
-	nextRevisionId int
+	journal          *journal
+	validRevisions   []revision
+	nextRevisionId   int
+	multiTxSnapshots int // Number of open snapshots spanning several transactions
 
 	// Measurements gathered during execution for debugging purposes
 	AccountReads         time.Duration
@@ -362,6 +364,29 @@ func (s *StateDB) StorageTrie(addr common.Address) Trie {
 	return cpy.getTrie(s.db)
 }
 
+// ValidateKnownAccounts checks that the storage of the given accounts matches
+// the expected storage roots or slot values.
+func (s *StateDB) ValidateKnownAccounts(accounts types.KnownAccounts) error {
+	for addr, account := range accounts {
+		if account.StorageRoot != nil {
+			root := emptyRoot
+			if trie := s.StorageTrie(addr); trie != nil {
+				root = trie.Hash()
+			}
+			if root != *account.StorageRoot {
+				return fmt.Errorf("%w: account %x storage root %x, expected %x", types.ErrConditionalStorage, addr, root, *account.StorageRoot)
+			}
+			continue
+		}
+		for slot, want := range account.StorageSlots {
+			if have := s.GetState(addr, slot); have != want {
+				return fmt.Errorf("%w: account %x slot %x is %x, expected %x", types.ErrConditionalStorage, addr, slot, have, want)
+			}
+		}
+	}
+	return nil
+}
+
 func (s *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
@@ -751,10 +776,40 @@ func (s *StateDB) Copy() *StateDB {
 func (s *StateDB) Snapshot() int {
 	id := s.nextRevisionId
 	s.nextRevisionId++
-	s.validRevisions = append(s.validRevisions, revision{id, s.journal.length()})
+	s.validRevisions = append(s.validRevisions, revision{id, s.journal.length(), false})
+	return id
+}
+
+// MultiTxSnapshot returns an identifier for the current revision of the state,
+// which unlike the ones returned by Snapshot remains valid across transactions,
+// so that the state changes of several of them can be reverted at once. It must
+// be taken in between transactions, and is released by reverting to it or by
+// discarding it. Until then the journal isn't cleared when finalising a
+// transaction.
+//
+// Revisions spanning several transactions don't survive IntermediateRoot, the
+// state must only be finalised in between the transactions.
+func (s *StateDB) MultiTxSnapshot() int {
+	id := s.nextRevisionId
+	s.nextRevisionId++
+	s.validRevisions = append(s.validRevisions, revision{id, s.journal.length(), true})
+	s.multiTxSnapshots++
 	return id
 }
 
+// DiscardMultiTxSnapshot releases a revision returned by MultiTxSnapshot, keeping
+// the state changes made since.
+func (s *StateDB) DiscardMultiTxSnapshot(revid int) {
+	for i, rev := range s.validRevisions {
+		if rev.id == revid && rev.multiTx {
+			s.validRevisions = append(s.validRevisions[:i], s.validRevisions[i+1:]...)
+			s.multiTxSnapshots--
+			return
+		}
+	}
+	panic(fmt.Errorf("revision id %v cannot be discarded", revid))
+}
+
 // RevertToSnapshot reverts all state changes made since the given revision.
 func (s *StateDB) RevertToSnapshot(revid int) {
 	// Find the snapshot in the stack of valid snapshots.
@@ -768,6 +823,11 @@ func (s *StateDB) RevertToSnapshot(revid int) {
 
 	// Replay the journal to undo changes and remove invalidated snapshots
 	s.journal.revert(s, snapshot)
+	for _, rev := range s.validRevisions[idx:] {
+		if rev.multiTx {
+			s.multiTxSnapshots--
+		}
+	}
 	s.validRevisions = s.validRevisions[:idx]
 }
 
@@ -792,6 +852,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 			// Thus, we can safely ignore it here
 			continue
 		}
+		if s.multiTxSnapshots > 0 {
+			s.journalFinalise(obj, obj.suicided || (deleteEmptyObjects && obj.empty()))
+		}
 		if obj.suicided || (deleteEmptyObjects && obj.empty()) {
 			obj.deleted = true
 
@@ -818,8 +881,58 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 	if s.prefetcher != nil && len(addressesToPrefetch) > 0 {
 		s.prefetcher.prefetch(s.originalRoot, addressesToPrefetch)
 	}
-	// Invalidate journal because reverting across transactions is not allowed.
-	s.clearJournalAndRefund()
+	// Invalidate journal because reverting across transactions is not allowed,
+	// unless a snapshot spanning several transactions is open
+	if s.multiTxSnapshots > 0 {
+		s.endTx()
+	} else {
+		s.clearJournalAndRefund()
+	}
+}
+
+// journalFinalise records the changes finalising an account makes, so that they
+// can be reverted along with the transaction by a snapshot spanning it.
+func (s *StateDB) journalFinalise(obj *stateObject, destruct bool) {
+	change := finaliseChange{
+		account:     &obj.address,
+		addrHash:    obj.addrHash,
+		prevdeleted: obj.deleted,
+		destructed:  destruct,
+	}
+	_, change.pending = s.stateObjectsPending[obj.address]
+	_, change.dirty = s.stateObjectsDirty[obj.address]
+	if destruct && s.snap != nil {
+		_, change.prevdestruct = s.snapDestructs[obj.addrHash]
+		change.snapAccount = s.snapAccounts[obj.addrHash]
+		change.snapStorage = s.snapStorage[obj.addrHash]
+	}
+	if !destruct && len(obj.dirtyStorage) > 0 {
+		change.prevpending = make(map[common.Hash]*common.Hash, len(obj.dirtyStorage))
+		for key := range obj.dirtyStorage {
+			if prev, ok := obj.pendingStorage[key]; ok {
+				change.prevpending[key] = &prev
+			} else {
+				change.prevpending[key] = nil
+			}
+		}
+	}
+	s.journal.append(change)
+}
+
+// endTx ends a transaction without clearing the journal, only resetting its
+// refund and dropping the snapshots it took. The ones spanning transactions
+// stay valid.
+func (s *StateDB) endTx() {
+	s.journal.endTx()
+	s.refund = 0
+
+	revisions := s.validRevisions[:0]
+	for _, rev := range s.validRevisions {
+		if rev.multiTx {
+			revisions = append(revisions, rev)
+		}
+	}
+	s.validRevisions = revisions
 }
 
 // IntermediateRoot computes the current root hash of the state trie.
@@ -31,6 +31,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
@@ -695,6 +696,87 @@ func TestDeleteCreateRevert(t *testing.T) {
 	}
 }
 
+// Tests that reverting to a snapshot spanning several transactions undoes all
+// of them, including the changes made when finalising each one, and that
+// discarding it keeps them.
+func TestMultiTxSnapshot(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		sdb   = NewDatabase(db)
+		alice = common.BytesToAddress([]byte("alice"))
+		bob   = common.BytesToAddress([]byte("bob"))
+		carol = common.BytesToAddress([]byte("carol"))
+		key   = common.HexToHash("0x01")
+	)
+	// Create an initial state with a contract about to self-destruct
+	state, _ := New(common.Hash{}, sdb, nil)
+	state.SetBalance(alice, big.NewInt(100))
+	state.SetState(alice, key, common.HexToHash("0x01"))
+	state.SetBalance(bob, big.NewInt(1))
+	state.SetCode(bob, []byte{0x01})
+
+	root, _ := state.Commit(false)
+	sdb.TrieDB().Commit(root, false, nil)
+	snaps, _ := snapshot.New(db, sdb.TrieDB(), 16, root, false, true, false)
+
+	apply := func(state *StateDB) {
+		state.Prepare(common.HexToHash("0x0a"), 0)
+		state.SetState(alice, key, common.HexToHash("0x02"))
+		state.AddBalance(carol, big.NewInt(1))
+		state.AddSlotToAccessList(alice, key)
+		state.AddLog(&types.Log{Address: alice})
+		state.AddRefund(10)
+		state.Finalise(true)
+
+		state.Prepare(common.HexToHash("0x0b"), 1)
+		state.Suicide(bob)
+		state.SetState(alice, key, common.HexToHash("0x03"))
+		id := state.Snapshot()
+		state.SubBalance(alice, big.NewInt(50))
+		state.RevertToSnapshot(id)
+		state.Finalise(true)
+	}
+	reference, _ := New(root, sdb, snaps)
+	apply(reference)
+	want := reference.IntermediateRoot(true)
+
+	// Discarding the snapshot keeps the changes of all the transactions
+	state, _ = New(root, sdb, snaps)
+	id := state.MultiTxSnapshot()
+	apply(state)
+	state.DiscardMultiTxSnapshot(id)
+	if have := state.IntermediateRoot(true); have != want {
+		t.Errorf("discarded snapshot root mismatch: have %x, want %x", have, want)
+	}
+	// Reverting to it restores the initial state, committed values included
+	state, _ = New(root, sdb, snaps)
+	id = state.MultiTxSnapshot()
+	apply(state)
+	state.RevertToSnapshot(id)
+
+	if have := state.GetCommittedState(alice, key); have != common.HexToHash("0x01") {
+		t.Errorf("committed storage mismatch: have %x, want %x", have, common.HexToHash("0x01"))
+	}
+	if !state.Exist(bob) || len(state.GetCode(bob)) == 0 {
+		t.Errorf("self-destructed contract not restored")
+	}
+	if state.Exist(carol) {
+		t.Errorf("created account not removed")
+	}
+	if logs := state.GetLogs(common.HexToHash("0x0a"), common.Hash{}); len(logs) != 0 {
+		t.Errorf("logs not removed: %v", logs)
+	}
+	if refund := state.GetRefund(); refund != 0 {
+		t.Errorf("refund mismatch: have %d, want 0", refund)
+	}
+	if len(state.snapDestructs) != 0 {
+		t.Errorf("snapshot destructs not restored: %v", state.snapDestructs)
+	}
+	if have, err := state.Commit(true); err != nil || have != root {
+		t.Errorf("reverted root mismatch: have %x, want %x (err %v)", have, root, err)
+	}
+}
+
 // TestMissingTrieNodes tests that if the StateDB fails to load parts of the trie,
 // the Commit operation fails with an error
 // If we are missing trie nodes, we should not continue writing to the trie
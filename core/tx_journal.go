@@ -111,7 +111,7 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 			batch = batch[:0]
 		}
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
+	log.Info("Loaded transaction journal", "path", journal.path, "transactions", total, "dropped", dropped)
 
 	return failure
 }
@@ -145,12 +145,16 @@ func (journal *txJournal) rotate(all map[common.Address]types.Transactions) erro
 	journaled := 0
 	for _, txs := range all {
 		for _, tx := range txs {
+			// Preconditions aren't persisted, don't reload the transaction without them
+			if tx.Conditional() != nil {
+				continue
+			}
 			if err = rlp.Encode(replacement, tx); err != nil {
 				replacement.Close()
 				return err
 			}
+			journaled++
 		}
-		journaled += len(txs)
 	}
 	replacement.Close()
 
@@ -168,6 +172,29 @@ func (journal *txJournal) rotate(all map[common.Address]types.Transactions) erro
 	return nil
 }
 
+// dump replaces the transaction journal with the given transactions. Contrary
+// to rotate, the journal isn't kept open for new transactions to be inserted.
+func (journal *txJournal) dump(txs types.Transactions) error {
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		if err = rlp.Encode(replacement, tx); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
+	if err = replacement.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	log.Info("Regenerated transaction journal", "path", journal.path, "transactions", len(txs))
+	return nil
+}
+
 // close flushes the transaction journal contents to disk and closes the file.
 func (journal *txJournal) close() error {
 	var err error
@@ -583,9 +583,12 @@ func (l *txPricedList) underpricedFor(h *priceHeap, tx *types.Transaction) bool
 // Discard finds a number of most underpriced transactions, removes them from the
 // priced list and returns them for further removal from the entire pool.
 //
-// Note local transaction won't be considered for eviction.
-func (l *txPricedList) Discard(slots int, force bool) (types.Transactions, bool) {
-	drop := make(types.Transactions, 0, slots) // Remote underpriced transactions to drop
+// Note local transaction won't be considered for eviction, nor the skipped ones.
+func (l *txPricedList) Discard(slots int, force bool, skip map[common.Hash]struct{}) (types.Transactions, bool) {
+	var (
+		drop    = make(types.Transactions, 0, slots) // Remote underpriced transactions to drop
+		skipped types.Transactions                   // Skipped transactions to put back
+	)
 	for slots > 0 {
 		if len(l.urgent.list)*floatingRatio > len(l.floating.list)*urgentRatio || floatingRatio == 0 {
 			// Discard stale transactions if found during cleanup
@@ -607,11 +610,18 @@ func (l *txPricedList) Discard(slots int, force bool) (types.Transactions, bool)
 				l.stales--
 				continue
 			}
+			if _, ok := skip[tx.Hash()]; ok {
+				skipped = append(skipped, tx)
+				continue
+			}
 			// Non stale transaction found, discard it
 			drop = append(drop, tx)
 			slots -= numSlots(tx)
 		}
 	}
+	for _, tx := range skipped {
+		heap.Push(&l.urgent, tx)
+	}
 	// If we still can't make enough room for the new transaction
 	if slots > 0 && !force {
 		for _, tx := range drop {
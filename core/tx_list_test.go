@@ -21,6 +21,7 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 )
@@ -51,6 +52,31 @@ func TestStrictTxListAdd(t *testing.T) {
 	}
 }
 
+// Tests that the priced list doesn't discard the skipped transactions, but keeps
+// them for later discards.
+func TestPricedListDiscardSkip(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	all := newTxLookup(nil)
+	priced := newTxPricedList(all)
+
+	txs := make(types.Transactions, 3)
+	for i := range txs {
+		txs[i] = pricedTransaction(uint64(i), 100000, big.NewInt(int64(i+1)), key)
+		all.Add(txs[i], false)
+		priced.Put(txs[i], false)
+	}
+	skip := map[common.Hash]struct{}{txs[0].Hash(): {}}
+	drop, ok := priced.Discard(2, false, skip)
+	if !ok || len(drop) != 2 || drop[0] != txs[1] || drop[1] != txs[2] {
+		t.Fatalf("discarded transactions mismatch: have %v (%v), want %v", drop, ok, txs[1:])
+	}
+	drop, ok = priced.Discard(1, false, nil)
+	if !ok || len(drop) != 1 || drop[0] != txs[0] {
+		t.Fatalf("skipped transaction not kept: have %v (%v), want %v", drop, ok, txs[:1])
+	}
+}
+
 func BenchmarkTxListAdd(t *testing.B) {
 	// Generate a list of transactions to insert
 	key, _ := crypto.GenerateKey()
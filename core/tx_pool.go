@@ -19,6 +19,7 @@ package core
 import (
 	"container/heap"
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -34,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 const (
@@ -98,6 +100,7 @@ var (
 var (
 	evictionInterval    = time.Minute     // Time interval to check for evictable transactions
 	statsReportInterval = 8 * time.Second // Time interval to report transaction pool stats
+	privateInterval     = time.Second     // Time interval to check for expired private transactions
 )
 
 var (
@@ -114,6 +117,28 @@ var (
 	queuedNofundsMeter   = metrics.NewRegisteredMeter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 	queuedEvictionMeter  = metrics.NewRegisteredMeter("txpool/queued/eviction", nil)  // Dropped due to lifetime
 
+	// Metrics for private transactions
+	privateAddMeter     = metrics.NewRegisteredMeter("txpool/private/add", nil)
+	privateEvictMeter   = metrics.NewRegisteredMeter("txpool/private/eviction", nil) // Dropped due to private lifetime
+	privatePublishMeter = metrics.NewRegisteredMeter("txpool/private/publish", nil)  // Made public due to private lifetime
+
+	// Metrics for conditional transactions
+	conditionalRejectMeter = metrics.NewRegisteredMeter("txpool/conditional/reject", nil)
+	conditionalDropMeter   = metrics.NewRegisteredMeter("txpool/conditional/drop", nil) // Dropped as the block number or timestamp range ended
+
+	// Metrics for spam scoring
+	spamEvictMeter = metrics.NewRegisteredMeter("txpool/spam/eviction", nil) // Evicted ahead of cheaper transactions due to the sender's spam score
+	spamGauge      = metrics.NewRegisteredGauge("txpool/spam/senders", nil)  // Senders scoring above the spam threshold
+
+	// Metrics for dropped transactions, by announced reason
+	dropMeters = map[TxDropReason]metrics.Meter{
+		TxDropReplaced:    metrics.NewRegisteredMeter("txpool/dropped/replaced", nil),
+		TxDropUnderpriced: metrics.NewRegisteredMeter("txpool/dropped/underpriced", nil),
+		TxDropEvicted:     metrics.NewRegisteredMeter("txpool/dropped/evicted", nil),
+		TxDropExpired:     metrics.NewRegisteredMeter("txpool/dropped/expired", nil),
+		TxDropInvalidated: metrics.NewRegisteredMeter("txpool/dropped/invalidated", nil),
+	}
+
 	// General tx metrics
 	knownTxMeter       = metrics.NewRegisteredMeter("txpool/known", nil)
 	validTxMeter       = metrics.NewRegisteredMeter("txpool/valid", nil)
@@ -164,6 +189,9 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	RemoteJournal     string // Snapshot of remote transactions to survive node restarts, disabled if empty
+	RemoteJournalSize uint64 // Maximum size in bytes of the remote transaction snapshot
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -173,6 +201,18 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	PrivateLifetime time.Duration // Maximum amount of time private transactions are kept out of the network
+	PrivatePublish  bool          // Whether to broadcast private transactions past their lifetime instead of dropping them
+
+	Quotas []TxQuota // Limits on the pooled transactions by destination contract and method selector
+
+	SpamHalfLife  time.Duration // Half-life of the sender spam scores, zero disables spam scoring
+	SpamThreshold float64       // Spam score above which a sender's transactions are evicted ahead of cheaper ones
+
+	PreExec        string        // Pre-execution of remote transactions: disabled if empty, PreExecFlag or PreExecReject
+	PreExecGas     uint64        // Maximum gas to pre-execute a remote transaction with, the earlier pending ones of its sender included
+	PreExecTimeout time.Duration // Maximum time to pre-execute a remote transaction for, the earlier pending ones of its sender included
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -181,6 +221,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	Journal:   "transactions.rlp",
 	Rejournal: time.Hour,
 
+	RemoteJournalSize: 16 * 1024 * 1024,
+
 	PriceLimit: 1,
 	PriceBump:  10,
 
@@ -190,6 +232,14 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	PrivateLifetime: 10 * time.Minute,
+
+	SpamHalfLife:  10 * time.Minute,
+	SpamThreshold: 32,
+
+	PreExecGas:     10000000,
+	PreExecTimeout: 50 * time.Millisecond,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -200,6 +250,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
 		conf.Rejournal = time.Second
 	}
+	if conf.RemoteJournal != "" && conf.RemoteJournalSize < txMaxSize {
+		log.Warn("Sanitizing invalid txpool remote journal size", "provided", conf.RemoteJournalSize, "updated", DefaultTxPoolConfig.RemoteJournalSize)
+		conf.RemoteJournalSize = DefaultTxPoolConfig.RemoteJournalSize
+	}
 	if conf.PriceLimit < 1 {
 		log.Warn("Sanitizing invalid txpool price limit", "provided", conf.PriceLimit, "updated", DefaultTxPoolConfig.PriceLimit)
 		conf.PriceLimit = DefaultTxPoolConfig.PriceLimit
@@ -228,6 +282,40 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
 	}
+	if conf.PrivateLifetime < 1 {
+		log.Warn("Sanitizing invalid txpool private lifetime", "provided", conf.PrivateLifetime, "updated", DefaultTxPoolConfig.PrivateLifetime)
+		conf.PrivateLifetime = DefaultTxPoolConfig.PrivateLifetime
+	}
+	var quotas []TxQuota
+	for i, quota := range conf.Quotas {
+		if err := quota.validate(); err != nil {
+			log.Warn("Sanitizing invalid txpool quota", "index", i, "err", err)
+			continue
+		}
+		quotas = append(quotas, quota)
+	}
+	conf.Quotas = quotas
+
+	if conf.SpamHalfLife < 0 {
+		log.Warn("Sanitizing invalid txpool spam half-life", "provided", conf.SpamHalfLife, "updated", 0)
+		conf.SpamHalfLife = 0
+	}
+	if conf.SpamHalfLife > 0 && conf.SpamThreshold < 1 {
+		log.Warn("Sanitizing invalid txpool spam threshold", "provided", conf.SpamThreshold, "updated", DefaultTxPoolConfig.SpamThreshold)
+		conf.SpamThreshold = DefaultTxPoolConfig.SpamThreshold
+	}
+	if conf.PreExec != "" && conf.PreExec != PreExecFlag && conf.PreExec != PreExecReject {
+		log.Warn("Sanitizing invalid txpool pre-execution mode", "provided", conf.PreExec, "updated", "disabled")
+		conf.PreExec = ""
+	}
+	if conf.PreExec != "" && conf.PreExecGas < params.TxGas {
+		log.Warn("Sanitizing invalid txpool pre-execution gas", "provided", conf.PreExecGas, "updated", DefaultTxPoolConfig.PreExecGas)
+		conf.PreExecGas = DefaultTxPoolConfig.PreExecGas
+	}
+	if conf.PreExec != "" && conf.PreExecTimeout <= 0 {
+		log.Warn("Sanitizing invalid txpool pre-execution timeout", "provided", conf.PreExecTimeout, "updated", DefaultTxPoolConfig.PreExecTimeout)
+		conf.PreExecTimeout = DefaultTxPoolConfig.PreExecTimeout
+	}
 	return conf
 }
 
@@ -244,6 +332,9 @@ type TxPool struct {
 	chain       blockChain
 	gasPrice    *big.Int
 	txFeed      event.Feed
+	shownFeed   event.Feed // Transactions which can be shown outside of the node
+	publicFeed  event.Feed // Private transactions made public
+	dropFeed    event.Feed // Transactions removed without being included
 	scope       event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
@@ -252,12 +343,18 @@ type TxPool struct {
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
 
+	currentHead   *types.Header  // Current head of the blockchain
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals  *accountSet   // Set of local transaction to exempt from eviction rules
+	journal *txJournal    // Journal of local transaction to back up to disk
+	remotes *txJournal    // Snapshot of remote transactions to back up to disk
+	private *privateTxSet // Set of transactions to keep out of the network
+	spam    *spamScores   // Decaying spam scores of the senders, nil if disabled
+
+	preExecCache *lru.Cache // Pre-execution results of remote transactions by state root
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -274,7 +371,12 @@ type TxPool struct {
 	reorgShutdownCh chan struct{}  // requests shutdown of scheduleReorgLoop
 	wg              sync.WaitGroup // tracks loop, scheduleReorgLoop
 
-	changesSinceReorg int // A counter for how many drops we've performed in-between reorg.
+	changesSinceReorg int           // A counter for how many drops we've performed in-between reorg.
+	drops             []TxDropEvent // Drops to announce after the next reorg
+
+	// included are the transactions of the blocks imported by the running reset,
+	// telling included transactions from invalidated ones. Nil if unknown.
+	included map[common.Hash]struct{}
 }
 
 type txpoolResetRequest struct {
@@ -296,7 +398,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		pending:         make(map[common.Address]*txList),
 		queue:           make(map[common.Address]*txList),
 		beats:           make(map[common.Address]time.Time),
-		all:             newTxLookup(),
+		all:             newTxLookup(newTxQuotas(config.Quotas)),
+		private:         newPrivateTxSet(),
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
@@ -310,6 +413,12 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		log.Info("Setting new local account", "address", addr)
 		pool.locals.add(addr)
 	}
+	if config.SpamHalfLife > 0 {
+		pool.spam = newSpamScores(config.SpamHalfLife)
+	}
+	if config.PreExec != "" {
+		pool.preExecCache, _ = lru.New(preExecCacheSize)
+	}
 	pool.priced = newTxPricedList(pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -328,6 +437,15 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction persistence is enabled, reload them, revalidating
+	// them against the current state and price limit like any new arrival
+	if config.RemoteJournal != "" {
+		pool.remotes = newTxJournal(config.RemoteJournal)
+
+		if err := pool.remotes.load(pool.AddRemotes); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -348,12 +466,14 @@ func (pool *TxPool) loop() {
 		// Start the stats reporting and transaction eviction tickers
 		report  = time.NewTicker(statsReportInterval)
 		evict   = time.NewTicker(evictionInterval)
+		private = time.NewTicker(privateInterval)
 		journal = time.NewTicker(pool.config.Rejournal)
 		// Track the previous head headers for transaction reorgs
 		head = pool.chain.CurrentBlock()
 	)
 	defer report.Stop()
 	defer evict.Stop()
+	defer private.Stop()
 	defer journal.Stop()
 
 	for {
@@ -385,6 +505,9 @@ func (pool *TxPool) loop() {
 		// Handle inactive account transaction eviction
 		case <-evict.C:
 			pool.mu.Lock()
+			if pool.spam != nil {
+				pool.spam.prune(time.Now())
+			}
 			for addr := range pool.queue {
 				// Skip local transactions from the eviction mechanism
 				if pool.locals.contains(addr) {
@@ -395,11 +518,17 @@ func (pool *TxPool) loop() {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
 						pool.removeTx(tx.Hash(), true)
+						pool.queueDropEvent(tx, TxDropExpired, nil)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
+			pool.flushDropEvents()
+
+		// Handle private transaction expiration
+		case <-private.C:
+			pool.expirePrivate(time.Now())
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -410,6 +539,7 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			pool.snapshotRemotes()
 		}
 	}
 }
@@ -426,6 +556,7 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	pool.snapshotRemotes()
 	log.Info("Transaction pool stopped")
 }
 
@@ -435,6 +566,26 @@ func (pool *TxPool) SubscribeNewTxsEvent(ch chan<- NewTxsEvent) event.Subscripti
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeShownTxsEvent registers a subscription of NewTxsEvent, like the one
+// of SubscribeNewTxsEvent but leaving out the private transactions, which are
+// only sent once made public.
+func (pool *TxPool) SubscribeShownTxsEvent(ch chan<- NewTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.shownFeed.Subscribe(ch))
+}
+
+// SubscribeDropTxsEvent registers a subscription of TxDropEvent, posted for the
+// transactions removed from the pool without being included into a block.
+// Private transactions are left out.
+func (pool *TxPool) SubscribeDropTxsEvent(ch chan<- TxDropEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// SubscribePublicTxsEvent registers a subscription of NewTxsEvent for private
+// transactions made public, as they reach the end of their private lifetime.
+func (pool *TxPool) SubscribePublicTxsEvent(ch chan<- NewTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.publicFeed.Subscribe(ch))
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -446,6 +597,9 @@ func (pool *TxPool) GasPrice() *big.Int {
 // SetGasPrice updates the minimum price required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
+	// Announce the transactions dropped below the new price once unlocked
+	defer pool.flushDropEvents()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -457,6 +611,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 		drop := pool.all.RemotesBelowTip(price)
 		for _, tx := range drop {
 			pool.removeTx(tx.Hash(), false)
+			pool.queueUnscoredDropEvent(tx, TxDropUnderpriced, nil)
 		}
 		pool.priced.Removed(len(drop))
 	}
@@ -585,6 +740,69 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves the remote transactions to persist across restarts, best
+// paying first and up to the configured snapshot size. Private and conditional
+// transactions, and the ones following them, are left out.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) remote() types.Transactions {
+	var (
+		pending = make(map[common.Address]types.Transactions)
+		queued  = make(map[common.Address]types.Transactions)
+	)
+	collect := func(lists map[common.Address]*txList, txs map[common.Address]types.Transactions) {
+		for addr, list := range lists {
+			if pool.locals.contains(addr) {
+				continue
+			}
+			flat := list.Flatten()
+			for i, tx := range flat {
+				if pool.IsPrivate(tx.Hash()) {
+					flat = flat[:i]
+					break
+				}
+			}
+			if len(flat) > 0 {
+				txs[addr] = flat
+			}
+		}
+	}
+	collect(pool.pending, pending)
+	collect(pool.queue, queued)
+
+	var (
+		txs  types.Transactions
+		size uint64
+	)
+	for _, set := range []map[common.Address]types.Transactions{pending, queued} {
+		sorted := types.NewTransactionsByPriceAndNonce(pool.signer, set, pool.priced.urgent.baseFee)
+		for tx := sorted.Peek(); tx != nil; tx = sorted.Peek() {
+			if size+uint64(tx.Size()) > pool.config.RemoteJournalSize {
+				sorted.Pop() // Skip the account, later nonces would be gapped
+				continue
+			}
+			txs = append(txs, tx)
+			size += uint64(tx.Size())
+			sorted.Shift()
+		}
+	}
+	return txs
+}
+
+// snapshotRemotes regenerates the remote transaction snapshot, if enabled.
+func (pool *TxPool) snapshotRemotes() {
+	if pool.remotes == nil {
+		return
+	}
+	pool.mu.Lock()
+	txs := pool.remote()
+	pool.mu.Unlock()
+
+	if err := pool.remotes.dump(txs); err != nil {
+		log.Warn("Failed to snapshot remote transactions", "err", err)
+	}
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -609,6 +827,13 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentMaxGas < tx.Gas() {
 		return ErrGasLimit
 	}
+	// Ensure the block ranges of the preconditions can still be met
+	if conditional := tx.Conditional(); conditional != nil {
+		if err := pool.validateConditional(conditional); err != nil {
+			conditionalRejectMeter.Mark(1)
+			return err
+		}
+	}
 	// Sanity check for extremely large numbers
 	if tx.GasFeeCap().BitLen() > 256 {
 		return ErrFeeCapVeryHigh
@@ -693,10 +918,25 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	// already validated by this point
 	from, _ := types.Sender(pool.signer, tx)
 
+	// Enforce the contract and selector quotas, replacements only count towards
+	// the quotas the replaced transaction didn't
+	if !isLocal {
+		if err := pool.all.CheckQuotas(tx, pool.overlapping(from, tx)); err != nil {
+			log.Trace("Discarding transaction exceeding quota", "hash", hash, "err", err)
+			return false, err
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
+		// Make room evicting the transactions of senders spamming the pool first,
+		// falling back to pricing for any remaining room
+		needed := pool.all.Slots() - int(pool.config.GlobalSlots+pool.config.GlobalQueue) + numSlots(tx)
+		spam := pool.spamDiscard(needed, from, pool.isFuture(from, tx))
+		for _, spamTx := range spam {
+			needed -= numSlots(spamTx)
+		}
 		// If the new transaction is underpriced, don't accept it
-		if !isLocal && pool.priced.Underpriced(tx) {
+		if !isLocal && needed > 0 && pool.priced.Underpriced(tx) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
 			return false, ErrUnderpriced
@@ -714,7 +954,18 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		// New transaction is better than our worse ones, make room for it.
 		// If it's a local transaction, forcibly discard all available transactions.
 		// Otherwise if we can't make enough room for new one, abort the operation.
-		drop, success := pool.priced.Discard(pool.all.Slots()-int(pool.config.GlobalSlots+pool.config.GlobalQueue)+numSlots(tx), isLocal)
+		var (
+			drop    types.Transactions
+			success = true
+		)
+		if needed > 0 {
+			// Don't pick the spamming senders' transactions twice
+			picked := make(map[common.Hash]struct{}, len(spam))
+			for _, tx := range spam {
+				picked[tx.Hash()] = struct{}{}
+			}
+			drop, success = pool.priced.Discard(needed, isLocal, picked)
+		}
 
 		// Special case, we still can't make the room for the new remote one.
 		if !isLocal && !success {
@@ -744,12 +995,27 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 				return false, ErrFutureReplacePending
 			}
 		}
-		// Kick out the underpriced remote transactions.
+		// Kick out the underpriced remote transactions. Senders making room for
+		// themselves aren't scored for it.
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
 			dropped := pool.removeTx(tx.Hash(), false)
 			pool.changesSinceReorg += dropped
+			if dropSender, _ := types.Sender(pool.signer, tx); dropSender == from {
+				pool.queueUnscoredDropEvent(tx, TxDropUnderpriced, nil)
+			} else {
+				pool.queueDropEvent(tx, TxDropUnderpriced, nil)
+			}
+		}
+		// Kick out the transactions of the spamming senders, not scoring them
+		// any further for the evictions their score caused.
+		for _, tx := range spam {
+			log.Trace("Discarding spamming sender's transaction", "hash", tx.Hash())
+			spamEvictMeter.Mark(1)
+			dropped := pool.removeTx(tx.Hash(), true)
+			pool.changesSinceReorg += dropped
+			pool.queueUnscoredDropEvent(tx, TxDropEvicted, nil)
 		}
 	}
 	// Try to replace an existing transaction in the pending pool
@@ -765,6 +1031,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.queueDropEvent(old, TxDropReplaced, tx)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
@@ -830,6 +1097,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.queueDropEvent(old, TxDropReplaced, tx)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -850,6 +1118,123 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 	return old != nil, nil
 }
 
+// queueDropEvent records a dropped transaction, to be announced once the pool
+// lock is released by the next reorg. Replacements and evictions count towards
+// the spam score of the sender.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) queueDropEvent(tx *types.Transaction, reason TxDropReason, replacement *types.Transaction) {
+	pool.queueUnscoredDropEvent(tx, reason, replacement)
+
+	if pool.spam != nil && (reason == TxDropReplaced || reason == TxDropUnderpriced || reason == TxDropEvicted) {
+		if from, _ := types.Sender(pool.signer, tx); !pool.locals.contains(from) {
+			pool.spam.bump(from, time.Now())
+		}
+	}
+}
+
+// queueUnscoredDropEvent records a dropped transaction without counting it
+// towards the spam score of the sender, for discards the sender didn't cause by
+// churning the pool.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) queueUnscoredDropEvent(tx *types.Transaction, reason TxDropReason, replacement *types.Transaction) {
+	pool.drops = append(pool.drops, TxDropEvent{Tx: tx, Reason: reason, Replacement: replacement})
+	dropMeters[reason].Mark(1)
+}
+
+// overlapping returns the pooled transaction of the sender with the same nonce
+// as the given one, which it would replace, if any.
+func (pool *TxPool) overlapping(from common.Address, tx *types.Transaction) *types.Transaction {
+	if list := pool.pending[from]; list != nil {
+		if old := list.txs.Get(tx.Nonce()); old != nil {
+			return old
+		}
+	}
+	if list := pool.queue[from]; list != nil {
+		return list.txs.Get(tx.Nonce())
+	}
+	return nil
+}
+
+// spamDiscard picks remote transactions to evict to make room for one from the
+// given sender, among the ones of senders scoring above the spam threshold and
+// higher than it, highest first. Transactions are picked from the end of the
+// queue, and of the pending list unless making room for a future transaction.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) spamDiscard(slots int, from common.Address, future bool) types.Transactions {
+	if pool.spam == nil || slots <= 0 {
+		return nil
+	}
+	var (
+		now      = time.Now()
+		score    = pool.spam.get(from, now)
+		spammers = pool.spam.above(pool.config.SpamThreshold, now)
+		drop     types.Transactions
+	)
+	spamGauge.Update(int64(len(spammers)))
+
+	for _, addr := range spammers {
+		if slots <= 0 || pool.spam.get(addr, now) <= score {
+			break
+		}
+		if addr == from || pool.locals.contains(addr) {
+			continue
+		}
+		var lists []*txList
+		if list := pool.queue[addr]; list != nil {
+			lists = append(lists, list)
+		}
+		if list := pool.pending[addr]; list != nil && !future {
+			lists = append(lists, list)
+		}
+		for _, list := range lists {
+			txs := list.Flatten()
+			for i := len(txs) - 1; i >= 0 && slots > 0; i-- {
+				drop = append(drop, txs[i])
+				slots -= numSlots(txs[i])
+			}
+		}
+	}
+	return drop
+}
+
+// queueStaleDropEvent records a transaction removed for its nonce being used,
+// unless it was included by the blocks imported by the running reset.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) queueStaleDropEvent(tx *types.Transaction) {
+	if pool.included == nil {
+		return // Unknown whether included or not, stay silent
+	}
+	if _, ok := pool.included[tx.Hash()]; !ok {
+		pool.queueDropEvent(tx, TxDropInvalidated, nil)
+	}
+}
+
+// flushDropEvents announces the drops recorded outside of a reorg, which would
+// otherwise wait for the next block or transaction.
+//
+// Note, this method assumes the pool lock is not held!
+func (pool *TxPool) flushDropEvents() {
+	pool.mu.Lock()
+	drops := pool.drops
+	pool.drops = nil
+	pool.mu.Unlock()
+
+	pool.sendDropEvents(drops)
+}
+
+// sendDropEvents announces the given drops, leaving out private transactions.
+func (pool *TxPool) sendDropEvents(drops []TxDropEvent) {
+	for _, drop := range drops {
+		if !pool.isPrivate(drop.Tx) {
+			pool.dropFeed.Send(drop)
+		}
+	}
+}
+
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
@@ -857,6 +1242,10 @@ func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
 	if pool.journal == nil || !pool.locals.contains(from) {
 		return
 	}
+	// Preconditions aren't persisted, don't reload the transaction without them
+	if tx.Conditional() != nil {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -886,6 +1275,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.queueDropEvent(old, TxDropReplaced, tx)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
@@ -914,6 +1304,93 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 	return errs[0]
 }
 
+// AddPrivate enqueues a single transaction into the pool, marking it to be kept
+// out of the network until its private lifetime expires. It is otherwise handled
+// like a remote transaction, in particular it is not journaled, so it can't leak
+// out as a local one after a restart.
+func (pool *TxPool) AddPrivate(tx *types.Transaction) error {
+	// Mark before adding, the pool announces transactions as soon as they're in
+	hash := tx.Hash()
+	pool.private.add(hash, time.Now().Add(pool.config.PrivateLifetime))
+
+	if err := pool.addTxs([]*types.Transaction{tx}, false, true)[0]; err != nil {
+		pool.private.remove(hash)
+		return err
+	}
+	privateAddMeter.Mark(1)
+	return nil
+}
+
+// AddConditional enqueues a single transaction carrying preconditions into the
+// pool. It is handled like a remote transaction, so its sender isn't exempted
+// from pricing constraints and evictions, and is kept out of the network as long
+// as it stays in the pool.
+//
+// Only the block number and timestamp ranges are checked here, the storage the
+// transaction expects is up to the caller to check against the pending state.
+func (pool *TxPool) AddConditional(tx *types.Transaction) error {
+	if tx.Conditional() == nil {
+		return errors.New("transaction has no preconditions")
+	}
+	return pool.addTxs([]*types.Transaction{tx}, false, true)[0]
+}
+
+// IsPrivate returns whether a transaction must be kept out of the network.
+// Transactions submitted with preconditions are always private, as the peers
+// wouldn't know about the preconditions.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	if pool.private.contains(hash) {
+		return true
+	}
+	tx := pool.all.Get(hash)
+	return tx != nil && tx.Conditional() != nil
+}
+
+// isPrivate returns whether a transaction, which may have left the pool, must
+// be kept out of the network.
+func (pool *TxPool) isPrivate(tx *types.Transaction) bool {
+	return tx.Conditional() != nil || pool.private.contains(tx.Hash())
+}
+
+// expirePrivate ends the privacy of the transactions past their private lifetime,
+// either dropping them or announcing them as public.
+func (pool *TxPool) expirePrivate(now time.Time) {
+	expired := pool.private.expire(now)
+	if len(expired) == 0 {
+		return
+	}
+	if pool.config.PrivatePublish {
+		var txs []*types.Transaction
+		for _, hash := range expired {
+			if tx := pool.all.Get(hash); tx != nil {
+				txs = append(txs, tx)
+			}
+		}
+		if len(txs) > 0 {
+			log.Debug("Publishing expired private transactions", "count", len(txs))
+			privatePublishMeter.Mark(int64(len(txs)))
+			pool.publicFeed.Send(NewTxsEvent{txs})
+			pool.shownFeed.Send(NewTxsEvent{txs})
+		}
+		return
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var dropped int
+	for _, hash := range expired {
+		// Never public, the transactions are dropped without notice
+		if pool.all.Get(hash) != nil {
+			pool.removeTx(hash, true)
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		log.Debug("Dropped expired private transactions", "count", dropped)
+		privateEvictMeter.Mark(int64(dropped))
+	}
+}
+
 // AddRemotes enqueues a batch of transactions into the pool if they are valid. If the
 // senders are not among the locally tracked ones, full pricing constraints will apply.
 //
@@ -969,6 +1446,18 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 		// Accumulate all unknown transactions for deeper processing
 		news = append(news, tx)
 	}
+	// Pre-execute the remote transactions if enabled. It's done before obtaining
+	// the lock as it can take a while.
+	if !local && len(news) > 0 && pool.config.PreExec != "" {
+		pool.preExecute(txs, errs)
+
+		news = news[:0]
+		for i, tx := range txs {
+			if errs[i] == nil {
+				news = append(news, tx)
+			}
+		}
+	}
 	if len(news) == 0 {
 		return errs
 	}
@@ -1237,6 +1726,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	// remove any transaction that has been included in the block or was invalidated
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
+		pool.dropExpiredConditionals()
 		pool.demoteUnexecutables()
 		if reset.newHead != nil {
 			if pool.chainconfig.IsLondon(new(big.Int).Add(reset.newHead.Number, big.NewInt(1))) {
@@ -1260,8 +1750,14 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	}
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
+	pool.included = nil
+	drops := pool.drops
+	pool.drops = nil
 	pool.mu.Unlock()
 
+	// Notify subsystems for transactions dropped since the last reorg
+	pool.sendDropEvents(drops)
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1271,11 +1767,20 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		events[addr].Put(tx)
 	}
 	if len(events) > 0 {
-		var txs []*types.Transaction
+		var txs, shown []*types.Transaction
 		for _, set := range events {
 			txs = append(txs, set.Flatten()...)
 		}
 		pool.txFeed.Send(NewTxsEvent{txs})
+
+		for _, tx := range txs {
+			if !pool.isPrivate(tx) {
+				shown = append(shown, tx)
+			}
+		}
+		if len(shown) > 0 {
+			pool.shownFeed.Send(NewTxsEvent{shown})
+		}
 	}
 }
 
@@ -1342,8 +1847,14 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 					}
 				}
 				reinject = types.TxDifference(discarded, included)
+				pool.setIncluded(included)
 			}
 		}
+	} else if oldHead != nil {
+		// Plain chain extension, only the new head's transactions got included
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			pool.setIncluded(block.Transactions())
+		}
 	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
@@ -1354,6 +1865,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 		log.Error("Failed to reset txpool state", "err", err)
 		return
 	}
+	pool.currentHead = newHead
 	pool.currentState = statedb
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
@@ -1370,6 +1882,15 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.eip1559 = pool.chainconfig.IsLondon(next)
 }
 
+// setIncluded records the transactions included by the blocks imported by the
+// running reset.
+func (pool *TxPool) setIncluded(txs types.Transactions) {
+	pool.included = make(map[common.Hash]struct{}, len(txs))
+	for _, tx := range txs {
+		pool.included[tx.Hash()] = struct{}{}
+	}
+}
+
 // promoteExecutables moves transactions that have become processable from the
 // future queue to the set of pending transactions. During this process, all
 // invalidated transactions (low nonce, low balance) are deleted.
@@ -1388,6 +1909,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.queueStaleDropEvent(tx)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1395,6 +1917,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.queueDropEvent(tx, TxDropInvalidated, nil)
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1417,6 +1940,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.queueDropEvent(tx, TxDropEvicted, nil)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1479,6 +2003,7 @@ func (pool *TxPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.queueDropEvent(tx, TxDropEvicted, nil)
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1506,6 +2031,7 @@ func (pool *TxPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.queueDropEvent(tx, TxDropEvicted, nil)
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1553,6 +2079,7 @@ func (pool *TxPool) truncateQueue() {
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
 				pool.removeTx(tx.Hash(), true)
+				pool.queueDropEvent(tx, TxDropEvicted, nil)
 			}
 			drop -= size
 			queuedRateLimitMeter.Mark(int64(size))
@@ -1562,12 +2089,46 @@ func (pool *TxPool) truncateQueue() {
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
 			pool.removeTx(txs[i].Hash(), true)
+			pool.queueDropEvent(txs[i], TxDropEvicted, nil)
 			drop--
 			queuedRateLimitMeter.Mark(1)
 		}
 	}
 }
 
+// validateConditional checks whether the block number and timestamp ranges of
+// the preconditions of a transaction can still be met after the current head.
+func (pool *TxPool) validateConditional(conditional *types.TransactionConditional) error {
+	if err := conditional.Validate(); err != nil {
+		return err
+	}
+	if conditional.Expired(pool.currentHead) {
+		return fmt.Errorf("%w: expired at block %v", types.ErrConditionalBlock, pool.currentHead.Number)
+	}
+	return nil
+}
+
+// dropExpiredConditionals removes all the transactions whose block number or
+// timestamp range ends at the new head, their preconditions never being met
+// again. Transactions whose expected storage doesn't match are kept, the storage
+// possibly changing back, and are skipped by the miner meanwhile.
+func (pool *TxPool) dropExpiredConditionals() {
+	var drops []*types.Transaction
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		if conditional := tx.Conditional(); conditional != nil && conditional.Expired(pool.currentHead) {
+			log.Trace("Removed expired conditional transaction", "hash", hash)
+			drops = append(drops, tx)
+		}
+		return true
+	}, true, true)
+
+	for _, tx := range drops {
+		pool.removeTx(tx.Hash(), true)
+		pool.queueDropEvent(tx, TxDropExpired, nil)
+	}
+	conditionalDropMeter.Mark(int64(len(drops)))
+}
+
 // demoteUnexecutables removes invalid and processed transactions from the pools
 // executable/pending queue and any subsequent transactions that become unexecutable
 // are moved back into the future queue.
@@ -1585,6 +2146,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.queueStaleDropEvent(tx)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1593,6 +2155,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.queueDropEvent(tx, TxDropInvalidated, nil)
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -1731,13 +2294,15 @@ type txLookup struct {
 	lock    sync.RWMutex
 	locals  map[common.Hash]*types.Transaction
 	remotes map[common.Hash]*types.Transaction
+	quotas  *txQuotas // Counters of the contract and selector quotas, nil if none
 }
 
 // newTxLookup returns a new txLookup structure.
-func newTxLookup() *txLookup {
+func newTxLookup(quotas *txQuotas) *txLookup {
 	return &txLookup{
 		locals:  make(map[common.Hash]*types.Transaction),
 		remotes: make(map[common.Hash]*types.Transaction),
+		quotas:  quotas,
 	}
 }
 
@@ -1831,6 +2396,9 @@ func (t *txLookup) Add(tx *types.Transaction, local bool) {
 	t.slots += numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 
+	if t.quotas != nil {
+		t.quotas.add(tx)
+	}
 	if local {
 		t.locals[tx.Hash()] = tx
 	} else {
@@ -1854,10 +2422,25 @@ func (t *txLookup) Remove(hash common.Hash) {
 	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 
+	if t.quotas != nil {
+		t.quotas.remove(tx)
+	}
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
 }
 
+// CheckQuotas returns an error if adding the transaction, replacing the given one
+// if not nil, would exceed any of the contract and selector quotas.
+func (t *txLookup) CheckQuotas(tx *types.Transaction, replaced *types.Transaction) error {
+	if t.quotas == nil {
+		return nil
+	}
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.quotas.check(tx, replaced)
+}
+
 // RemoteToLocals migrates the transactions belongs to the given locals to locals
 // set. The assumption is held the locals set is thread-safe to be used.
 func (t *txLookup) RemoteToLocals(locals *accountSet) int {
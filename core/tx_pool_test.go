@@ -21,16 +21,21 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
@@ -2348,6 +2353,360 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are persisted across restarts if enabled, best
+// paying first, and revalidated when reloaded.
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary directory for the snapshot
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RemoteJournal = filepath.Join(dir, "remotes.rlp")
+	config.RemoteJournalSize = DefaultTxPoolConfig.RemoteJournalSize
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	var (
+		best   = types.Transactions{pricedTransaction(0, 100000, big.NewInt(3), keys[0]), pricedTransaction(1, 100000, big.NewInt(3), keys[0])}
+		cheap  = pricedTransaction(0, 100000, big.NewInt(1), keys[1])
+		future = pricedTransaction(5, 100000, big.NewInt(2), keys[2])
+	)
+	for _, tx := range append(best, cheap, future) {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	if err := pool.AddPrivate(pricedTransaction(0, 100000, big.NewInt(3), keys[3])); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	// Ensure the snapshot is bounded, keeping the best paying transactions
+	pool.mu.Lock()
+	pool.config.RemoteJournalSize = uint64(best[0].Size() + best[1].Size())
+	if txs := pool.remote(); len(txs) != 2 || txs[0].Hash() != best[0].Hash() || txs[1].Hash() != best[1].Hash() {
+		t.Fatalf("bounded snapshot mismatch: have %d transactions", len(txs))
+	}
+	pool.config.RemoteJournalSize = config.RemoteJournalSize
+	pool.mu.Unlock()
+
+	// Restart with a higher price limit, ensure only the valid transactions
+	// survive and the private one stays out
+	pool.Stop()
+
+	config.PriceLimit = 2
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	<-pool.requestPromoteExecutables(newAccountSet(pool.signer))
+	pending, queued := pool.Stats()
+	if pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if pool.Get(cheap.Hash()) != nil {
+		t.Fatalf("underpriced transaction reloaded")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that remote transactions exceeding the contract and selector quotas are
+// rejected, unless replacing pooled ones.
+func TestTransactionQuotas(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	contract := common.HexToAddress("0xc0ffee")
+	config := testTxPoolConfig
+	config.Quotas = []TxQuota{
+		{Name: "coffee", Contract: &contract, Limit: 2},
+		{Selector: hexutil.MustDecode("0xa9059cbb"), Limit: 1},
+	}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	call := func(nonce uint64, to common.Address, data []byte, price int64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(0), 100000, big.NewInt(price), data), types.HomesteadSigner{}, key)
+		return tx
+	}
+	// Fill up the contract quota, ensure further calls are rejected but not replacements
+	if err := pool.addRemoteSync(call(0, contract, nil, 1, keys[0])); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(call(1, contract, nil, 1, keys[0])); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(call(0, contract, nil, 1, keys[1])); !errors.Is(err, ErrTxQuotaExceeded) || !strings.Contains(err.Error(), `rule "coffee"`) {
+		t.Fatalf("contract quota error mismatch: have %v, want %v of rule coffee", err, ErrTxQuotaExceeded)
+	}
+	if err := pool.addRemoteSync(call(1, contract, nil, 2, keys[0])); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	// Fill up the selector quota, ensure further calls to any contract are
+	// rejected unless local
+	transfer := hexutil.MustDecode("0xa9059cbb0000")
+	if err := pool.addRemoteSync(call(0, common.HexToAddress("0x01"), transfer, 1, keys[1])); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(call(0, common.HexToAddress("0x02"), transfer, 1, keys[2])); !errors.Is(err, ErrTxQuotaExceeded) || !strings.Contains(err.Error(), `rule "1"`) {
+		t.Fatalf("selector quota error mismatch: have %v, want %v of rule 1", err, ErrTxQuotaExceeded)
+	}
+	if err := pool.AddLocal(call(0, common.HexToAddress("0x02"), transfer, 1, keys[2])); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	// Replacements moving to a destination over its quota are rejected
+	if err := pool.addRemoteSync(call(1, common.HexToAddress("0x03"), transfer, 3, keys[0])); !errors.Is(err, ErrTxQuotaExceeded) {
+		t.Fatalf("replacement quota error mismatch: have %v, want %v", err, ErrTxQuotaExceeded)
+	}
+	// Ensure removing transactions frees up their quota
+	pool.mu.Lock()
+	pool.removeTx(pool.pending[crypto.PubkeyToAddress(keys[0].PublicKey)].txs.Get(1).Hash(), true)
+	pool.mu.Unlock()
+
+	if err := pool.addRemoteSync(call(1, contract, nil, 1, keys[1])); err != nil {
+		t.Fatalf("failed to add transaction after removal: %v", err)
+	}
+}
+
+// Tests that when the pool is full, the transactions of senders repeatedly
+// replacing theirs are evicted ahead of cheaper ones.
+func TestTransactionSpamEviction(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 3
+	config.GlobalQueue = 1
+	config.SpamHalfLife = time.Hour
+	config.SpamThreshold = 2
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	spammer, _ := crypto.GenerateKey()
+	honest, _ := crypto.GenerateKey()
+	cheap, _ := crypto.GenerateKey()
+	for _, key := range []*ecdsa.PrivateKey{spammer, honest, cheap} {
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	}
+	// Churn the pool with replacements, then fill it up
+	for price := int64(1); price <= 4; price++ {
+		if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(price), spammer)); err != nil {
+			t.Fatalf("failed to add spam transaction: %v", err)
+		}
+	}
+	tail := pricedTransaction(2, 100000, big.NewInt(10), spammer)
+	for _, tx := range []*types.Transaction{pricedTransaction(1, 100000, big.NewInt(10), spammer), tail, pricedTransaction(0, 100000, big.NewInt(5), honest)} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	// Ensure a transaction cheaper than all pooled ones evicts the spammer's last,
+	// without raising its score any further
+	score := pool.spam.get(crypto.PubkeyToAddress(spammer.PublicKey), time.Now())
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), cheap)); err != nil {
+		t.Fatalf("failed to add cheap transaction: %v", err)
+	}
+	if pool.Get(tail.Hash()) != nil {
+		t.Fatalf("spammer's transaction not evicted")
+	}
+	if have := pool.spam.get(crypto.PubkeyToAddress(spammer.PublicKey), time.Now()); have > score {
+		t.Fatalf("spam score raised by eviction: have %f, was %f", have, score)
+	}
+	if pending, queued := pool.Stats(); pending != 4 || queued != 0 {
+		t.Fatalf("pool content mismatch: have %d/%d, want 4/0", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Without spam scoring, the cheap transaction would have been underpriced
+	config.SpamHalfLife = 0
+	plain := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer plain.Stop()
+
+	for _, tx := range []*types.Transaction{pricedTransaction(0, 100000, big.NewInt(4), spammer), pricedTransaction(1, 100000, big.NewInt(10), spammer), tail, pricedTransaction(0, 100000, big.NewInt(5), honest)} {
+		if err := plain.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	if err := plain.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), cheap)); !errors.Is(err, ErrUnderpriced) {
+		t.Fatalf("cheap transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+}
+
+// Tests that the senders of evicted transactions are scored as spamming, unless
+// making room for their own transactions, or evicted by a price limit raise.
+func TestTransactionSpamEvictionScoring(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 1
+	config.GlobalQueue = 1
+	config.SpamHalfLife = time.Hour
+	config.SpamThreshold = 100
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		testAddBalance(pool, addrs[i], big.NewInt(1000000000))
+	}
+	score := func(addr common.Address) float64 {
+		return math.Round(pool.spam.get(addr, time.Now()))
+	}
+	// Fill the pool up, and evict the cheapest transaction with a better one
+	for i, price := range []int64{1, 2, 3} {
+		if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(price), keys[i])); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	if have := score(addrs[0]); have != 1 {
+		t.Fatalf("evicted sender score mismatch: have %f, want 1", have)
+	}
+	// Evicting its own cheapest transaction doesn't score the sender
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(5), keys[1])); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if pool.all.Get(pricedTransaction(0, 100000, big.NewInt(2), keys[1]).Hash()) != nil {
+		t.Fatalf("cheapest transaction not evicted")
+	}
+	if have := score(addrs[1]); have != 0 {
+		t.Fatalf("self-evicted sender score mismatch: have %f, want 0", have)
+	}
+	// Raising the price limit doesn't score the senders below it
+	pool.SetGasPrice(big.NewInt(4))
+	if pool.all.Get(pricedTransaction(0, 100000, big.NewInt(3), keys[2]).Hash()) != nil {
+		t.Fatalf("transaction below the price limit not dropped")
+	}
+	if have := score(addrs[2]); have != 0 {
+		t.Fatalf("price limited sender score mismatch: have %f, want 0", have)
+	}
+}
+
+// preExecBlockChain is a testBlockChain handing out state copies, pre-execution
+// running concurrently with the pool.
+type preExecBlockChain struct {
+	*testBlockChain
+}
+
+func (bc *preExecBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb.Copy(), nil
+}
+
+// Tests that remote transactions bound to fail are flagged or rejected when
+// pre-execution is enabled, and that the outcomes are cached.
+func TestTransactionPreExecution(t *testing.T) {
+	t.Run("flag", func(t *testing.T) { testTransactionPreExecution(t, PreExecFlag) })
+	t.Run("reject", func(t *testing.T) { testTransactionPreExecution(t, PreExecReject) })
+}
+
+func testTransactionPreExecution(t *testing.T, mode string) {
+	t.Parallel()
+
+	var (
+		reverter = common.HexToAddress("0xdead")
+		stopper  = common.HexToAddress("0xbeef")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(reverter, []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)})
+	statedb.SetCode(stopper, []byte{byte(vm.STOP)})
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		statedb.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(params.Ether))
+	}
+	blockchain := &preExecBlockChain{&testBlockChain{statedb, 1000000, new(event.Feed)}}
+
+	config := testTxPoolConfig
+	config.PreExec = mode
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Pay above the initial base fee, or the outcome is unknown
+	call := func(nonce uint64, to common.Address, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(0), 100000, big.NewInt(2*params.GWei), nil), types.HomesteadSigner{}, key)
+		return tx
+	}
+	// Ensure succeeding calls are accepted, and failing ones depending on the mode
+	if err := pool.addRemoteSync(call(0, stopper, keys[0])); err != nil {
+		t.Fatalf("failed to add succeeding transaction: %v", err)
+	}
+	failing := call(0, reverter, keys[1])
+	err := pool.addRemoteSync(failing)
+	switch mode {
+	case PreExecFlag:
+		if err != nil {
+			t.Fatalf("failed to add flagged transaction: %v", err)
+		}
+	case PreExecReject:
+		if !errors.Is(err, ErrPreExecutionFailed) {
+			t.Fatalf("failing transaction error mismatch: have %v, want %v", err, ErrPreExecutionFailed)
+		}
+		// Retry to hit the cache
+		if err := pool.addRemoteSync(failing); !errors.Is(err, ErrPreExecutionFailed) {
+			t.Fatalf("cached failing transaction error mismatch: have %v, want %v", err, ErrPreExecutionFailed)
+		}
+	}
+	if !pool.preExecCache.Contains(preExecKey{tx: failing.Hash(), root: pool.currentHead.Root}) {
+		t.Fatalf("pre-execution result not cached")
+	}
+	// Ensure transactions are executed after the pending ones of their sender
+	dependent := call(1, reverter, keys[0])
+	err = pool.addRemoteSync(dependent)
+	if mode == PreExecReject && !errors.Is(err, ErrPreExecutionFailed) {
+		t.Fatalf("dependent transaction error mismatch: have %v, want %v", err, ErrPreExecutionFailed)
+	}
+	prior := types.Transactions{pool.pending[crypto.PubkeyToAddress(keys[0].PublicKey)].txs.Get(0)}
+	if !pool.preExecCache.Contains(preExecKey{tx: dependent.Hash(), root: pool.currentHead.Root, prior: priorHash(prior)}) {
+		t.Fatalf("dependent transaction not pre-executed")
+	}
+	// Ensure local and gapped transactions aren't pre-executed
+	if err := pool.AddLocal(call(0, reverter, keys[2])); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	gapped := call(5, reverter, keys[0])
+	if err := pool.addRemoteSync(gapped); err != nil {
+		t.Fatalf("failed to add gapped transaction: %v", err)
+	}
+	if pool.preExecCache.Len() != 3 {
+		t.Fatalf("pre-execution cache size mismatch: have %d, want 3", pool.preExecCache.Len())
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -2541,3 +2900,307 @@ func BenchmarkInsertRemoteWithAllLocals(b *testing.B) {
 		pool.Stop()
 	}
 }
+
+// Tests that private transactions are tracked until their lifetime expires, and
+// are then either dropped or published depending on the configuration.
+func TestTransactionPrivate(t *testing.T) {
+	t.Parallel()
+
+	for _, publish := range []bool{false, true} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+		config := testTxPoolConfig
+		config.PrivatePublish = publish
+
+		pool := NewTxPool(config, params.TestChainConfig, blockchain)
+		defer pool.Stop()
+
+		key, _ := crypto.GenerateKey()
+		testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+		publicCh := make(chan NewTxsEvent, 1)
+		sub := pool.SubscribePublicTxsEvent(publicCh)
+		defer sub.Unsubscribe()
+
+		shownCh := make(chan NewTxsEvent, 2)
+		shownSub := pool.SubscribeShownTxsEvent(shownCh)
+		defer shownSub.Unsubscribe()
+
+		drops := make(chan TxDropEvent, 1)
+		dropSub := pool.SubscribeDropTxsEvent(drops)
+		defer dropSub.Unsubscribe()
+
+		private, public := transaction(0, 100000, key), transaction(1, 100000, key)
+		if err := pool.AddPrivate(private); err != nil {
+			t.Fatalf("failed to add private transaction: %v", err)
+		}
+		if err := pool.AddRemotesSync([]*types.Transaction{public})[0]; err != nil {
+			t.Fatalf("failed to add public transaction: %v", err)
+		}
+		if !pool.IsPrivate(private.Hash()) || pool.IsPrivate(public.Hash()) {
+			t.Fatalf("privacy mismatch: private %v, public %v", pool.IsPrivate(private.Hash()), pool.IsPrivate(public.Hash()))
+		}
+		if pending, _ := pool.Stats(); pending != 2 {
+			t.Fatalf("pending transactions mismatch: have %d, want %d", pending, 2)
+		}
+		// Only the public transaction is shown
+		select {
+		case ev := <-shownCh:
+			if len(ev.Txs) != 1 || ev.Txs[0].Hash() != public.Hash() {
+				t.Fatalf("shown transactions mismatch: have %v, want %x", ev.Txs, public.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("public transaction not shown")
+		}
+		// Known transactions can't be turned private
+		if err := pool.AddPrivate(public); err != ErrAlreadyKnown {
+			t.Fatalf("re-adding public transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+		}
+		if pool.IsPrivate(public.Hash()) {
+			t.Fatalf("known transaction turned private")
+		}
+		// Nothing happens before the lifetime expires
+		pool.expirePrivate(time.Now())
+		if !pool.IsPrivate(private.Hash()) {
+			t.Fatalf("private transaction expired early")
+		}
+		pool.expirePrivate(time.Now().Add(config.PrivateLifetime))
+		if pool.IsPrivate(private.Hash()) {
+			t.Fatalf("private transaction not expired")
+		}
+		if publish {
+			select {
+			case ev := <-publicCh:
+				if len(ev.Txs) != 1 || ev.Txs[0].Hash() != private.Hash() {
+					t.Fatalf("published transactions mismatch: have %v, want %x", ev.Txs, private.Hash())
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("expired private transaction not published")
+			}
+			if !pool.Has(private.Hash()) {
+				t.Fatalf("published transaction dropped")
+			}
+			select {
+			case ev := <-shownCh:
+				if len(ev.Txs) != 1 || ev.Txs[0].Hash() != private.Hash() {
+					t.Fatalf("shown transactions mismatch: have %v, want %x", ev.Txs, private.Hash())
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("published transaction not shown")
+			}
+		} else {
+			if pool.Has(private.Hash()) {
+				t.Fatalf("expired private transaction not dropped")
+			}
+			<-pool.requestReset(nil, nil)
+			select {
+			case ev := <-drops:
+				t.Fatalf("private transaction drop announced: %x %s", ev.Tx.Hash(), ev.Reason)
+			case ev := <-shownCh:
+				t.Fatalf("private transaction shown: %v", ev.Txs)
+			default:
+			}
+			if err := validateTxPoolInternals(pool); err != nil {
+				t.Fatalf("pool internal state corrupted: %v", err)
+			}
+		}
+	}
+}
+
+// Tests that transactions submitted with preconditions are only accepted if the
+// preconditions can be met, and dropped once their block range ends.
+func TestTransactionConditional(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	var (
+		contract = common.HexToAddress("0xc0de")
+		slot     = common.HexToHash("0x01")
+	)
+	statedb.SetState(contract, slot, common.HexToHash("0x01"))
+
+	shownCh := make(chan NewTxsEvent, 1)
+	shownSub := pool.SubscribeShownTxsEvent(shownCh)
+	defer shownSub.Unsubscribe()
+
+	drops := make(chan TxDropEvent, 1)
+	dropSub := pool.SubscribeDropTxsEvent(drops)
+	defer dropSub.Unsubscribe()
+
+	expect := func(value byte) *types.TransactionConditional {
+		return &types.TransactionConditional{KnownAccounts: types.KnownAccounts{
+			contract: {StorageSlots: map[common.Hash]common.Hash{slot: common.BytesToHash([]byte{value})}},
+		}}
+	}
+	// Transactions without preconditions or with expired ones are rejected
+	if err := pool.AddConditional(transaction(0, 100000, key)); err == nil {
+		t.Fatalf("transaction without preconditions accepted")
+	}
+	expired := transaction(0, 100000, key)
+	expired.SetConditional(&types.TransactionConditional{BlockNumberMax: common.Big0})
+	if err := pool.AddConditional(expired); !errors.Is(err, types.ErrConditionalBlock) {
+		t.Fatalf("expired block range error mismatch: have %v, want %v", err, types.ErrConditionalBlock)
+	}
+	// Preconditions which can be met are accepted as remote transactions, and
+	// kept out of the network
+	tx := transaction(0, 100000, key)
+	tx.SetConditional(expect(1))
+	if err := pool.AddConditional(tx); err != nil {
+		t.Fatalf("failed to add conditional transaction: %v", err)
+	}
+	if !pool.IsPrivate(tx.Hash()) {
+		t.Fatalf("conditional transaction not private")
+	}
+	if pool.locals.contains(crypto.PubkeyToAddress(key.PublicKey)) {
+		t.Fatalf("conditional transaction sender tracked as local")
+	}
+	// The transaction is kept while the storage differs, as it may change back
+	<-pool.requestReset(nil, nil)
+	if !pool.Has(tx.Hash()) {
+		t.Fatalf("conditional transaction dropped while preconditions hold")
+	}
+	statedb.SetState(contract, slot, common.HexToHash("0x02"))
+	<-pool.requestReset(nil, nil)
+	if !pool.Has(tx.Hash()) {
+		t.Fatalf("conditional transaction dropped on a storage mismatch")
+	}
+	// It's only dropped once its block range ends
+	dead := transaction(1, 100000, key)
+	dead.SetConditional(&types.TransactionConditional{BlockNumberMax: common.Big1})
+	if err := pool.AddConditional(dead); err != nil {
+		t.Fatalf("failed to add conditional transaction: %v", err)
+	}
+	<-pool.requestReset(nil, &types.Header{Number: common.Big1, GasLimit: 1000000})
+	if pool.Has(dead.Hash()) {
+		t.Fatalf("expired conditional transaction not dropped")
+	}
+	if !pool.Has(tx.Hash()) {
+		t.Fatalf("unexpired conditional transaction dropped")
+	}
+	// Neither its arrival nor its drop are announced
+	select {
+	case ev := <-shownCh:
+		t.Fatalf("conditional transaction shown: %v", ev.Txs)
+	case ev := <-drops:
+		t.Fatalf("conditional transaction drop announced: %x %s", ev.Tx.Hash(), ev.Reason)
+	default:
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that replaced transactions are announced as dropped along with their
+// replacement.
+func TestTransactionReplacementDropEvent(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	drops := make(chan TxDropEvent, 2)
+	sub := pool.SubscribeDropTxsEvent(drops)
+	defer sub.Unsubscribe()
+
+	// Replace both a pending and a queued transaction
+	pending, queued := pricedTransaction(0, 100000, big.NewInt(1), key), pricedTransaction(2, 100000, big.NewInt(1), key)
+	pendingRepl, queuedRepl := pricedTransaction(0, 100000, big.NewInt(2), key), pricedTransaction(2, 100000, big.NewInt(2), key)
+
+	for _, tx := range []*types.Transaction{pending, queued, pendingRepl, queuedRepl} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	want := map[common.Hash]common.Hash{pending.Hash(): pendingRepl.Hash(), queued.Hash(): queuedRepl.Hash()}
+	for i := 0; i < len(want); i++ {
+		select {
+		case ev := <-drops:
+			if ev.Reason != TxDropReplaced || ev.Replacement == nil || want[ev.Tx.Hash()] != ev.Replacement.Hash() {
+				t.Fatalf("drop event mismatch: %x %s by %v", ev.Tx.Hash(), ev.Reason, ev.Replacement)
+			}
+			delete(want, ev.Tx.Hash())
+		case <-time.After(time.Second):
+			t.Fatalf("missing drop events: %v", want)
+		}
+	}
+}
+
+// Tests that transactions removed from the pool without being included are
+// announced along with the reason.
+func TestTransactionDropEventReasons(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.AccountQueue = 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	drops := make(chan TxDropEvent, 16)
+	sub := pool.SubscribeDropTxsEvent(drops)
+	defer sub.Unsubscribe()
+
+	expect := func(want map[common.Hash]TxDropReason) {
+		t.Helper()
+		for len(want) > 0 {
+			select {
+			case ev := <-drops:
+				if reason, ok := want[ev.Tx.Hash()]; !ok || reason != ev.Reason {
+					t.Fatalf("unexpected drop event: %x %s", ev.Tx.Hash(), ev.Reason)
+				}
+				delete(want, ev.Tx.Hash())
+			case <-time.After(time.Second):
+				t.Fatalf("missing drop events: %v", want)
+			}
+		}
+	}
+	cheap, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(cheap.PublicKey), big.NewInt(1000000))
+
+	// Transactions beyond the account queue limit are evicted
+	queued, capped := pricedTransaction(2, 100000, big.NewInt(1), cheap), pricedTransaction(3, 100000, big.NewInt(1), cheap)
+	if errs := pool.AddRemotesSync([]*types.Transaction{queued, capped}); errs[0] != nil || errs[1] != nil {
+		t.Fatalf("failed to add transactions: %v", errs)
+	}
+	expect(map[common.Hash]TxDropReason{capped.Hash(): TxDropEvicted})
+
+	// Transactions below a raised price limit are underpriced
+	pool.SetGasPrice(big.NewInt(2))
+	expect(map[common.Hash]TxDropReason{queued.Hash(): TxDropUnderpriced})
+
+	// Transactions whose nonce was used by a transaction not in the new head are
+	// invalidated, as are the ones which can't be paid for any more
+	stale, poor := pricedTransaction(0, 100000, big.NewInt(2), cheap), pricedTransaction(1, 100000, big.NewInt(2), cheap)
+	if errs := pool.AddRemotesSync([]*types.Transaction{stale, poor}); errs[0] != nil || errs[1] != nil {
+		t.Fatalf("failed to add transactions: %v", errs)
+	}
+	pool.mu.Lock()
+	pool.currentState.SetNonce(crypto.PubkeyToAddress(cheap.PublicKey), 1)
+	pool.currentState.SetBalance(crypto.PubkeyToAddress(cheap.PublicKey), big.NewInt(1))
+	pool.mu.Unlock()
+
+	parent := &types.Header{Number: big.NewInt(0), GasLimit: blockchain.gasLimit}
+	head := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(1), GasLimit: blockchain.gasLimit}
+	<-pool.requestReset(parent, head)
+	expect(map[common.Hash]TxDropReason{stale.Hash(): TxDropInvalidated, poor.Hash(): TxDropInvalidated})
+
+	select {
+	case ev := <-drops:
+		t.Fatalf("unexpected drop event: %x %s", ev.Tx.Hash(), ev.Reason)
+	default:
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// PreExecFlag is the pre-execution mode only flagging the remote transactions
+	// bound to fail in metrics and logs, a dry run sizing the impact of rejecting.
+	PreExecFlag = "flag"
+
+	// PreExecReject is the pre-execution mode rejecting the remote transactions
+	// bound to fail.
+	PreExecReject = "reject"
+
+	// preExecCacheSize is the number of pre-execution results to cache.
+	preExecCacheSize = 4096
+)
+
+// ErrPreExecutionFailed is returned if a remote transaction reverts or runs out
+// of gas when pre-executed on top of the pending state.
+var ErrPreExecutionFailed = errors.New("transaction pre-execution failed")
+
+var (
+	preExecRejectMeter  = metrics.NewRegisteredMeter("txpool/preexec/reject", nil)
+	preExecFlagMeter    = metrics.NewRegisteredMeter("txpool/preexec/flag", nil)
+	preExecSkipMeter    = metrics.NewRegisteredMeter("txpool/preexec/skip", nil)    // Behind a nonce gap or over the gas budget
+	preExecTimeoutMeter = metrics.NewRegisteredMeter("txpool/preexec/timeout", nil) // Over the time budget
+	preExecHitMeter     = metrics.NewRegisteredMeter("txpool/preexec/hit", nil)     // Result found in the cache
+	preExecTimer        = metrics.NewRegisteredTimer("txpool/preexec/time", nil)
+)
+
+// preExecKey identifies a pre-execution result: the outcome of a transaction
+// only depends on the state it's executed on, the head state with the earlier
+// pending transactions of the sender applied.
+type preExecKey struct {
+	tx    common.Hash
+	root  common.Hash
+	prior common.Hash // Hash of the earlier transactions, zero if none
+}
+
+// priorHash returns the hash identifying the earlier transactions of a sender in
+// a pre-execution key.
+func priorHash(prior types.Transactions) common.Hash {
+	if len(prior) == 0 {
+		return common.Hash{}
+	}
+	hashes := make([]byte, 0, len(prior)*common.HashLength)
+	for _, tx := range prior {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// preExecResult is a cached pre-execution outcome, the error being nil if the
+// transaction succeeded.
+type preExecResult struct {
+	err error
+}
+
+// preExecute executes the remote transactions without an error yet on top of
+// the pending state, setting the error of the ones failing if rejecting them.
+//
+// Transactions are executed after the pending ones of their sender with lower
+// nonces. They're only executed if those leave no nonce gap and all fit within
+// the gas budget, the outcome of the others being unknown. It must be called
+// without holding the pool lock, execution can take a while.
+func (pool *TxPool) preExecute(txs []*types.Transaction, errs []error) {
+	pool.mu.RLock()
+	var (
+		head    = pool.currentHead
+		remotes []int
+		prior   = make(map[int]types.Transactions)
+	)
+	for i, tx := range txs {
+		if errs[i] == nil && !pool.locals.containsTx(tx) {
+			remotes = append(remotes, i)
+
+			from, _ := types.Sender(pool.signer, tx) // already validated
+			if list := pool.pending[from]; list != nil {
+				for _, ptx := range list.Flatten() {
+					if ptx.Nonce() >= tx.Nonce() {
+						break
+					}
+					prior[i] = append(prior[i], ptx)
+				}
+			}
+		}
+	}
+	pool.mu.RUnlock()
+
+	var statedb *state.StateDB // Opened on the first cache miss
+	for _, i := range remotes {
+		tx := txs[i]
+		key := preExecKey{tx: tx.Hash(), root: head.Root, prior: priorHash(prior[i])}
+
+		var err error
+		if cached, ok := pool.preExecCache.Get(key); ok {
+			preExecHitMeter.Mark(1)
+			err = cached.(*preExecResult).err
+		} else {
+			if statedb == nil {
+				var stateErr error
+				if statedb, stateErr = pool.chain.StateAt(head.Root); stateErr != nil {
+					log.Warn("Failed to open state for pre-execution", "root", head.Root, "err", stateErr)
+					return
+				}
+			}
+			var known bool
+			if known, err = pool.preExecuteTx(statedb, head, prior[i], tx); !known {
+				continue
+			}
+			pool.preExecCache.Add(key, &preExecResult{err: err})
+		}
+		if err == nil {
+			continue
+		}
+		if pool.config.PreExec == PreExecReject {
+			log.Trace("Rejecting failing transaction", "hash", tx.Hash(), "err", err)
+			preExecRejectMeter.Mark(1)
+			errs[i] = err
+		} else {
+			log.Debug("Flagging failing transaction", "hash", tx.Hash(), "err", err)
+			preExecFlagMeter.Mark(1)
+		}
+	}
+}
+
+// preExecuteTx executes a transaction on top of the given state as part of the
+// block following head, after the given earlier transactions of its sender, and
+// reverts all their changes afterwards. It returns whether the outcome is known,
+// along with the error if the transaction fails.
+func (pool *TxPool) preExecuteTx(statedb *state.StateDB, head *types.Header, prior types.Transactions, tx *types.Transaction) (bool, error) {
+	from, _ := types.Sender(pool.signer, tx) // already validated during insertion
+
+	txs := append(prior[:len(prior):len(prior)], tx)
+	gas := uint64(0)
+	for _, tx := range txs {
+		gas += tx.Gas()
+	}
+	if gas > pool.config.PreExecGas || statedb.GetNonce(from) != txs[0].Nonce() {
+		preExecSkipMeter.Mark(1)
+		return false, nil
+	}
+	defer func(start time.Time) { preExecTimer.UpdateSince(start) }(time.Now())
+
+	number := new(big.Int).Add(head.Number, common.Big1)
+	blockCtx := vm.BlockContext{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		GetHash:     pool.ancestorHash(head),
+		GasLimit:    head.GasLimit,
+		BlockNumber: number,
+		Time:        new(big.Int).SetUint64(head.Time + 1),
+		Difficulty:  head.Difficulty,
+	}
+	if now := uint64(time.Now().Unix()); now > head.Time {
+		blockCtx.Time.SetUint64(now)
+	}
+	if pool.chainconfig.IsLondon(number) {
+		blockCtx.BaseFee = misc.CalcBaseFee(pool.chainconfig, head)
+	}
+	// The earlier transactions are finalised like in a block, revert them all
+	snapshot := statedb.MultiTxSnapshot()
+	defer statedb.RevertToSnapshot(snapshot)
+
+	evm := vm.NewEVM(blockCtx, vm.TxContext{}, statedb, pool.chainconfig, vm.Config{})
+	timer := time.AfterFunc(pool.config.PreExecTimeout, evm.Cancel)
+	defer timer.Stop()
+
+	var (
+		gp  = new(GasPool).AddGas(head.GasLimit)
+		res *ExecutionResult
+	)
+	for _, tx := range txs {
+		msg, err := tx.AsMessage(pool.signer, blockCtx.BaseFee)
+		if err != nil {
+			return false, nil
+		}
+		evm.Reset(NewEVMTxContext(msg), statedb)
+		res, err = ApplyMessage(evm, msg, gp)
+		if evm.Cancelled() {
+			preExecTimeoutMeter.Mark(1)
+			return false, nil
+		}
+		if err != nil {
+			// Not includable on top of the pending state (e.g. fee cap below the
+			// base fee), but possibly later on, leave it to the usual validation
+			return false, nil
+		}
+		statedb.Finalise(pool.chainconfig.IsEIP158(number))
+	}
+	if res.Failed() {
+		return true, fmt.Errorf("%w: %v", ErrPreExecutionFailed, res.Err)
+	}
+	return true, nil
+}
+
+// ancestorHash returns a GetHashFunc retrieving the hashes of the ancestors of
+// the block following head.
+func (pool *TxPool) ancestorHash(head *types.Header) vm.GetHashFunc {
+	return func(n uint64) common.Hash {
+		for header := head; header != nil && header.Number.Uint64() >= n; {
+			if header.Number.Uint64() == n {
+				return header.Hash()
+			}
+			if header.Number.Sign() == 0 {
+				break
+			}
+			block := pool.chain.GetBlock(header.ParentHash, header.Number.Uint64()-1)
+			if block == nil {
+				break
+			}
+			header = block.Header()
+		}
+		return common.Hash{}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// privateTxSet tracks the transactions that must be kept out of the network
+// gossip, along with the time their privacy expires at.
+//
+// It has its own lock as the network layer checks it for every transaction it
+// broadcasts, it doesn't need to contend on the pool lock.
+type privateTxSet struct {
+	deadlines map[common.Hash]time.Time
+	lock      sync.RWMutex
+}
+
+// newPrivateTxSet creates a new, empty set of private transactions.
+func newPrivateTxSet() *privateTxSet {
+	return &privateTxSet{deadlines: make(map[common.Hash]time.Time)}
+}
+
+// add marks a transaction private until the given deadline.
+func (s *privateTxSet) add(hash common.Hash, deadline time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.deadlines[hash] = deadline
+}
+
+// remove unmarks a transaction.
+func (s *privateTxSet) remove(hash common.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.deadlines, hash)
+}
+
+// contains returns whether a transaction is private.
+func (s *privateTxSet) contains(hash common.Hash) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, ok := s.deadlines[hash]
+	return ok
+}
+
+// expire unmarks and returns all the transactions whose deadline passed.
+func (s *privateTxSet) expire(now time.Time) []common.Hash {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var expired []common.Hash
+	for hash, deadline := range s.deadlines {
+		if !now.Before(deadline) {
+			expired = append(expired, hash)
+			delete(s.deadlines, hash)
+		}
+	}
+	return expired
+}
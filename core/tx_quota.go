@@ -0,0 +1,176 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// ErrTxQuotaExceeded is returned if a remote transaction would exceed the quota
+// of pooled transactions for its destination contract or method selector.
+var ErrTxQuotaExceeded = errors.New("transaction quota exceeded")
+
+// TxQuota limits the number of pooled transactions calling a contract, a method
+// selector, or a method selector of a given contract. Local transactions count
+// towards the quotas, but are always accepted.
+type TxQuota struct {
+	Name     string          // Name of the rule in the metrics and errors, its index if empty
+	Contract *common.Address // Destination contract, any if nil
+	Selector hexutil.Bytes   // Method selector, any if empty
+	Limit    uint64          // Maximum number of pooled transactions matching the rule
+}
+
+// ParseTxQuota parses a quota rule in the form <contract>[:<selector>]=<limit>,
+// where the contract may be * to match any destination.
+func ParseTxQuota(rule string) (TxQuota, error) {
+	var quota TxQuota
+
+	parts := strings.Split(rule, "=")
+	if len(parts) != 2 {
+		return quota, fmt.Errorf("invalid quota %q, want <contract>[:<selector>]=<limit>", rule)
+	}
+	limit, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil {
+		return quota, fmt.Errorf("invalid quota limit %q: %v", parts[1], err)
+	}
+	quota.Limit = limit
+
+	target := strings.Split(strings.TrimSpace(parts[0]), ":")
+	if len(target) > 2 {
+		return quota, fmt.Errorf("invalid quota target %q", parts[0])
+	}
+	if target[0] != "*" {
+		if !common.IsHexAddress(target[0]) {
+			return quota, fmt.Errorf("invalid quota contract %q", target[0])
+		}
+		contract := common.HexToAddress(target[0])
+		quota.Contract = &contract
+	}
+	if len(target) == 2 {
+		if quota.Selector, err = hexutil.Decode(target[1]); err != nil {
+			return quota, fmt.Errorf("invalid quota selector %q: %v", target[1], err)
+		}
+	}
+	return quota, quota.validate()
+}
+
+// validate checks that the quota is well formed.
+func (q *TxQuota) validate() error {
+	if q.Contract == nil && len(q.Selector) == 0 {
+		return errors.New("quota matches all transactions")
+	}
+	if len(q.Selector) != 0 && len(q.Selector) != 4 {
+		return fmt.Errorf("invalid quota selector length %d", len(q.Selector))
+	}
+	if q.Limit == 0 {
+		return errors.New("zero quota limit")
+	}
+	return nil
+}
+
+// matches returns whether the transaction counts towards the quota.
+func (q *TxQuota) matches(tx *types.Transaction) bool {
+	to := tx.To()
+	if to == nil {
+		return false
+	}
+	if q.Contract != nil && *q.Contract != *to {
+		return false
+	}
+	return len(q.Selector) == 0 || bytes.HasPrefix(tx.Data(), q.Selector)
+}
+
+// txQuotas tracks the number of pooled transactions matching each quota rule.
+//
+// Note, it's not safe for concurrent use, it's protected by the txLookup lock.
+type txQuotas struct {
+	rules  []TxQuota
+	names  []string // Names of the rules in the metrics and errors
+	counts []uint64
+
+	rejectMeters []metrics.Meter
+	countGauges  []metrics.Gauge
+}
+
+// newTxQuotas creates the trackers of the given quota rules, nil if there are
+// none.
+func newTxQuotas(rules []TxQuota) *txQuotas {
+	if len(rules) == 0 {
+		return nil
+	}
+	q := &txQuotas{
+		rules:        rules,
+		names:        make([]string, len(rules)),
+		counts:       make([]uint64, len(rules)),
+		rejectMeters: make([]metrics.Meter, len(rules)),
+		countGauges:  make([]metrics.Gauge, len(rules)),
+	}
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		q.names[i] = name
+		q.rejectMeters[i] = metrics.GetOrRegisterMeter("txpool/quota/"+name+"/reject", nil)
+		q.countGauges[i] = metrics.GetOrRegisterGauge("txpool/quota/"+name+"/count", nil)
+	}
+	return q
+}
+
+// add counts a transaction entering the pool.
+func (q *txQuotas) add(tx *types.Transaction) {
+	for i := range q.rules {
+		if q.rules[i].matches(tx) {
+			q.counts[i]++
+			q.countGauges[i].Update(int64(q.counts[i]))
+		}
+	}
+}
+
+// remove uncounts a transaction leaving the pool.
+func (q *txQuotas) remove(tx *types.Transaction) {
+	for i := range q.rules {
+		if q.rules[i].matches(tx) {
+			q.counts[i]--
+			q.countGauges[i].Update(int64(q.counts[i]))
+		}
+	}
+}
+
+// check returns an error if the transaction, replacing the given one if not nil,
+// would exceed any of the quotas.
+func (q *txQuotas) check(tx *types.Transaction, replaced *types.Transaction) error {
+	for i := range q.rules {
+		if !q.rules[i].matches(tx) || (replaced != nil && q.rules[i].matches(replaced)) {
+			continue
+		}
+		if q.counts[i] >= q.rules[i].Limit {
+			q.rejectMeters[i].Mark(1)
+			return fmt.Errorf("%w: %d transactions matching rule %q pooled, limit %d", ErrTxQuotaExceeded, q.counts[i], q.names[i], q.rules[i].Limit)
+		}
+	}
+	return nil
+}
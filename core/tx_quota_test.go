@@ -0,0 +1,65 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseTxQuota(t *testing.T) {
+	contract := common.HexToAddress("0x70c87d191324e6712a591f304b4eedef6ad9bb9d")
+	tests := []struct {
+		rule     string
+		contract *common.Address
+		selector string
+		limit    uint64
+		fail     bool
+	}{
+		{rule: "0x70c87d191324e6712a591f304b4eedef6ad9bb9d=100", contract: &contract, limit: 100},
+		{rule: "0x70c87d191324e6712a591f304b4eedef6ad9bb9d:0xa9059cbb=5", contract: &contract, selector: "0xa9059cbb", limit: 5},
+		{rule: "*:0xa9059cbb=500", selector: "0xa9059cbb", limit: 500},
+		{rule: "*=10", fail: true},
+		{rule: "0x70c87d191324e6712a591f304b4eedef6ad9bb9d=0", fail: true},
+		{rule: "0x70c87d191324e6712a591f304b4eedef6ad9bb9d:0xa9059c=1", fail: true},
+		{rule: "0x70c87d=1", fail: true},
+		{rule: "0x70c87d191324e6712a591f304b4eedef6ad9bb9d", fail: true},
+	}
+	for _, tt := range tests {
+		quota, err := ParseTxQuota(tt.rule)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("rule %q: expected failure", tt.rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("rule %q: unexpected error: %v", tt.rule, err)
+			continue
+		}
+		if (quota.Contract == nil) != (tt.contract == nil) || (quota.Contract != nil && *quota.Contract != *tt.contract) {
+			t.Errorf("rule %q: contract mismatch: have %v, want %v", tt.rule, quota.Contract, tt.contract)
+		}
+		if selector := quota.Selector.String(); (tt.selector == "" && len(quota.Selector) != 0) || (tt.selector != "" && selector != tt.selector) {
+			t.Errorf("rule %q: selector mismatch: have %s, want %s", tt.rule, selector, tt.selector)
+		}
+		if quota.Limit != tt.limit {
+			t.Errorf("rule %q: limit mismatch: have %d, want %d", tt.rule, quota.Limit, tt.limit)
+		}
+	}
+}
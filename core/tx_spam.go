@@ -0,0 +1,102 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// spamScores tracks a decaying spam score per sender, raised each time one of
+// its transactions is replaced or evicted. Senders churning the pool the most
+// get their transactions evicted first when the pool is full.
+//
+// Note, it's not safe for concurrent use, it's protected by the pool lock.
+type spamScores struct {
+	halfLife time.Duration
+	scores   map[common.Address]*spamScore
+}
+
+// spamScore is the score of a sender as of its last update.
+type spamScore struct {
+	value   float64
+	updated time.Time
+}
+
+// newSpamScores creates a score tracker, halving scores every halfLife.
+func newSpamScores(halfLife time.Duration) *spamScores {
+	return &spamScores{
+		halfLife: halfLife,
+		scores:   make(map[common.Address]*spamScore),
+	}
+}
+
+// decayed returns the score as of the given time.
+func (s *spamScores) decayed(score *spamScore, now time.Time) float64 {
+	elapsed := now.Sub(score.updated)
+	if elapsed <= 0 {
+		return score.value
+	}
+	return score.value * math.Exp2(-float64(elapsed)/float64(s.halfLife))
+}
+
+// bump raises the score of a sender by one.
+func (s *spamScores) bump(addr common.Address, now time.Time) {
+	score := s.scores[addr]
+	if score == nil {
+		score = new(spamScore)
+		s.scores[addr] = score
+	}
+	score.value = s.decayed(score, now) + 1
+	score.updated = now
+}
+
+// get returns the current score of a sender.
+func (s *spamScores) get(addr common.Address, now time.Time) float64 {
+	if score := s.scores[addr]; score != nil {
+		return s.decayed(score, now)
+	}
+	return 0
+}
+
+// above returns the senders scoring above the given threshold, highest first.
+func (s *spamScores) above(threshold float64, now time.Time) []common.Address {
+	var (
+		addrs  []common.Address
+		values = make(map[common.Address]float64)
+	)
+	for addr, score := range s.scores {
+		if value := s.decayed(score, now); value > threshold {
+			addrs = append(addrs, addr)
+			values[addr] = value
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return values[addrs[i]] > values[addrs[j]] })
+	return addrs
+}
+
+// prune forgets the senders whose score decayed below one.
+func (s *spamScores) prune(now time.Time) {
+	for addr, score := range s.scores {
+		if s.decayed(score, now) < 1 {
+			delete(s.scores, addr)
+		}
+	}
+}
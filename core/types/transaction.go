@@ -52,6 +52,8 @@ type Transaction struct {
 	inner TxData    // Consensus contents of a transaction
 	time  time.Time // Time first seen locally (spam avoidance)
 
+	conditional *TransactionConditional // Preconditions submitted locally, if any
+
 	// caches
 	hash atomic.Value
 	size atomic.Value
@@ -364,6 +366,28 @@ func (tx *Transaction) EffectiveGasTipIntCmp(other *big.Int, baseFee *big.Int) i
 	return tx.EffectiveGasTipValue(baseFee).Cmp(other)
 }
 
+// Time returns the time the transaction was first seen locally.
+func (tx *Transaction) Time() time.Time {
+	return tx.time
+}
+
+// SetTime overrides the time the transaction was first seen locally. It's meant
+// for tests and for restoring transactions loaded from disk.
+func (tx *Transaction) SetTime(t time.Time) {
+	tx.time = t
+}
+
+// Conditional returns the preconditions the transaction was submitted with, nil
+// if none. They are local to the node and not part of the transaction encoding.
+func (tx *Transaction) Conditional() *TransactionConditional {
+	return tx.conditional
+}
+
+// SetConditional attaches preconditions to the transaction.
+func (tx *Transaction) SetConditional(conditional *TransactionConditional) {
+	tx.conditional = conditional
+}
+
 // Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
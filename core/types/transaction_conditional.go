@@ -0,0 +1,192 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// MaxConditionalCost is the maximum number of preconditions a transaction can
+// be submitted with, each block number and timestamp bound, storage root and
+// storage slot counting as one.
+const MaxConditionalCost = 1000
+
+var (
+	// ErrConditionalTooCostly is returned if a transaction is submitted with
+	// more preconditions than MaxConditionalCost.
+	ErrConditionalTooCostly = errors.New("too many transaction preconditions")
+
+	// ErrConditionalBlock is returned if the block number or timestamp of a
+	// block is out of the range allowed by a transaction's preconditions.
+	ErrConditionalBlock = errors.New("block out of transaction precondition range")
+
+	// ErrConditionalStorage is returned if the storage of an account doesn't
+	// match the value expected by a transaction's preconditions.
+	ErrConditionalStorage = errors.New("storage doesn't match transaction precondition")
+)
+
+// KnownAccount is the storage an account is expected to have for a transaction
+// to be included: either its whole storage root, or the values of some slots.
+//
+// In JSON it's either a storage root hash, or an object of slot values.
+type KnownAccount struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+// MarshalJSON encodes the storage root if set, the slot values otherwise.
+func (a KnownAccount) MarshalJSON() ([]byte, error) {
+	if a.StorageRoot != nil {
+		return json.Marshal(*a.StorageRoot)
+	}
+	return json.Marshal(a.StorageSlots)
+}
+
+// UnmarshalJSON decodes either a storage root or an object of slot values.
+func (a *KnownAccount) UnmarshalJSON(input []byte) error {
+	if trimmed := bytes.TrimSpace(input); len(trimmed) > 0 && trimmed[0] == '"' {
+		var root common.Hash
+		if err := json.Unmarshal(input, &root); err != nil {
+			return err
+		}
+		*a = KnownAccount{StorageRoot: &root}
+		return nil
+	}
+	var slots map[common.Hash]common.Hash
+	if err := json.Unmarshal(input, &slots); err != nil {
+		return err
+	}
+	*a = KnownAccount{StorageSlots: slots}
+	return nil
+}
+
+// KnownAccounts is the expected storage of a set of accounts.
+type KnownAccounts map[common.Address]KnownAccount
+
+// TransactionConditional is the set of preconditions a transaction is submitted
+// with. The transaction is only included into blocks meeting all of them, and
+// it's dropped once they can no longer be met. Bounds are inclusive.
+type TransactionConditional struct {
+	BlockNumberMin *big.Int
+	BlockNumberMax *big.Int
+	TimestampMin   *uint64
+	TimestampMax   *uint64
+	KnownAccounts  KnownAccounts
+}
+
+// transactionConditionalJSON is the JSON encoding of the numeric fields of a
+// TransactionConditional, which are hex quantities.
+type transactionConditionalJSON struct {
+	BlockNumberMin *hexutil.Big    `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *hexutil.Big    `json:"blockNumberMax,omitempty"`
+	TimestampMin   *hexutil.Uint64 `json:"timestampMin,omitempty"`
+	TimestampMax   *hexutil.Uint64 `json:"timestampMax,omitempty"`
+	KnownAccounts  KnownAccounts   `json:"knownAccounts,omitempty"`
+}
+
+// MarshalJSON encodes the preconditions with hex quantities.
+func (c TransactionConditional) MarshalJSON() ([]byte, error) {
+	return json.Marshal(transactionConditionalJSON{
+		BlockNumberMin: (*hexutil.Big)(c.BlockNumberMin),
+		BlockNumberMax: (*hexutil.Big)(c.BlockNumberMax),
+		TimestampMin:   (*hexutil.Uint64)(c.TimestampMin),
+		TimestampMax:   (*hexutil.Uint64)(c.TimestampMax),
+		KnownAccounts:  c.KnownAccounts,
+	})
+}
+
+// UnmarshalJSON decodes preconditions with hex quantities.
+func (c *TransactionConditional) UnmarshalJSON(input []byte) error {
+	var dec transactionConditionalJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	*c = TransactionConditional{
+		BlockNumberMin: (*big.Int)(dec.BlockNumberMin),
+		BlockNumberMax: (*big.Int)(dec.BlockNumberMax),
+		TimestampMin:   (*uint64)(dec.TimestampMin),
+		TimestampMax:   (*uint64)(dec.TimestampMax),
+		KnownAccounts:  dec.KnownAccounts,
+	}
+	return nil
+}
+
+// Cost returns the number of preconditions.
+func (c *TransactionConditional) Cost() int {
+	cost := 0
+	for _, bound := range []bool{c.BlockNumberMin != nil, c.BlockNumberMax != nil, c.TimestampMin != nil, c.TimestampMax != nil} {
+		if bound {
+			cost++
+		}
+	}
+	for _, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			cost++
+		} else {
+			cost += len(account.StorageSlots)
+		}
+	}
+	return cost
+}
+
+// Validate checks that the preconditions are well formed and not too costly.
+func (c *TransactionConditional) Validate() error {
+	if cost := c.Cost(); cost > MaxConditionalCost {
+		return fmt.Errorf("%w: have %d, max %d", ErrConditionalTooCostly, cost, MaxConditionalCost)
+	}
+	if c.BlockNumberMin != nil && c.BlockNumberMax != nil && c.BlockNumberMin.Cmp(c.BlockNumberMax) > 0 {
+		return fmt.Errorf("block number range [%v, %v] is empty", c.BlockNumberMin, c.BlockNumberMax)
+	}
+	if c.TimestampMin != nil && c.TimestampMax != nil && *c.TimestampMin > *c.TimestampMax {
+		return fmt.Errorf("timestamp range [%d, %d] is empty", *c.TimestampMin, *c.TimestampMax)
+	}
+	return nil
+}
+
+// CheckBlock returns an error if a block with the given number and timestamp is
+// out of the allowed ranges.
+func (c *TransactionConditional) CheckBlock(number *big.Int, time uint64) error {
+	if c.BlockNumberMin != nil && number.Cmp(c.BlockNumberMin) < 0 {
+		return fmt.Errorf("%w: block number %v below minimum %v", ErrConditionalBlock, number, c.BlockNumberMin)
+	}
+	if c.BlockNumberMax != nil && number.Cmp(c.BlockNumberMax) > 0 {
+		return fmt.Errorf("%w: block number %v above maximum %v", ErrConditionalBlock, number, c.BlockNumberMax)
+	}
+	if c.TimestampMin != nil && time < *c.TimestampMin {
+		return fmt.Errorf("%w: timestamp %d below minimum %d", ErrConditionalBlock, time, *c.TimestampMin)
+	}
+	if c.TimestampMax != nil && time > *c.TimestampMax {
+		return fmt.Errorf("%w: timestamp %d above maximum %d", ErrConditionalBlock, time, *c.TimestampMax)
+	}
+	return nil
+}
+
+// Expired returns whether no block following the given head can meet the block
+// number and timestamp ranges any more. Block timestamps are strictly increasing.
+func (c *TransactionConditional) Expired(head *Header) bool {
+	if c.BlockNumberMax != nil && head.Number.Cmp(c.BlockNumberMax) >= 0 {
+		return true
+	}
+	return c.TimestampMax != nil && head.Time >= *c.TimestampMax
+}
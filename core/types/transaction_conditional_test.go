@@ -0,0 +1,106 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTransactionConditionalJSON(t *testing.T) {
+	input := `{
+		"blockNumberMin": "0x10",
+		"timestampMax": "0x64",
+		"knownAccounts": {
+			"0x000000000000000000000000000000000000c0de": "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+			"0x000000000000000000000000000000000000beef": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}
+		}
+	}`
+	var c TransactionConditional
+	if err := json.Unmarshal([]byte(input), &c); err != nil {
+		t.Fatalf("failed to decode preconditions: %v", err)
+	}
+	if c.BlockNumberMin.Uint64() != 16 || c.BlockNumberMax != nil || c.TimestampMin != nil || *c.TimestampMax != 100 {
+		t.Fatalf("block ranges mismatch: %+v", c)
+	}
+	root := c.KnownAccounts[common.HexToAddress("0xc0de")]
+	if root.StorageRoot == nil || *root.StorageRoot != common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421") || root.StorageSlots != nil {
+		t.Fatalf("storage root mismatch: %+v", root)
+	}
+	slots := c.KnownAccounts[common.HexToAddress("0xbeef")]
+	if slots.StorageRoot != nil || slots.StorageSlots[common.HexToHash("0x01")] != common.HexToHash("0x02") {
+		t.Fatalf("storage slots mismatch: %+v", slots)
+	}
+	if cost := c.Cost(); cost != 4 {
+		t.Fatalf("cost mismatch: have %d, want %d", cost, 4)
+	}
+	// Encoding and decoding again yields the same preconditions
+	blob, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("failed to encode preconditions: %v", err)
+	}
+	var dec TransactionConditional
+	if err := json.Unmarshal(blob, &dec); err != nil {
+		t.Fatalf("failed to decode encoded preconditions: %v", err)
+	}
+	if again, _ := json.Marshal(dec); string(again) != string(blob) {
+		t.Fatalf("round trip mismatch:\nhave %s\nwant %s", again, blob)
+	}
+}
+
+func TestTransactionConditionalCheckBlock(t *testing.T) {
+	min, max := uint64(100), uint64(200)
+	c := &TransactionConditional{
+		BlockNumberMin: big.NewInt(10),
+		BlockNumberMax: big.NewInt(20),
+		TimestampMin:   &min,
+		TimestampMax:   &max,
+	}
+	tests := []struct {
+		number  int64
+		time    uint64
+		fail    bool
+		expired bool
+	}{
+		{9, 150, true, false},
+		{10, 150, false, false},
+		{20, 150, false, true},
+		{21, 150, true, true},
+		{15, 99, true, false},
+		{15, 100, false, false},
+		{15, 200, false, true},
+		{15, 201, true, true},
+	}
+	for i, tt := range tests {
+		err := c.CheckBlock(big.NewInt(tt.number), tt.time)
+		if (err != nil) != tt.fail || (err != nil && !errors.Is(err, ErrConditionalBlock)) {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.fail)
+		}
+		if expired := c.Expired(&Header{Number: big.NewInt(tt.number), Time: tt.time}); expired != tt.expired {
+			t.Errorf("test %d: expiry mismatch: have %v, want %v", i, expired, tt.expired)
+		}
+	}
+	if err := (&TransactionConditional{BlockNumberMin: big.NewInt(2), BlockNumberMax: big.NewInt(1)}).Validate(); err == nil {
+		t.Errorf("empty block range accepted")
+	}
+}
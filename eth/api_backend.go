@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/miner"
@@ -141,6 +142,9 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 	// Pending state is only known by the miner
 	if number == rpc.PendingBlockNumber {
 		block, state := b.eth.miner.Pending()
+		if block == nil {
+			return nil, nil, errors.New("pending block not available")
+		}
 		return state, block.Header(), nil
 	}
 	// Otherwise resolve the block number and return its state
@@ -231,9 +235,22 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	// Transactions with preconditions are added as remote ones, so that their
+	// senders don't get exempted from evictions
+	if signedTx.Conditional() != nil {
+		return b.eth.txPool.AddConditional(signedTx)
+	}
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
+func (b *EthAPIBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return b.eth.txPool.AddPrivate(signedTx)
+}
+
+func (b *EthAPIBackend) SendBundle(ctx context.Context, bundle *core.Bundle) error {
+	return b.eth.bundlePool.Add(bundle)
+}
+
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending, err := b.eth.txPool.Pending(false)
 	if err != nil {
@@ -275,8 +292,14 @@ func (b *EthAPIBackend) TxPool() *core.TxPool {
 	return b.eth.TxPool()
 }
 
+// SubscribeNewTxsEvent subscribes to the transactions entering the pool which
+// can be shown to RPC users, leaving out the private ones.
 func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
-	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
+	return b.eth.TxPool().SubscribeShownTxsEvent(ch)
+}
+
+func (b *EthAPIBackend) SubscribeDropTxsEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return b.eth.TxPool().SubscribeDropTxsEvent(ch)
 }
 
 func (b *EthAPIBackend) Downloader() *downloader.Downloader {
@@ -291,10 +314,22 @@ func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount int, lastBloc
 	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
 }
 
+func (b *EthAPIBackend) GasPriceRecommendations(ctx context.Context) (*gasprice.Recommendations, error) {
+	return b.gpo.Recommendations(ctx)
+}
+
 func (b *EthAPIBackend) ChainDb() ethdb.Database {
 	return b.eth.ChainDb()
 }
 
+func (b *EthAPIBackend) TraceIndexer() *core.ChainIndexer {
+	return b.eth.TraceIndexer()
+}
+
+func (b *EthAPIBackend) TraceCache() *tracers.TraceCache {
+	return b.eth.TraceCache()
+}
+
 func (b *EthAPIBackend) EventMux() *event.TypeMux {
 	return b.eth.EventMux()
 }
@@ -319,6 +354,14 @@ func (b *EthAPIBackend) RPCTxFeeCap() float64 {
 	return b.eth.config.RPCTxFeeCap
 }
 
+func (b *EthAPIBackend) FeeIndexStatus() (uint64, uint64) {
+	if b.eth.feeIndexer == nil {
+		return gasprice.FeeIndexSectionSize, 0
+	}
+	sections, _, _ := b.eth.feeIndexer.Sections()
+	return gasprice.FeeIndexSectionSize, sections
+}
+
 func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.eth.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
@@ -44,6 +44,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -68,6 +69,7 @@ type Ethereum struct {
 
 	// Handlers
 	txPool             *core.TxPool
+	bundlePool         *core.BundlePool
 	blockchain         *core.BlockChain
 	handler            *handler
 	ethDialCandidates  enode.Iterator
@@ -82,6 +84,9 @@ type Ethereum struct {
 
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
+	traceIndexer      *core.ChainIndexer             // Trace indexer operating during block imports, if enabled
+	feeIndexer        *core.ChainIndexer             // Fee indexer operating during block imports, if enabled
+	traceCache        *tracers.TraceCache            // Trace result cache, if enabled
 	closeBloomHandler chan struct{}
 
 	APIBackend *EthAPIBackend
@@ -165,6 +170,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
 	}
+	if gpoParams.MinTipCap == nil {
+		gpoParams.MinTipCap = gasprice.ChainMinTipCap(chainConfig)
+	}
 	eth.APIBackend.gpo = gasprice.NewOracle(eth.APIBackend, gpoParams)
 
 	// create eth api and set engine
@@ -222,11 +230,31 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.TraceIndex {
+		eth.traceIndexer = tracers.NewTraceIndexer(eth.APIBackend)
+		eth.traceIndexer.Start(eth.blockchain)
+	}
+	if config.GPO.FeeIndex {
+		eth.feeIndexer = gasprice.NewFeeIndexer(chainDb, chainConfig, config.GPO.FeeIndexGranularity)
+		eth.feeIndexer.Start(eth.blockchain)
+	}
+	if config.TraceCache {
+		eth.traceCache = tracers.NewTraceCache(eth.APIBackend, tracers.TraceCacheConfig{
+			Tracers:  config.TraceCacheTracers,
+			Blocks:   config.TraceCacheBlocks,
+			TxBlocks: config.TraceCacheTxBlocks,
+		})
+		eth.traceCache.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = stack.ResolvePath(config.TxPool.RemoteJournal)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
+	eth.bundlePool = core.NewBundlePool(config.BundlePool, chainConfig, eth.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
@@ -530,6 +558,7 @@ func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 func (s *Ethereum) AccountManager() *accounts.Manager  { return s.accountManager }
 func (s *Ethereum) BlockChain() *core.BlockChain       { return s.blockchain }
 func (s *Ethereum) TxPool() *core.TxPool               { return s.txPool }
+func (s *Ethereum) BundlePool() *core.BundlePool       { return s.bundlePool }
 func (s *Ethereum) EventMux() *event.TypeMux           { return s.eventMux }
 func (s *Ethereum) Engine() consensus.Engine           { return s.engine }
 func (s *Ethereum) ChainDb() ethdb.Database            { return s.chainDb }
@@ -538,6 +567,9 @@ func (s *Ethereum) Downloader() *downloader.Downloader { return s.handler.downlo
 func (s *Ethereum) Synced() bool                       { return atomic.LoadUint32(&s.handler.acceptTxs) == 1 }
 func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
 func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
+func (s *Ethereum) TraceIndexer() *core.ChainIndexer   { return s.traceIndexer }
+func (s *Ethereum) FeeIndexer() *core.ChainIndexer     { return s.feeIndexer }
+func (s *Ethereum) TraceCache() *tracers.TraceCache    { return s.traceCache }
 
 // Protocols returns all the currently configured
 // network protocols to start.
@@ -583,6 +615,15 @@ func (s *Ethereum) Stop() error {
 	// Then stop everything else.
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	if s.traceIndexer != nil {
+		s.traceIndexer.Close()
+	}
+	if s.feeIndexer != nil {
+		s.feeIndexer.Close()
+	}
+	if s.traceCache != nil {
+		s.traceCache.Stop()
+	}
 
 	s.txPool.Stop()
 	s.miner.Close()
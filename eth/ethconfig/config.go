@@ -43,18 +43,24 @@ import (
 
 // FullNodeGPO contains default gasprice oracle settings for full node.
 var FullNodeGPO = gasprice.Config{
-	Blocks:           20,
-	Percentile:       60,
-	MaxHeaderHistory: 1024,
-	MaxBlockHistory:  1024,
-	MaxPrice:         gasprice.DefaultMaxPrice,
-	IgnorePrice:      gasprice.DefaultIgnorePrice,
+	Blocks:              20,
+	Percentile:          60,
+	SlowPercentile:      30,
+	FastPercentile:      90,
+	MaxHeaderHistory:    1024,
+	MaxBlockHistory:     1024,
+	FeeIndexGranularity: gasprice.DefaultFeeIndexGranularity,
+	MaxIndexedHistory:   1 << 19,
+	MaxPrice:            gasprice.DefaultMaxPrice,
+	IgnorePrice:         gasprice.DefaultIgnorePrice,
 }
 
 // LightClientGPO contains default gasprice oracle settings for light client.
 var LightClientGPO = gasprice.Config{
 	Blocks:           2,
 	Percentile:       60,
+	SlowPercentile:   30,
+	FastPercentile:   90,
 	MaxHeaderHistory: 300,
 	MaxBlockHistory:  5,
 	MaxPrice:         gasprice.DefaultMaxPrice,
@@ -85,14 +91,18 @@ var Defaults = Config{
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
 	Miner: miner.Config{
-		GasCeil:  8000000,
-		GasPrice: big.NewInt(params.GWei),
-		Recommit: 3 * time.Second,
+		GasCeil:    8000000,
+		GasPrice:   big.NewInt(params.GWei),
+		Recommit:   3 * time.Second,
+		SlotMargin: 500 * time.Millisecond,
+		FillBudget: 500 * time.Millisecond,
 	},
-	TxPool:      core.DefaultTxPoolConfig,
-	RPCGasCap:   50000000,
-	GPO:         FullNodeGPO,
-	RPCTxFeeCap: 1, // 1 ether
+	TxPool:           core.DefaultTxPoolConfig,
+	BundlePool:       core.DefaultBundlePoolConfig,
+	TraceCacheBlocks: 8192,
+	RPCGasCap:        50000000,
+	GPO:              FullNodeGPO,
+	RPCTxFeeCap:      1, // 1 ether
 }
 
 func init() {
@@ -178,12 +188,26 @@ type Config struct {
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
+	// Bundle pool options
+	BundlePool core.BundlePoolConfig
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables the index of the addresses in the call traces of each block,
+	// backing trace_filter. It requires the historical state to be available.
+	TraceIndex bool `toml:",omitempty"`
+
+	// Trace result cache options. Tracing new blocks in the background, and the
+	// whole block of the transactions traced, are optional.
+	TraceCache         bool     `toml:",omitempty"`
+	TraceCacheTracers  []string `toml:",omitempty"`
+	TraceCacheBlocks   uint64   `toml:",omitempty"`
+	TraceCacheTxBlocks bool     `toml:",omitempty"`
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
@@ -206,6 +230,9 @@ type Config struct {
 	// No heimdall service
 	WithoutHeimdall bool
 
+	// Heimdall client overriding the one connecting to HeimdallURL (dev mode)
+	HeimdallClient bor.IHeimdallClient `toml:"-"`
+
 	// Berlin block override (TODO: remove after the fork)
 	OverrideBerlin *big.Int `toml:",omitempty"`
 	OverrideLondon *big.Int `toml:",omitempty"`
@@ -226,7 +253,11 @@ func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, et
 	}
 	// If Matic bor consensus is requested, set it up
 	if chainConfig.Bor != nil {
-		return bor.New(chainConfig, db, blockchainAPI, ethConfig.HeimdallURL, ethConfig.WithoutHeimdall)
+		engine := bor.New(chainConfig, db, blockchainAPI, ethConfig.HeimdallURL, ethConfig.WithoutHeimdall)
+		if ethConfig.HeimdallClient != nil {
+			engine.SetHeimdallClient(ethConfig.HeimdallClient)
+		}
+		return engine
 	}
 	// Otherwise assume proof-of-work
 	switch config.PowMode {
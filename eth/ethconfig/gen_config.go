@@ -51,9 +51,15 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Miner                   miner.Config
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
+		BundlePool              core.BundlePoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
+		TraceIndex              bool     `toml:",omitempty"`
+		TraceCache              bool     `toml:",omitempty"`
+		TraceCacheTracers       []string `toml:",omitempty"`
+		TraceCacheBlocks        uint64   `toml:",omitempty"`
+		TraceCacheTxBlocks      bool     `toml:",omitempty"`
+		DocRoot                 string   `toml:"-"`
 		RPCGasCap               uint64
 		RPCTxFeeCap             float64
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
@@ -94,8 +100,14 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
+	enc.BundlePool = c.BundlePool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.TraceIndex = c.TraceIndex
+	enc.TraceCache = c.TraceCache
+	enc.TraceCacheTracers = c.TraceCacheTracers
+	enc.TraceCacheBlocks = c.TraceCacheBlocks
+	enc.TraceCacheTxBlocks = c.TraceCacheTxBlocks
 	enc.DocRoot = c.DocRoot
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
@@ -141,9 +153,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Miner                   *miner.Config
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
+		BundlePool              *core.BundlePoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
+		TraceIndex              *bool    `toml:",omitempty"`
+		TraceCache              *bool    `toml:",omitempty"`
+		TraceCacheTracers       []string `toml:",omitempty"`
+		TraceCacheBlocks        *uint64  `toml:",omitempty"`
+		TraceCacheTxBlocks      *bool    `toml:",omitempty"`
+		DocRoot                 *string  `toml:"-"`
 		RPCGasCap               *uint64
 		RPCTxFeeCap             *float64
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
@@ -253,12 +271,30 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.BundlePool != nil {
+		c.BundlePool = *dec.BundlePool
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.TraceIndex != nil {
+		c.TraceIndex = *dec.TraceIndex
+	}
+	if dec.TraceCache != nil {
+		c.TraceCache = *dec.TraceCache
+	}
+	if dec.TraceCacheTracers != nil {
+		c.TraceCacheTracers = dec.TraceCacheTracers
+	}
+	if dec.TraceCacheBlocks != nil {
+		c.TraceCacheBlocks = *dec.TraceCacheBlocks
+	}
+	if dec.TraceCacheTxBlocks != nil {
+		c.TraceCacheTxBlocks = *dec.TraceCacheTxBlocks
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -17,6 +17,7 @@
 package filters
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -28,6 +29,8 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -116,7 +119,7 @@ func (api *PublicFilterAPI) timeoutLoop(timeout time.Duration) {
 // https://eth.wiki/json-rpc/API#eth_newpendingtransactionfilter
 func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	var (
-		pendingTxs   = make(chan []common.Hash)
+		pendingTxs   = make(chan []*types.Transaction)
 		pendingTxSub = api.events.SubscribePendingTxs(pendingTxs)
 	)
 
@@ -127,10 +130,12 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	go func() {
 		for {
 			select {
-			case ph := <-pendingTxs:
+			case pTx := <-pendingTxs:
 				api.filtersMu.Lock()
 				if f, found := api.filters[pendingTxSub.ID]; found {
-					f.hashes = append(f.hashes, ph...)
+					for _, tx := range pTx {
+						f.hashes = append(f.hashes, tx.Hash())
+					}
 				}
 				api.filtersMu.Unlock()
 			case <-pendingTxSub.Err():
@@ -146,32 +151,60 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 }
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
-// enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// enters the transaction pool. By default the transaction hash is sent, the criteria may
+// request full transactions, restrict the transactions notified about, and request
+// notifications for the transactions dropped from the pool.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, crit *PendingTxCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
-
+	if crit == nil {
+		crit = new(PendingTxCriteria)
+	}
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		txHashes := make(chan []common.Hash, 128)
-		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
-
+		var (
+			config       = api.backend.ChainConfig()
+			signer       = types.LatestSigner(config)
+			pendingTxs   = make(chan []*types.Transaction, 128)
+			pendingTxSub = api.events.SubscribePendingTxs(pendingTxs)
+			drops        chan core.TxDropEvent // Never fires unless requested
+		)
+		defer pendingTxSub.Unsubscribe()
+
+		if crit.Dropped {
+			drops = make(chan core.TxDropEvent, 128)
+			dropSub := api.events.SubscribeDroppedTxs(drops)
+			defer dropSub.Unsubscribe()
+		}
 		for {
 			select {
-			case hashes := <-txHashes:
-				// To keep the original behaviour, send a single tx hash in one notification.
-				// TODO(rjl493456442) Send a batch of tx hashes in one notification
-				for _, h := range hashes {
-					notifier.Notify(rpcSub.ID, h)
+			case txs := <-pendingTxs:
+				head := api.backend.CurrentHeader()
+				baseFee := pendingBaseFee(config, head)
+
+				// To keep the original behaviour, send a single tx in one notification.
+				for _, tx := range txs {
+					if !crit.matches(signer, baseFee, tx) {
+						continue
+					}
+					if crit.FullTx {
+						notifier.Notify(rpcSub.ID, newRPCPendingTransaction(tx))
+					} else {
+						notifier.Notify(rpcSub.ID, tx.Hash())
+					}
+				}
+			case drop := <-drops:
+				if !crit.matches(signer, nil, drop.Tx) {
+					continue
 				}
+				notifier.Notify(rpcSub.ID, newRPCDroppedTransaction(drop, signer))
+
 			case <-rpcSub.Err():
-				pendingTxSub.Unsubscribe()
 				return
 			case <-notifier.Closed():
-				pendingTxSub.Unsubscribe()
 				return
 			}
 		}
@@ -180,6 +213,182 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// rpcPendingTransaction is the RPC representation of a pending transaction, as
+// returned by eth_getTransactionByHash.
+type rpcPendingTransaction struct {
+	BlockHash        *common.Hash      `json:"blockHash"`
+	BlockNumber      *hexutil.Big      `json:"blockNumber"`
+	From             common.Address    `json:"from"`
+	Gas              hexutil.Uint64    `json:"gas"`
+	GasPrice         *hexutil.Big      `json:"gasPrice"`
+	GasFeeCap        *hexutil.Big      `json:"maxFeePerGas,omitempty"`
+	GasTipCap        *hexutil.Big      `json:"maxPriorityFeePerGas,omitempty"`
+	Hash             common.Hash       `json:"hash"`
+	Input            hexutil.Bytes     `json:"input"`
+	Nonce            hexutil.Uint64    `json:"nonce"`
+	To               *common.Address   `json:"to"`
+	TransactionIndex *hexutil.Uint64   `json:"transactionIndex"`
+	Value            *hexutil.Big      `json:"value"`
+	Type             hexutil.Uint64    `json:"type"`
+	Accesses         *types.AccessList `json:"accessList,omitempty"`
+	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	V                *hexutil.Big      `json:"v"`
+	R                *hexutil.Big      `json:"r"`
+	S                *hexutil.Big      `json:"s"`
+}
+
+// newRPCPendingTransaction returns a pending transaction that will serialize to
+// the RPC representation.
+func newRPCPendingTransaction(tx *types.Transaction) *rpcPendingTransaction {
+	// Non-protected transactions report a zero chain id, use the homestead signer
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	from, _ := types.Sender(signer, tx)
+	v, r, s := tx.RawSignatureValues()
+	result := &rpcPendingTransaction{
+		Type:     hexutil.Uint64(tx.Type()),
+		From:     from,
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasFeeCap()),
+		Hash:     tx.Hash(),
+		Input:    hexutil.Bytes(tx.Data()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		To:       tx.To(),
+		Value:    (*hexutil.Big)(tx.Value()),
+		V:        (*hexutil.Big)(v),
+		R:        (*hexutil.Big)(r),
+		S:        (*hexutil.Big)(s),
+	}
+	if tx.Type() != types.LegacyTxType {
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		result.GasFeeCap = (*hexutil.Big)(tx.GasFeeCap())
+		result.GasTipCap = (*hexutil.Big)(tx.GasTipCap())
+	}
+	return result
+}
+
+// rpcDroppedTransaction is the notification of a transaction removed from the
+// transaction pool without being included into a block, as sent by
+// txpool_dropped.
+type rpcDroppedTransaction struct {
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	Reason     string         `json:"reason"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+}
+
+// newRPCDroppedTransaction returns a dropped transaction notification that will
+// serialize to the RPC representation.
+func newRPCDroppedTransaction(drop core.TxDropEvent, signer types.Signer) *rpcDroppedTransaction {
+	from, _ := types.Sender(signer, drop.Tx)
+	result := &rpcDroppedTransaction{
+		Hash:   drop.Tx.Hash(),
+		From:   from,
+		Nonce:  hexutil.Uint64(drop.Tx.Nonce()),
+		Reason: string(drop.Reason),
+	}
+	if drop.Replacement != nil {
+		hash := drop.Replacement.Hash()
+		result.ReplacedBy = &hash
+	}
+	return result
+}
+
+// PendingTxCriteria selects the pending transactions to be notified about, and
+// how. Unset filters match all transactions.
+type PendingTxCriteria struct {
+	FullTx   bool             // Send full transactions instead of hashes
+	From     []common.Address // Senders to match
+	To       []common.Address // Recipients to match, contract creations never match
+	Selector []hexutil.Bytes  // Call data prefixes to match, typically method selectors
+	MinTip   *big.Int         // Minimum miner tip at the pending base fee
+	Dropped  bool             // Notify about dropped transactions too
+}
+
+// UnmarshalJSON sets *crit fields with given data. It accepts a single boolean
+// requesting full transactions, or an object.
+func (crit *PendingTxCriteria) UnmarshalJSON(data []byte) error {
+	var fullTx bool
+	if err := json.Unmarshal(data, &fullTx); err == nil {
+		*crit = PendingTxCriteria{FullTx: fullTx}
+		return nil
+	}
+	var input struct {
+		FullTx   bool             `json:"fullTx"`
+		From     []common.Address `json:"from"`
+		To       []common.Address `json:"to"`
+		Selector []hexutil.Bytes  `json:"selector"`
+		MinTip   *hexutil.Big     `json:"minTip"`
+		Dropped  bool             `json:"dropped"`
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	for _, selector := range input.Selector {
+		if len(selector) == 0 {
+			return errors.New("empty call data selector")
+		}
+	}
+	*crit = PendingTxCriteria{
+		FullTx:   input.FullTx,
+		From:     input.From,
+		To:       input.To,
+		Selector: input.Selector,
+		MinTip:   (*big.Int)(input.MinTip),
+		Dropped:  input.Dropped,
+	}
+	return nil
+}
+
+// matches returns whether a transaction passes the filters. The minimum tip is
+// not checked if no base fee is given.
+func (crit *PendingTxCriteria) matches(signer types.Signer, baseFee *big.Int, tx *types.Transaction) bool {
+	if len(crit.From) > 0 {
+		from, err := types.Sender(signer, tx)
+		if err != nil || !includes(crit.From, from) {
+			return false
+		}
+	}
+	if len(crit.To) > 0 && (tx.To() == nil || !includes(crit.To, *tx.To())) {
+		return false
+	}
+	if len(crit.Selector) > 0 {
+		var found bool
+		for _, selector := range crit.Selector {
+			if bytes.HasPrefix(tx.Data(), selector) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if crit.MinTip != nil && baseFee != nil {
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil || tip.Cmp(crit.MinTip) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// pendingBaseFee returns the base fee of the block following the given head,
+// or zero before London.
+func pendingBaseFee(config *params.ChainConfig, head *types.Header) *big.Int {
+	if head == nil || !config.IsLondon(new(big.Int).Add(head.Number, common.Big1)) {
+		return new(big.Int)
+	}
+	return misc.CalcBaseFee(config, head)
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
@@ -183,3 +183,27 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
 }
+
+func TestUnmarshalJSONPendingTxCriteria(t *testing.T) {
+	var full PendingTxCriteria
+	if err := json.Unmarshal([]byte("true"), &full); err != nil {
+		t.Fatal(err)
+	}
+	if !full.FullTx || full.From != nil || full.MinTip != nil {
+		t.Fatalf("boolean criteria mismatch: %+v", full)
+	}
+	var crit PendingTxCriteria
+	vector := `{"to":["0x70c87d191324e6712a591f304b4eedef6ad9bb9d"],"selector":["0xa9059cbb"],"minTip":"0x3b9aca00","dropped":true}`
+	if err := json.Unmarshal([]byte(vector), &crit); err != nil {
+		t.Fatal(err)
+	}
+	if crit.FullTx || len(crit.To) != 1 || crit.To[0] != common.HexToAddress("70c87d191324e6712a591f304b4eedef6ad9bb9d") {
+		t.Fatalf("recipient mismatch: %+v", crit)
+	}
+	if len(crit.Selector) != 1 || crit.Selector[0].String() != "0xa9059cbb" || crit.MinTip.Uint64() != 1000000000 || !crit.Dropped {
+		t.Fatalf("criteria mismatch: %+v", crit)
+	}
+	if err := json.Unmarshal([]byte(`{"selector":["0x"]}`), &crit); err == nil {
+		t.Fatal("empty selector accepted")
+	}
+}
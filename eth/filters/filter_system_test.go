@@ -18,6 +18,8 @@ package filters
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -28,11 +30,13 @@ import (
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
@@ -48,6 +52,7 @@ type testBackend struct {
 	db              ethdb.Database
 	sections        uint64
 	txFeed          event.Feed
+	dropFeed        event.Feed
 	logsFeed        event.Feed
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
@@ -108,10 +113,26 @@ func (b *testBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types
 	return logs, nil
 }
 
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+func (b *testBackend) CurrentHeader() *types.Header {
+	hash := rawdb.ReadHeadBlockHash(b.db)
+	if number := rawdb.ReadHeaderNumber(b.db, hash); number != nil {
+		return rawdb.ReadHeader(b.db, hash, *number)
+	}
+	return nil
+}
+
 func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeDropTxsEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return b.dropFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -174,7 +195,7 @@ func TestBlockSubscription(t *testing.T) {
 	var (
 		db          = rawdb.NewMemoryDatabase()
 		backend     = &testBackend{db: db}
-		api         = NewPublicFilterAPI(backend, false, deadline)
+		api         = NewPublicFilterAPI(backend, false, deadline, false)
 		genesis     = (&core.Genesis{BaseFee: big.NewInt(params.InitialBaseFee)}).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -226,7 +247,7 @@ func TestPendingTxFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, false)
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
@@ -275,13 +296,110 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPendingTxSubscriptionCriteria tests that pending transaction subscriptions
+// only notify about the transactions matching their criteria, in the requested
+// format, and about dropped transactions if requested.
+func TestPendingTxSubscriptionCriteria(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline, false)
+		signer  = types.LatestSigner(params.TestChainConfig)
+
+		key1, _  = crypto.GenerateKey()
+		key2, _  = crypto.GenerateKey()
+		from1    = crypto.PubkeyToAddress(key1.PublicKey)
+		to       = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+		transfer = common.FromHex("0xa9059cbb")
+	)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, tip int64, data []byte) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &to,
+			Gas:      100000,
+			GasPrice: big.NewInt(tip),
+			Data:     data,
+		})
+	}
+	var (
+		match      = newTx(key1, 0, params.GWei, append(transfer, 1))
+		otherFrom  = newTx(key2, 0, params.GWei, transfer)
+		otherData  = newTx(key1, 1, params.GWei, []byte{1, 2, 3, 4})
+		lowTip     = newTx(key1, 2, 1, transfer)
+		replaced   = newTx(key1, 3, params.GWei, transfer)
+		replacedBy = newTx(key1, 3, 2*params.GWei, transfer)
+	)
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatalf("failed to register filter API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	notifications := make(chan json.RawMessage, 16)
+	crit := map[string]interface{}{
+		"fullTx":   true,
+		"from":     []common.Address{from1},
+		"selector": []hexutil.Bytes{transfer},
+		"minTip":   (*hexutil.Big)(big.NewInt(params.GWei)),
+		"dropped":  true,
+	}
+	sub, err := client.EthSubscribe(context.Background(), notifications, "newPendingTransactions", crit)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	time.Sleep(1 * time.Second)
+	backend.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{otherFrom, otherData, lowTip, match}})
+	backend.dropFeed.Send(core.TxDropEvent{Tx: otherFrom, Reason: core.TxDropReplaced})
+	backend.dropFeed.Send(core.TxDropEvent{Tx: replaced, Reason: core.TxDropReplaced, Replacement: replacedBy})
+
+	next := func() json.RawMessage {
+		select {
+		case msg := <-notifications:
+			return msg
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("notification timeout")
+		}
+		return nil
+	}
+	var tx struct {
+		Hash common.Hash    `json:"hash"`
+		From common.Address `json:"from"`
+	}
+	if err := json.Unmarshal(next(), &tx); err != nil {
+		t.Fatalf("failed to decode full transaction: %v", err)
+	}
+	if tx.Hash != match.Hash() || tx.From != from1 {
+		t.Fatalf("transaction mismatch: have %x from %x, want %x from %x", tx.Hash, tx.From, match.Hash(), from1)
+	}
+	var dropped rpcDroppedTransaction
+	if err := json.Unmarshal(next(), &dropped); err != nil {
+		t.Fatalf("failed to decode dropped transaction: %v", err)
+	}
+	if dropped.Hash != replaced.Hash() || dropped.Reason != string(core.TxDropReplaced) || dropped.ReplacedBy == nil || *dropped.ReplacedBy != replacedBy.Hash() {
+		t.Fatalf("dropped transaction mismatch: have %+v, want %x replaced by %x", dropped, replaced.Hash(), replacedBy.Hash())
+	}
+	select {
+	case msg := <-notifications:
+		t.Fatalf("unexpected notification: %s", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, false)
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -325,7 +443,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, false)
 	)
 
 	// different situations where log filter creation should fail.
@@ -347,7 +465,7 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 	var (
 		db        = rawdb.NewMemoryDatabase()
 		backend   = &testBackend{db: db}
-		api       = NewPublicFilterAPI(backend, false, deadline)
+		api       = NewPublicFilterAPI(backend, false, deadline, false)
 		blockHash = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
 	)
 
@@ -372,7 +490,7 @@ func TestLogFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -486,7 +604,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -622,7 +740,7 @@ func TestPendingTxFilterDeadlock(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, timeout)
+		api     = NewPublicFilterAPI(backend, false, timeout, false)
 		done    = make(chan struct{})
 	)
 
@@ -103,33 +103,40 @@ func (oracle *Oracle) processBlock(bf *blockFees, percentiles []float64) {
 		return
 	}
 
-	bf.results.reward = make([]*big.Int, len(percentiles))
 	if len(bf.block.Transactions()) == 0 {
 		// return an all zero row if there are no transactions to gather data from
+		bf.results.reward = make([]*big.Int, len(percentiles))
 		for i := range bf.results.reward {
 			bf.results.reward[i] = new(big.Int)
 		}
 		return
 	}
+	bf.results.reward = blockRewards(bf.block, bf.receipts, percentiles)
+}
 
-	sorter := make(sortGasAndReward, len(bf.block.Transactions()))
-	for i, tx := range bf.block.Transactions() {
-		reward, _ := tx.EffectiveGasTip(bf.block.BaseFee())
-		sorter[i] = txGasAndReward{gasUsed: bf.receipts[i].GasUsed, reward: reward}
+// blockRewards returns the given percentiles of the effective priority fees per
+// gas of the transactions of a non-empty block, weighted by gas used.
+func blockRewards(block *types.Block, receipts types.Receipts, percentiles []float64) []*big.Int {
+	sorter := make(sortGasAndReward, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		reward, _ := tx.EffectiveGasTip(block.BaseFee())
+		sorter[i] = txGasAndReward{gasUsed: receipts[i].GasUsed, reward: reward}
 	}
 	sort.Sort(sorter)
 
 	var txIndex int
 	sumGasUsed := sorter[0].gasUsed
 
+	rewards := make([]*big.Int, len(percentiles))
 	for i, p := range percentiles {
-		thresholdGasUsed := uint64(float64(bf.block.GasUsed()) * p / 100)
-		for sumGasUsed < thresholdGasUsed && txIndex < len(bf.block.Transactions())-1 {
+		thresholdGasUsed := uint64(float64(block.GasUsed()) * p / 100)
+		for sumGasUsed < thresholdGasUsed && txIndex < len(block.Transactions())-1 {
 			txIndex++
 			sumGasUsed += sorter[txIndex].gasUsed
 		}
-		bf.results.reward[i] = sorter[txIndex].reward
+		rewards[i] = sorter[txIndex].reward
 	}
+	return rewards
 }
 
 // resolveBlockRange resolves the specified block range to absolute block numbers while also
@@ -180,7 +187,8 @@ func (oracle *Oracle) resolveBlockRange(ctx context.Context, lastBlock rpc.Block
 // FeeHistory returns data relevant for fee estimation based on the specified range of blocks.
 // The range can be specified either with absolute block numbers or ending with the latest
 // or pending block. Backends may or may not support gathering data from the pending block
-// or blocks older than a certain age (specified in maxHistory). The first block of the
+// or blocks older than a certain age (specified in maxHistory), unless they are covered by
+// the fee index, whose rewards are rounded up to its precomputed percentiles. The first block of the
 // actually processed range is returned to avoid ambiguity when parts of the requested range
 // are not available or when the head has changed during processing this request.
 // Three arrays are returned based on the processed blocks:
@@ -198,9 +206,16 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 	if len(rewardPercentiles) != 0 {
 		maxFeeHistory = oracle.maxBlockHistory
 	}
-	if blocks > maxFeeHistory {
-		log.Warn("Sanitizing fee history length", "requested", blocks, "truncated", maxFeeHistory)
-		blocks = maxFeeHistory
+	// Blocks covered by the fee index are cheap to retrieve, so only the ones
+	// past it are subject to the usual history limits
+	indexDb, indexed := oracle.feeIndex()
+	maxRequest := maxFeeHistory
+	if indexed > 0 && oracle.maxIndexedHistory > maxRequest {
+		maxRequest = oracle.maxIndexedHistory
+	}
+	if blocks > maxRequest {
+		log.Warn("Sanitizing fee history length", "requested", blocks, "truncated", maxRequest)
+		blocks = maxRequest
 	}
 	for i, p := range rewardPercentiles {
 		if p < 0 || p > 100 {
@@ -219,6 +234,10 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 	if err != nil || blocks == 0 {
 		return common.Big0, nil, nil, nil, err
 	}
+	if blocks > maxFeeHistory && lastBlock+1-uint64(maxFeeHistory) > indexed {
+		log.Warn("Sanitizing unindexed fee history length", "requested", blocks, "truncated", maxFeeHistory)
+		blocks = maxFeeHistory
+	}
 	oldestBlock := lastBlock + 1 - uint64(blocks)
 
 	var (
@@ -244,6 +263,8 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLast
 					fees.header = fees.block.Header()
 					oracle.processBlock(fees, rewardPercentiles)
 					results <- fees
+				} else if blockNumber < indexed && oracle.processIndexedBlock(indexDb, fees, rewardPercentiles) {
+					results <- fees
 				} else {
 					cacheKey := struct {
 						number      uint64
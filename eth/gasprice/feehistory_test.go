@@ -22,6 +22,8 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -87,3 +89,88 @@ func TestFeeHistory(t *testing.T) {
 		}
 	}
 }
+
+// indexedTestBackend is a test backend maintaining a fee index.
+type indexedTestBackend struct {
+	*testBackend
+	sections uint64
+}
+
+func (b *indexedTestBackend) ChainDb() ethdb.Database {
+	return b.db
+}
+
+func (b *indexedTestBackend) FeeIndexStatus() (uint64, uint64) {
+	return 8, b.sections
+}
+
+func TestFeeHistoryIndexed(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(16), false)
+
+	// Index all the blocks, only the indexed sections being reported
+	indexer := newFeeIndexer(backend.db, backend.ChainConfig(), 5)
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("Failed to reset fee indexer: %v", err)
+	}
+	for number := uint64(0); number <= testHead; number++ {
+		if err := indexer.Process(context.Background(), backend.chain.GetHeaderByNumber(number)); err != nil {
+			t.Fatalf("Failed to index block #%d: %v", number, err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("Failed to commit fee index: %v", err)
+	}
+	reference := NewOracle(backend, Config{MaxHeaderHistory: 1000, MaxBlockHistory: 1000})
+
+	var cases = []struct {
+		sections   uint64
+		percent    []float64
+		expPercent []float64 // Precomputed percentiles the requested ones round up to
+		expFirst   uint64
+		expCount   int
+	}{
+		{4, nil, nil, 0, 33},
+		{4, []float64{0, 10, 50, 100}, []float64{0, 10, 50, 100}, 0, 33},
+		{4, []float64{0, 12, 47.5, 99.9}, []float64{0, 15, 50, 100}, 0, 33},
+		{3, []float64{0, 10}, []float64{0, 10}, 31, 2}, // Too many blocks past the index
+		{0, []float64{0, 10}, []float64{0, 10}, 31, 2},
+	}
+	for i, c := range cases {
+		oracle := NewOracle(&indexedTestBackend{backend, c.sections}, Config{
+			MaxHeaderHistory:  2,
+			MaxBlockHistory:   2,
+			MaxIndexedHistory: 1000,
+		})
+		first, reward, baseFee, ratio, err := oracle.FeeHistory(context.Background(), 100, rpc.LatestBlockNumber, c.percent)
+		if err != nil {
+			t.Fatalf("Test case %d: failed to retrieve fee history: %v", i, err)
+		}
+		if first.Uint64() != c.expFirst || len(ratio) != c.expCount {
+			t.Fatalf("Test case %d: range mismatch, want %d+%d, got %d+%d", i, c.expFirst, c.expCount, first, len(ratio))
+		}
+		_, expReward, expBaseFee, expRatio, err := reference.FeeHistory(context.Background(), c.expCount, rpc.LatestBlockNumber, c.expPercent)
+		if err != nil {
+			t.Fatalf("Test case %d: failed to retrieve reference fee history: %v", i, err)
+		}
+		if len(reward) != len(expReward) {
+			t.Fatalf("Test case %d: reward array length mismatch, want %d, got %d", i, len(expReward), len(reward))
+		}
+		for j := range reward {
+			for k := range reward[j] {
+				if reward[j][k].Cmp(expReward[j][k]) != 0 {
+					t.Errorf("Test case %d: reward #%d/%d mismatch, want %v, got %v", i, j, k, expReward[j][k], reward[j][k])
+				}
+			}
+		}
+		for j := range baseFee {
+			if baseFee[j].Cmp(expBaseFee[j]) != 0 {
+				t.Errorf("Test case %d: base fee #%d mismatch, want %v, got %v", i, j, expBaseFee[j], baseFee[j])
+			}
+		}
+		for j := range ratio {
+			if ratio[j] != expRatio[j] {
+				t.Errorf("Test case %d: gas used ratio #%d mismatch, want %v, got %v", i, j, expRatio[j], ratio[j])
+			}
+		}
+	}
+}
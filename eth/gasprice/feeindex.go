@@ -0,0 +1,236 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// FeeIndexSectionSize is the number of blocks in a fee index section.
+	FeeIndexSectionSize = 4096
+
+	// feeIndexConfirms is the number of confirmation blocks before a fee index
+	// section is considered final.
+	feeIndexConfirms = 256
+
+	// feeIndexThrottling is the time to wait between processing two consecutive
+	// index sections.
+	feeIndexThrottling = 100 * time.Millisecond
+
+	// DefaultFeeIndexGranularity is the default step, in percent, between the
+	// reward percentiles precomputed by the fee index.
+	DefaultFeeIndexGranularity = 5.0
+)
+
+// feeIndexBackend is implemented by backends maintaining a fee index.
+type feeIndexBackend interface {
+	ChainDb() ethdb.Database
+	FeeIndexStatus() (uint64, uint64) // Section size and number of indexed sections
+}
+
+// feeIndexEntry is the fee history data of a block as stored in the fee index.
+// The rewards are precomputed at evenly spaced percentiles from 0 to 100, their
+// number defining the granularity. Blocks without transactions have none.
+type feeIndexEntry struct {
+	BaseFee  *big.Int
+	GasUsed  uint64
+	GasLimit uint64
+	Rewards  []*big.Int
+}
+
+// FeeIndexer implements a core.ChainIndexer, storing the base fee, gas usage
+// and rewards at fixed percentiles of each canonical block, permitting fee
+// histories to span long ranges of blocks cheaply.
+//
+// Entries are keyed by block number and overwritten when a section is indexed
+// again after a reorg.
+type FeeIndexer struct {
+	config      *params.ChainConfig
+	db          ethdb.Database // database instance to read blocks from and write index data into
+	batch       ethdb.Batch    // batch of index entries of the section being processed
+	percentiles []float64      // reward percentiles precomputed for each block
+}
+
+// NewFeeIndexer returns a chain indexer that generates the fee index of the
+// canonical chain, precomputing the rewards at every given percentage step.
+func NewFeeIndexer(db ethdb.Database, config *params.ChainConfig, granularity float64) *core.ChainIndexer {
+	table := rawdb.NewTable(db, string(rawdb.FeeIndexPrefix))
+	return core.NewChainIndexer(db, table, newFeeIndexer(db, config, granularity), FeeIndexSectionSize, feeIndexConfirms, feeIndexThrottling, "fees")
+}
+
+// newFeeIndexer creates the chain indexer backend of the fee index.
+func newFeeIndexer(db ethdb.Database, config *params.ChainConfig, granularity float64) *FeeIndexer {
+	steps := feeIndexSteps(granularity)
+	percentiles := make([]float64, steps+1)
+	for i := range percentiles {
+		percentiles[i] = float64(i) * 100 / float64(steps)
+	}
+	return &FeeIndexer{
+		config:      config,
+		db:          db,
+		percentiles: percentiles,
+	}
+}
+
+// feeIndexSteps returns the number of percentage steps between 0 and 100 the
+// granularity amounts to.
+func feeIndexSteps(granularity float64) int {
+	if granularity <= 0 || granularity > 100 {
+		log.Warn("Sanitizing invalid fee index granularity", "provided", granularity, "updated", DefaultFeeIndexGranularity)
+		granularity = DefaultFeeIndexGranularity
+	}
+	steps := int(math.Round(100 / granularity))
+	if steps < 1 {
+		steps = 1
+	}
+	return steps
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new fee index section.
+func (f *FeeIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	f.batch = f.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the fee history data of
+// the block of a header into the index.
+func (f *FeeIndexer) Process(ctx context.Context, header *types.Header) error {
+	number, hash := header.Number.Uint64(), header.Hash()
+
+	entry := &feeIndexEntry{
+		BaseFee:  header.BaseFee,
+		GasUsed:  header.GasUsed,
+		GasLimit: header.GasLimit,
+	}
+	if header.GasUsed > 0 {
+		block := rawdb.ReadBlock(f.db, hash, number)
+		if block == nil {
+			return fmt.Errorf("block #%d %x not found", number, hash)
+		}
+		receipts := rawdb.ReadReceipts(f.db, hash, number, f.config)
+		if len(receipts) != len(block.Transactions()) {
+			return fmt.Errorf("receipts of block #%d %x not found", number, hash)
+		}
+		if len(block.Transactions()) > 0 {
+			entry.Rewards = blockRewards(block, receipts, f.percentiles)
+		}
+	}
+	blob, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteFeeIndexEntry(f.batch, number, blob)
+
+	if f.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := f.batch.Write(); err != nil {
+			return err
+		}
+		f.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the remaining entries of
+// the section into the database.
+func (f *FeeIndexer) Commit() error {
+	return f.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (f *FeeIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// feeIndex returns the database of the fee index and the number of blocks from
+// genesis it covers, zero if the backend maintains none.
+func (oracle *Oracle) feeIndex() (ethdb.KeyValueReader, uint64) {
+	backend, ok := oracle.backend.(feeIndexBackend)
+	if !ok {
+		return nil, 0
+	}
+	size, sections := backend.FeeIndexStatus()
+	if sections == 0 {
+		return nil, 0
+	}
+	return backend.ChainDb(), size * sections
+}
+
+// processIndexedBlock fills in the results of a block from its fee index entry,
+// reporting whether one was found. Requested reward percentiles are rounded up
+// to the nearest precomputed one, so the rewards are never underestimated.
+func (oracle *Oracle) processIndexedBlock(db ethdb.KeyValueReader, bf *blockFees, percentiles []float64) bool {
+	blob := rawdb.ReadFeeIndexEntry(db, bf.blockNumber)
+	if len(blob) == 0 {
+		return false
+	}
+	var entry feeIndexEntry
+	if err := rlp.DecodeBytes(blob, &entry); err != nil {
+		log.Error("Invalid fee index entry", "number", bf.blockNumber, "err", err)
+		return false
+	}
+	header := &types.Header{
+		Number:   new(big.Int).SetUint64(bf.blockNumber),
+		GasLimit: entry.GasLimit,
+		GasUsed:  entry.GasUsed,
+		BaseFee:  entry.BaseFee,
+	}
+	chainconfig := oracle.backend.ChainConfig()
+	if !chainconfig.IsLondon(header.Number) {
+		header.BaseFee = nil
+	}
+	if bf.results.baseFee = header.BaseFee; bf.results.baseFee == nil {
+		bf.results.baseFee = new(big.Int)
+	}
+	if chainconfig.IsLondon(big.NewInt(int64(bf.blockNumber + 1))) {
+		bf.results.nextBaseFee = misc.CalcBaseFee(chainconfig, header)
+	} else {
+		bf.results.nextBaseFee = new(big.Int)
+	}
+	bf.results.gasUsedRatio = float64(entry.GasUsed) / float64(entry.GasLimit)
+	if len(percentiles) == 0 {
+		return true
+	}
+	bf.results.reward = make([]*big.Int, len(percentiles))
+	for i, p := range percentiles {
+		if len(entry.Rewards) == 0 {
+			bf.results.reward[i] = new(big.Int)
+			continue
+		}
+		steps := len(entry.Rewards) - 1
+		index := int(math.Ceil(p*float64(steps)/100 - 1e-9))
+		if index > steps {
+			index = steps
+		}
+		bf.results.reward[i] = entry.Rewards[index]
+	}
+	return true
+}
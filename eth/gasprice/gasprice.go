@@ -40,13 +40,19 @@ var (
 )
 
 type Config struct {
-	Blocks           int
-	Percentile       int
-	MaxHeaderHistory int
-	MaxBlockHistory  int
-	Default          *big.Int `toml:",omitempty"`
-	MaxPrice         *big.Int `toml:",omitempty"`
-	IgnorePrice      *big.Int `toml:",omitempty"`
+	Blocks              int
+	Percentile          int
+	SlowPercentile      int // Percentile of the tip recommended for slow inclusion
+	FastPercentile      int // Percentile of the tip recommended for fast inclusion
+	MaxHeaderHistory    int
+	MaxBlockHistory     int
+	FeeIndex            bool     // Whether to index the fee history data of all blocks
+	FeeIndexGranularity float64  // Step between the reward percentiles precomputed by the fee index
+	MaxIndexedHistory   int      // Maximum fee history length served from the fee index
+	Default             *big.Int `toml:",omitempty"`
+	MaxPrice            *big.Int `toml:",omitempty"`
+	IgnorePrice         *big.Int `toml:",omitempty"`
+	MinTipCap           *big.Int `toml:",omitempty"` // Defaults to the minimum tip of the bor config, if any
 }
 
 // OracleBackend includes all necessary background APIs for oracle.
@@ -65,16 +71,30 @@ type Oracle struct {
 	backend     OracleBackend
 	lastHead    common.Hash
 	lastPrice   *big.Int
+	lastTips    []*big.Int // Tips sampled at the last head, sorted
 	maxPrice    *big.Int
 	ignorePrice *big.Int
+	minTipCap   *big.Int
 	cacheLock   sync.RWMutex
 	fetchLock   sync.Mutex
 
 	checkBlocks, percentile           int
+	slowPercentile, fastPercentile    int
 	maxHeaderHistory, maxBlockHistory int
+	maxIndexedHistory                 int
 	historyCache                      *lru.Cache
 }
 
+// ChainMinTipCap returns the minimum tip of the bor config of a chain, flooring
+// the tips of oracles configured without one. The oracle can't look it up by
+// itself since it is created before the chain it serves.
+func ChainMinTipCap(config *params.ChainConfig) *big.Int {
+	if config == nil || config.Bor == nil {
+		return nil
+	}
+	return config.Bor.MinTipCap
+}
+
 // NewOracle returns a new gasprice oracle which can recommend suitable
 // gasprice for newly created transaction.
 func NewOracle(backend OracleBackend, params Config) *Oracle {
@@ -83,14 +103,21 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		blocks = 1
 		log.Warn("Sanitizing invalid gasprice oracle sample blocks", "provided", params.Blocks, "updated", blocks)
 	}
-	percent := params.Percentile
-	if percent < 0 {
-		percent = 0
-		log.Warn("Sanitizing invalid gasprice oracle sample percentile", "provided", params.Percentile, "updated", percent)
+	percent := sanitizePercentile("sample", params.Percentile)
+	slowPercent, fastPercent := percent, percent
+	if params.SlowPercentile != 0 {
+		slowPercent = sanitizePercentile("slow", params.SlowPercentile)
 	}
-	if percent > 100 {
-		percent = 100
-		log.Warn("Sanitizing invalid gasprice oracle sample percentile", "provided", params.Percentile, "updated", percent)
+	if params.FastPercentile != 0 {
+		fastPercent = sanitizePercentile("fast", params.FastPercentile)
+	}
+	if slowPercent > percent {
+		slowPercent = percent
+		log.Warn("Sanitizing invalid gasprice oracle slow percentile", "provided", params.SlowPercentile, "updated", slowPercent)
+	}
+	if fastPercent < percent {
+		fastPercent = percent
+		log.Warn("Sanitizing invalid gasprice oracle fast percentile", "provided", params.FastPercentile, "updated", fastPercent)
 	}
 	maxPrice := params.MaxPrice
 	if maxPrice == nil || maxPrice.Int64() <= 0 {
@@ -104,20 +131,49 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 	} else if ignorePrice.Int64() > 0 {
 		log.Info("Gasprice oracle is ignoring threshold set", "threshold", ignorePrice)
 	}
+	// Tips below the minimum validators accept only get into blocks through the
+	// validators themselves, so they aren't sampled either
+	minTipCap := params.MinTipCap
+	if minTipCap != nil && minTipCap.Sign() > 0 {
+		log.Info("Gasprice oracle is flooring tips", "minimum", minTipCap)
+		if minTipCap.Cmp(ignorePrice) > 0 {
+			ignorePrice = minTipCap
+		}
+	} else {
+		minTipCap = nil
+	}
 
 	cache, _ := lru.New(2048)
 
 	return &Oracle{
-		backend:          backend,
-		lastPrice:        params.Default,
-		maxPrice:         maxPrice,
-		ignorePrice:      ignorePrice,
-		checkBlocks:      blocks,
-		percentile:       percent,
-		maxHeaderHistory: params.MaxHeaderHistory,
-		maxBlockHistory:  params.MaxBlockHistory,
-		historyCache:     cache,
+		backend:           backend,
+		lastPrice:         params.Default,
+		maxPrice:          maxPrice,
+		ignorePrice:       ignorePrice,
+		minTipCap:         minTipCap,
+		checkBlocks:       blocks,
+		percentile:        percent,
+		slowPercentile:    slowPercent,
+		fastPercentile:    fastPercent,
+		maxHeaderHistory:  params.MaxHeaderHistory,
+		maxBlockHistory:   params.MaxBlockHistory,
+		maxIndexedHistory: params.MaxIndexedHistory,
+		historyCache:      cache,
+	}
+}
+
+// sanitizePercentile brings a percentile of the oracle configuration within
+// bounds.
+func sanitizePercentile(name string, percent int) int {
+	if percent < 0 {
+		log.Warn("Sanitizing invalid gasprice oracle "+name+" percentile", "provided", percent, "updated", 0)
+		return 0
+	}
+	if percent > 100 {
+		log.Warn("Sanitizing invalid gasprice oracle "+name+" percentile", "provided", percent, "updated", 100)
+		return 100
 	}
+	return percent
 }
 
 func (oracle *Oracle) ProcessCache() {
@@ -141,25 +197,33 @@ func (oracle *Oracle) ProcessCache() {
 // necessary to add the basefee to the returned number to fall back to the legacy
 // behavior.
 func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
+	_, price, _, err := oracle.sample(ctx)
+	return new(big.Int).Set(price), err
+}
+
+// sample returns the latest header, the tip cap suggested at it and the sorted
+// tips sampled from the recent blocks, sampling them again only once the head
+// changed.
+func (oracle *Oracle) sample(ctx context.Context) (*types.Header, *big.Int, []*big.Int, error) {
 	head, _ := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	headHash := head.Hash()
 
 	// If the latest gasprice is still available, return it.
 	oracle.cacheLock.RLock()
-	lastHead, lastPrice := oracle.lastHead, oracle.lastPrice
+	lastHead, lastPrice, lastTips := oracle.lastHead, oracle.lastPrice, oracle.lastTips
 	oracle.cacheLock.RUnlock()
 	if headHash == lastHead {
-		return new(big.Int).Set(lastPrice), nil
+		return head, lastPrice, lastTips, nil
 	}
 	oracle.fetchLock.Lock()
 	defer oracle.fetchLock.Unlock()
 
 	// Try checking the cache again, maybe the last fetch fetched what we need
 	oracle.cacheLock.RLock()
-	lastHead, lastPrice = oracle.lastHead, oracle.lastPrice
+	lastHead, lastPrice, lastTips = oracle.lastHead, oracle.lastPrice, oracle.lastTips
 	oracle.cacheLock.RUnlock()
 	if headHash == lastHead {
-		return new(big.Int).Set(lastPrice), nil
+		return head, lastPrice, lastTips, nil
 	}
 	var (
 		sent, exp int
@@ -178,7 +242,7 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 		res := <-result
 		if res.err != nil {
 			close(quit)
-			return new(big.Int).Set(lastPrice), res.err
+			return head, lastPrice, nil, res.err
 		}
 		exp--
 		// Nothing returned. There are two special cases here:
@@ -199,20 +263,33 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 		}
 		results = append(results, res.values...)
 	}
-	price := lastPrice
-	if len(results) > 0 {
-		sort.Sort(bigIntArray(results))
-		price = results[(len(results)-1)*oracle.percentile/100]
-	}
-	if price.Cmp(oracle.maxPrice) > 0 {
-		price = new(big.Int).Set(oracle.maxPrice)
-	}
+	sort.Sort(bigIntArray(results))
+	price := oracle.pick(results, oracle.percentile, lastPrice)
+
 	oracle.cacheLock.Lock()
 	oracle.lastHead = headHash
 	oracle.lastPrice = price
+	oracle.lastTips = results
 	oracle.cacheLock.Unlock()
 
-	return new(big.Int).Set(price), nil
+	return head, price, results, nil
+}
+
+// pick returns the given percentile of the sorted tips, or the fallback if there
+// are none, within the price cap and the tip floor.
+func (oracle *Oracle) pick(tips []*big.Int, percentile int, fallback *big.Int) *big.Int {
+	price := fallback
+	if len(tips) > 0 {
+		price = tips[(len(tips)-1)*percentile/100]
+	}
+	if price.Cmp(oracle.maxPrice) > 0 {
+		price = new(big.Int).Set(oracle.maxPrice)
+	}
+	// The floor wins over the cap, validators not accepting lower tips anyway
+	if oracle.minTipCap != nil && price.Cmp(oracle.minTipCap) < 0 {
+		price = new(big.Int).Set(oracle.minTipCap)
+	}
+	return price
 }
 
 type results struct {
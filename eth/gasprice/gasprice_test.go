@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -37,6 +38,7 @@ import (
 const testHead = 32
 
 type testBackend struct {
+	db      ethdb.Database
 	chain   *core.BlockChain
 	pending bool // pending block available
 }
@@ -156,7 +158,7 @@ func newTestBackend(t *testing.T, londonBlock *big.Int, pending bool) *testBacke
 		t.Fatalf("Failed to create local chain, %v", err)
 	}
 	chain.InsertChain(blocks)
-	return &testBackend{chain: chain, pending: pending}
+	return &testBackend{db: diskdb, chain: chain, pending: pending}
 }
 
 func (b *testBackend) CurrentHeader() *types.Header {
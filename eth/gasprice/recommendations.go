@@ -0,0 +1,101 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// BaseFeeForecastBlocks is the number of blocks the base fee is forecast for
+// when recommending fee caps.
+const BaseFeeForecastBlocks = 5
+
+// Recommendation is a fee setting suggested for dynamic fee transactions.
+type Recommendation struct {
+	TipCap *big.Int // Priority fee per gas, the gas price before London
+	FeeCap *big.Int // Maximum fee per gas, covering the forecast base fees
+}
+
+// Recommendations are the fee settings suggested for several speeds of inclusion,
+// along with the base fees they are based on.
+type Recommendations struct {
+	Number          uint64     // Latest block the recommendations are made at
+	BaseFee         *big.Int   // Base fee of the latest block, nil before London
+	BaseFeeForecast []*big.Int // Upper bounds of the base fees of the next blocks
+	MinTipCap       *big.Int   // Minimum tip validators accept, if any
+
+	Slow     Recommendation
+	Standard Recommendation
+	Fast     Recommendation
+}
+
+// ForecastBaseFees returns the base fees of the given number of blocks following
+// the given header. The first is exact, the following are upper bounds reached
+// if the blocks are all full. Nothing is returned before London.
+func ForecastBaseFees(config *params.ChainConfig, parent *types.Header, blocks int) []*big.Int {
+	if !config.IsLondon(new(big.Int).Add(parent.Number, common.Big1)) {
+		return nil
+	}
+	fees := make([]*big.Int, 0, blocks)
+	for len(fees) < blocks {
+		fee := misc.CalcBaseFee(config, parent)
+		fees = append(fees, fee)
+
+		parent = &types.Header{
+			Number:   new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit: parent.GasLimit,
+			GasUsed:  parent.GasLimit,
+			BaseFee:  fee,
+		}
+	}
+	return fees
+}
+
+// Recommendations returns the tip caps at the slow, standard and fast inclusion
+// percentiles of the recent transactions, all floored at the minimum tip
+// validators accept. The fee caps cover the base fees of the next blocks,
+// should they all be full.
+func (oracle *Oracle) Recommendations(ctx context.Context) (*Recommendations, error) {
+	head, price, tips, err := oracle.sample(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recs := &Recommendations{
+		Number:          head.Number.Uint64(),
+		BaseFee:         head.BaseFee,
+		BaseFeeForecast: ForecastBaseFees(oracle.backend.ChainConfig(), head, BaseFeeForecastBlocks),
+		MinTipCap:       oracle.minTipCap,
+	}
+	maxBaseFee := new(big.Int)
+	if n := len(recs.BaseFeeForecast); n > 0 {
+		maxBaseFee = recs.BaseFeeForecast[n-1]
+	}
+	recommend := func(percentile int) Recommendation {
+		tip := new(big.Int).Set(oracle.pick(tips, percentile, price))
+		return Recommendation{TipCap: tip, FeeCap: new(big.Int).Add(tip, maxBaseFee)}
+	}
+	recs.Slow = recommend(oracle.slowPercentile)
+	recs.Standard = recommend(oracle.percentile)
+	recs.Fast = recommend(oracle.fastPercentile)
+	return recs, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestForecastBaseFees(t *testing.T) {
+	config := *params.TestChainConfig
+	config.LondonBlock = big.NewInt(0)
+
+	// A parent at the gas target keeps the base fee, full blocks raise it by 1/8
+	parent := &types.Header{
+		Number:   big.NewInt(10),
+		GasLimit: 30_000_000,
+		GasUsed:  15_000_000,
+		BaseFee:  big.NewInt(100 * params.GWei),
+	}
+	want := []int64{100_000_000_000, 112_500_000_000, 126_562_500_000, 142_382_812_500}
+	got := ForecastBaseFees(&config, parent, len(want))
+	if len(got) != len(want) {
+		t.Fatalf("forecast length mismatch: have %d, want %d", len(got), len(want))
+	}
+	for i, fee := range got {
+		if fee.Int64() != want[i] {
+			t.Errorf("block %d: base fee mismatch: have %v, want %v", i, fee, want[i])
+		}
+	}
+	// Nothing is forecast before London
+	config.LondonBlock = big.NewInt(100)
+	if got := ForecastBaseFees(&config, parent, 4); got != nil {
+		t.Errorf("forecast before London: have %v, want none", got)
+	}
+}
+
+func TestRecommendations(t *testing.T) {
+	config := Config{
+		Blocks:         3,
+		Percentile:     60,
+		SlowPercentile: 30,
+		FastPercentile: 90,
+		Default:        big.NewInt(params.GWei),
+	}
+	var cases = []struct {
+		fork                 *big.Int // London fork number
+		minTip               int64    // Tip floor, in gwei
+		slow, standard, fast int64    // Expected tips, in gwei
+	}{
+		{nil, 0, 28, 30, 31},
+		{big.NewInt(0), 0, 28, 30, 31},
+		{big.NewInt(0), 29, 29, 30, 31}, // Tips below the floor aren't sampled
+		{big.NewInt(0), 40, 40, 40, 40}, // The floor wins over all samples
+	}
+	for i, c := range cases {
+		backend := newTestBackend(t, c.fork, false)
+		config.MinTipCap = big.NewInt(c.minTip * params.GWei)
+		oracle := NewOracle(backend, config)
+
+		recs, err := oracle.Recommendations(context.Background())
+		if err != nil {
+			t.Fatalf("case %d: failed to retrieve recommendations: %v", i, err)
+		}
+		for name, check := range map[string]struct {
+			rec  Recommendation
+			want int64
+		}{
+			"slow":     {recs.Slow, c.slow},
+			"standard": {recs.Standard, c.standard},
+			"fast":     {recs.Fast, c.fast},
+		} {
+			if want := big.NewInt(check.want * params.GWei); check.rec.TipCap.Cmp(want) != 0 {
+				t.Errorf("case %d: %s tip mismatch: have %v, want %v", i, name, check.rec.TipCap, want)
+			}
+			maxBaseFee := new(big.Int)
+			if c.fork != nil {
+				if len(recs.BaseFeeForecast) != BaseFeeForecastBlocks {
+					t.Fatalf("case %d: forecast length mismatch: have %d, want %d", i, len(recs.BaseFeeForecast), BaseFeeForecastBlocks)
+				}
+				maxBaseFee = recs.BaseFeeForecast[BaseFeeForecastBlocks-1]
+			}
+			if want := new(big.Int).Add(check.rec.TipCap, maxBaseFee); check.rec.FeeCap.Cmp(want) != 0 {
+				t.Errorf("case %d: %s fee cap mismatch: have %v, want %v", i, name, check.rec.FeeCap, want)
+			}
+		}
+		// The standard tip is the one suggested
+		tip, err := oracle.SuggestTipCap(context.Background())
+		if err != nil {
+			t.Fatalf("case %d: failed to retrieve recommended gas price: %v", i, err)
+		}
+		if tip.Cmp(recs.Standard.TipCap) != 0 {
+			t.Errorf("case %d: suggested tip mismatch: have %v, want %v", i, tip, recs.Standard.TipCap)
+		}
+	}
+}
+
+func TestChainMinTipCap(t *testing.T) {
+	// Chains without a bor config have no minimum tip
+	if tip := ChainMinTipCap(params.TestChainConfig); tip != nil {
+		t.Fatalf("minimum tip without bor config: have %v, want none", tip)
+	}
+	config := *params.TestChainConfig
+	config.Bor = &params.BorConfig{}
+	if tip := ChainMinTipCap(&config); tip != nil {
+		t.Fatalf("minimum tip without bor minimum: have %v, want none", tip)
+	}
+	// The bor minimum floors the tips of oracles configured without one
+	config.Bor.MinTipCap = big.NewInt(40 * params.GWei)
+	oracle := NewOracle(newTestBackend(t, big.NewInt(0), false), Config{
+		Blocks:     3,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+		MinTipCap:  ChainMinTipCap(&config),
+	})
+	tip, err := oracle.SuggestTipCap(context.Background())
+	if err != nil {
+		t.Fatalf("failed to retrieve recommended gas price: %v", err)
+	}
+	if tip.Cmp(config.Bor.MinTipCap) != 0 {
+		t.Fatalf("suggested tip mismatch: have %v, want %v", tip, config.Bor.MinTipCap)
+	}
+}
@@ -71,6 +71,13 @@ type txPool interface {
 	// SubscribeNewTxsEvent should return an event subscription of
 	// NewTxsEvent and send events to the given channel.
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+
+	// IsPrivate returns whether a transaction must be kept out of the network.
+	IsPrivate(hash common.Hash) bool
+
+	// SubscribePublicTxsEvent should return an event subscription of private
+	// transactions made public, to be broadcast from then on.
+	SubscribePublicTxsEvent(chan<- core.NewTxsEvent) event.Subscription
 }
 
 // handlerConfig is the collection of initialization parameters to create a full
@@ -112,6 +119,8 @@ type handler struct {
 	eventMux      *event.TypeMux
 	txsCh         chan core.NewTxsEvent
 	txsSub        event.Subscription
+	publicTxsCh   chan core.NewTxsEvent
+	publicTxsSub  event.Subscription
 	minedBlockSub *event.TypeMuxSubscription
 
 	whitelist map[uint64]common.Hash
@@ -398,6 +407,8 @@ func (h *handler) Start(maxPeers int) {
 	h.wg.Add(1)
 	h.txsCh = make(chan core.NewTxsEvent, txChanSize)
 	h.txsSub = h.txpool.SubscribeNewTxsEvent(h.txsCh)
+	h.publicTxsCh = make(chan core.NewTxsEvent, txChanSize)
+	h.publicTxsSub = h.txpool.SubscribePublicTxsEvent(h.publicTxsCh)
 	go h.txBroadcastLoop()
 
 	// broadcast mined blocks
@@ -412,6 +423,7 @@ func (h *handler) Start(maxPeers int) {
 
 func (h *handler) Stop() {
 	h.txsSub.Unsubscribe()        // quits txBroadcastLoop
+	h.publicTxsSub.Unsubscribe()  // quits txBroadcastLoop
 	h.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
 
 	// Quit chainSync and txsync64.
@@ -466,6 +478,7 @@ func (h *handler) BroadcastBlock(block *types.Block, propagate bool) {
 // - To a square root of all peers
 // - And, separately, as announcements to all peers which are not known to
 // already have the given transaction.
+// Private transactions are skipped.
 func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	var (
 		annoCount   int // Count of announcements made
@@ -479,6 +492,9 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
+		if h.txpool.IsPrivate(tx.Hash()) {
+			continue
+		}
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
 		// Send the tx unconditionally to a subset of our peers
 		numDirect := int(math.Sqrt(float64(len(peers))))
@@ -524,8 +540,12 @@ func (h *handler) txBroadcastLoop() {
 		select {
 		case event := <-h.txsCh:
 			h.BroadcastTransactions(event.Txs)
+		case event := <-h.publicTxsCh:
+			h.BroadcastTransactions(event.Txs)
 		case <-h.txsSub.Err():
 			return
+		case <-h.publicTxsSub.Err():
+			return
 		}
 	}
 }
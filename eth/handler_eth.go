@@ -38,7 +38,20 @@ type ethHandler handler
 
 func (h *ethHandler) Chain() *core.BlockChain     { return h.chain }
 func (h *ethHandler) StateBloom() *trie.SyncBloom { return h.stateBloom }
-func (h *ethHandler) TxPool() eth.TxPool          { return h.txpool }
+func (h *ethHandler) TxPool() eth.TxPool          { return publicTxPool{h.txpool} }
+
+// publicTxPool hides the private transactions of the pool from the eth protocol,
+// which serves pooled transactions to remote peers.
+type publicTxPool struct {
+	txPool
+}
+
+func (p publicTxPool) Get(hash common.Hash) *types.Transaction {
+	if p.IsPrivate(hash) {
+		return nil
+	}
+	return p.txPool.Get(hash)
+}
 
 // RunPeer is invoked when a peer joins on the `eth` protocol.
 func (h *ethHandler) RunPeer(peer *eth.Peer, hand eth.Handler) error {
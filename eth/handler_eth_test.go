@@ -446,6 +446,72 @@ func testTransactionPropagation(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that private transactions are not propagated to peers until they are
+// made public.
+func TestPrivateTransactionPropagation66(t *testing.T) {
+	testPrivateTransactionPropagation(t, eth.ETH66)
+}
+
+func testPrivateTransactionPropagation(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	source := newTestHandler()
+	defer source.close()
+
+	sink := newTestHandler()
+	defer sink.close()
+	sink.handler.acceptTxs = 1 // mark synced to accept transactions
+
+	sourcePipe, sinkPipe := p2p.MsgPipe()
+	defer sourcePipe.Close()
+	defer sinkPipe.Close()
+
+	sourcePeer := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{1}, "", nil, sourcePipe), sourcePipe, source.txpool)
+	sinkPeer := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{0}, "", nil, sinkPipe), sinkPipe, sink.txpool)
+	defer sourcePeer.Close()
+	defer sinkPeer.Close()
+
+	go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(source.handler), peer)
+	})
+	go sink.handler.runEthPeer(sinkPeer, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(sink.handler), peer)
+	})
+	txCh := make(chan core.NewTxsEvent, 16)
+	sub := sink.txpool.SubscribeNewTxsEvent(txCh)
+	defer sub.Unsubscribe()
+
+	waitFor := func(tx *types.Transaction) {
+		for {
+			select {
+			case event := <-txCh:
+				for _, have := range event.Txs {
+					if have.Hash() == tx.Hash() {
+						return
+					}
+				}
+			case <-time.NewTimer(time.Second).C:
+				t.Fatalf("transaction %x propagation timed out", tx.Hash())
+			}
+		}
+	}
+	// Add a private transaction followed by a public one, the latter arriving
+	// means the former was skipped
+	private, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil), types.HomesteadSigner{}, testKey)
+	public, _ := types.SignTx(types.NewTransaction(1, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil), types.HomesteadSigner{}, testKey)
+
+	source.txpool.AddPrivate(private)
+	source.txpool.AddRemotes([]*types.Transaction{public})
+	waitFor(public)
+
+	if sink.txpool.Has(private.Hash()) {
+		t.Fatalf("private transaction propagated")
+	}
+	// Publish the private transaction and ensure it's propagated
+	source.txpool.Publish(private)
+	waitFor(private)
+}
+
 // Tests that post eth protocol handshake, clients perform a mutual checkpoint
 // challenge to validate each other's chains. Hash mismatches, or missing ones
 // during a fast sync should lead to the peer getting dropped.
@@ -46,16 +46,19 @@ var (
 // Its goal is to get around setting up a valid statedb for the balance and nonce
 // checks.
 type testTxPool struct {
-	pool map[common.Hash]*types.Transaction // Hash map of collected transactions
+	pool    map[common.Hash]*types.Transaction // Hash map of collected transactions
+	private map[common.Hash]bool               // Set of transactions to keep out of the network
 
-	txFeed event.Feed   // Notification feed to allow waiting for inclusion
-	lock   sync.RWMutex // Protects the transaction pool
+	txFeed     event.Feed   // Notification feed to allow waiting for inclusion
+	publicFeed event.Feed   // Notification feed of private transactions made public
+	lock       sync.RWMutex // Protects the transaction pool
 }
 
 // newTestTxPool creates a mock transaction pool.
 func newTestTxPool() *testTxPool {
 	return &testTxPool{
-		pool: make(map[common.Hash]*types.Transaction),
+		pool:    make(map[common.Hash]*types.Transaction),
+		private: make(map[common.Hash]bool),
 	}
 }
 
@@ -90,6 +93,34 @@ func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
 	return make([]error, len(txs))
 }
 
+// AddPrivate adds a transaction to the pool, to be kept out of the network until
+// published.
+func (p *testTxPool) AddPrivate(tx *types.Transaction) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pool[tx.Hash()] = tx
+	p.private[tx.Hash()] = true
+	p.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx}})
+}
+
+// Publish makes a private transaction public.
+func (p *testTxPool) Publish(tx *types.Transaction) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.private, tx.Hash())
+	p.publicFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx}})
+}
+
+// IsPrivate returns whether a transaction must be kept out of the network.
+func (p *testTxPool) IsPrivate(hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.private[hash]
+}
+
 // Pending returns all the transactions known to the pool
 func (p *testTxPool) Pending(enforceTips bool) (map[common.Address]types.Transactions, error) {
 	p.lock.RLock()
@@ -112,6 +143,12 @@ func (p *testTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subs
 	return p.txFeed.Subscribe(ch)
 }
 
+// SubscribePublicTxsEvent should return an event subscription of private
+// transactions made public.
+func (p *testTxPool) SubscribePublicTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.publicFeed.Subscribe(ch)
+}
+
 // testHandler is a live implementation of the Ethereum protocol handler, just
 // preinitialized with some sane testing defaults and the transaction pool mocked
 // out.
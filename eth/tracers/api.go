@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -111,6 +112,14 @@ func (api *API) chainContext(ctx context.Context) core.ChainContext {
 	return &chainContext{api: api, ctx: ctx}
 }
 
+// traceCache returns the trace result cache of the backend, if enabled.
+func (api *API) traceCache() *TraceCache {
+	if backend, ok := api.backend.(traceCacheBackend); ok {
+		return backend.TraceCache()
+	}
+	return nil
+}
+
 // blockByNumber is the wrapper of the chain access function offered by the backend.
 // It will return an error if the block is not found.
 func (api *API) blockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
@@ -156,19 +165,22 @@ func (api *API) blockByNumberAndHash(ctx context.Context, number rpc.BlockNumber
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*vm.LogConfig
-	Tracer  *string
-	Timeout *string
-	Reexec  *uint64
+	Tracer       *string
+	TracerConfig json.RawMessage // Options of the native tracers, such as the prestate diff mode
+	Timeout      *string
+	Reexec       *uint64
 }
 
-// TraceCallConfig is the config for traceCall API. It holds one more
-// field to override the state for tracing.
+// TraceCallConfig is the config for traceCall API. It holds two more
+// fields to override the state and the block context for tracing.
 type TraceCallConfig struct {
 	*vm.LogConfig
 	Tracer         *string
+	TracerConfig   json.RawMessage
 	Timeout        *string
 	Reexec         *uint64
 	StateOverrides *ethapi.StateOverride
+	BlockOverrides *ethapi.BlockOverrides
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -483,6 +495,13 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if block.NumberU64() == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
+	// Serve the results from the cache if the block was already traced
+	cache := api.traceCache()
+	if cache != nil {
+		if results, ok := cache.get(block, config); ok {
+			return results, nil
+		}
+	}
 	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
 	if err != nil {
 		return nil, err
@@ -495,7 +514,28 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if err != nil {
 		return nil, err
 	}
-	// Execute all the transaction contained within the block concurrently
+	// Trace the transactions concurrently on top of their own pre-state snapshots,
+	// unless there is nothing to parallelise
+	var results []*txTraceResult
+	if threads := runtime.NumCPU(); threads > 1 && len(block.Transactions()) > 1 {
+		results, err = api.traceBlockParallel(ctx, block, statedb, config, threads)
+	} else {
+		results, err = api.traceBlockSequential(ctx, block, statedb, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		cache.put(block, config, results)
+	}
+	return results, nil
+}
+
+// traceBlockParallel traces the transactions of a block on top of the given
+// parent state concurrently. The pre-state of each transaction is checkpointed
+// by copying the state as the block is executed without tracing, so that each
+// transaction is traced on its own snapshot as soon as it is available.
+func (api *API) traceBlockParallel(ctx context.Context, block *types.Block, statedb *state.StateDB, config *TraceConfig, threads int) ([]*txTraceResult, error) {
 	var (
 		signer  = types.MakeSigner(api.backend.ChainConfig(), block.Number())
 		txs     = block.Transactions()
@@ -504,7 +544,6 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 		pend = new(sync.WaitGroup)
 		jobs = make(chan *txTraceTask, len(txs))
 	)
-	threads := runtime.NumCPU()
 	if threads > len(txs) {
 		threads = len(txs)
 	}
@@ -534,6 +573,11 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	// Feed the transactions into the tracers and return
 	var failed error
 	for i, tx := range txs {
+		// Abort feeding if the request was cancelled, the tracers will fail anyway
+		if err := ctx.Err(); err != nil {
+			failed = err
+			break
+		}
 		// Send the trace task over for execution
 		jobs <- &txTraceTask{statedb: statedb.Copy(), index: i}
 
@@ -559,6 +603,46 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	return results, nil
 }
 
+// traceBlockSequential traces the transactions of a block on top of the given
+// parent state one after the other, without copying the state. A transaction
+// failing to be traced is executed again without tracing, yielding the same
+// results as traceBlockParallel.
+func (api *API) traceBlockSequential(ctx context.Context, block *types.Block, statedb *state.StateDB, config *TraceConfig) ([]*txTraceResult, error) {
+	var (
+		signer    = types.MakeSigner(api.backend.ChainConfig(), block.Number())
+		txs       = block.Transactions()
+		results   = make([]*txTraceResult, len(txs))
+		blockCtx  = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		blockHash = block.Hash()
+		eip158    = api.backend.ChainConfig().IsEIP158(block.Number())
+	)
+	for i, tx := range txs {
+		msg, _ := tx.AsMessage(signer, block.BaseFee())
+		txctx := &Context{
+			BlockHash: blockHash,
+			TxIndex:   i,
+			TxHash:    tx.Hash(),
+		}
+		snapshot := statedb.Snapshot()
+		res, err := api.traceTx(ctx, msg, txctx, blockCtx, statedb, config)
+		if err != nil {
+			results[i] = &txTraceResult{Error: err.Error()}
+
+			// Tracing may have been aborted midway, redo the transaction
+			statedb.RevertToSnapshot(snapshot)
+			vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, api.backend.ChainConfig(), vm.Config{})
+			if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+				return nil, err
+			}
+		} else {
+			results[i] = &txTraceResult{Result: res}
+		}
+		// Finalize the state so any modifications are written to the trie
+		statedb.Finalise(eip158)
+	}
+	return results, nil
+}
+
 // standardTraceBlockToFile configures a new tracer which uses standard JSON output,
 // and traces either a full block or an individual transaction. The return value will
 // be one filename per transaction traced.
@@ -717,6 +801,26 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 	if err != nil {
 		return nil, err
 	}
+	// Serve the result from the cache if the block was already traced. Otherwise
+	// trace the whole block if enabled, so that the following requests for any
+	// of its transactions are served from the cache.
+	if cache := api.traceCache(); cache != nil {
+		results, ok := cache.get(block, config)
+		if !ok && cache.config.TxBlocks {
+			if _, cacheable := traceConfigHash(config); cacheable {
+				if results, err = api.traceBlock(ctx, block, config); err != nil {
+					return nil, err
+				}
+				ok = true
+			}
+		}
+		if ok {
+			if results[index].Error != "" {
+				return nil, errors.New(results[index].Error)
+			}
+			return results[index].Result, nil
+		}
+	}
 	msg, vmctx, statedb, err := api.backend.StateAtTransaction(ctx, block, int(index), reexec)
 	if err != nil {
 		return nil, err
@@ -734,6 +838,48 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 // top of the provided block and returns them as a JSON object.
 // You can provide -2 as a block number to trace on top of the pending block.
 func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	statedb, vmctx, traceConfig, err := api.callEnv(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	// Execute the trace
+	msg, err := args.ToMessage(api.backend.RPCGasCap(), vmctx.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+}
+
+// TraceCallMany lets you trace a sequence of eth_calls, executed one after the
+// other on top of the provided block, each call seeing the state changes of the
+// previous ones. The state and block overrides apply to the whole sequence. The
+// return value is one trace per call.
+func (api *API) TraceCallMany(ctx context.Context, args []ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) ([]interface{}, error) {
+	statedb, vmctx, traceConfig, err := api.callEnv(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	// Execute the traces, finalizing the state after each call
+	results := make([]interface{}, len(args))
+	for i, call := range args {
+		msg, err := call.ToMessage(api.backend.RPCGasCap(), vmctx.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		res, err := api.traceTx(ctx, msg, &Context{TxIndex: i}, vmctx, statedb, traceConfig)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		statedb.Finalise(api.backend.ChainConfig().IsEIP158(vmctx.BlockNumber))
+		results[i] = res
+	}
+	return results, nil
+}
+
+// callEnv assembles the environment to trace calls in on top of the provided
+// block: its state and block context, with the configured overrides applied,
+// and the trace config.
+func (api *API) callEnv(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (*state.StateDB, vm.BlockContext, *TraceConfig, error) {
 	// Try to retrieve the specified block
 	var (
 		err   error
@@ -744,10 +890,10 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	} else if number, ok := blockNrOrHash.Number(); ok {
 		block, err = api.blockByNumber(ctx, number)
 	} else {
-		return nil, errors.New("invalid arguments; neither block nor hash specified")
+		return nil, vm.BlockContext{}, nil, errors.New("invalid arguments; neither block nor hash specified")
 	}
 	if err != nil {
-		return nil, err
+		return nil, vm.BlockContext{}, nil, err
 	}
 	// try to recompute the state
 	reexec := defaultTraceReexec
@@ -756,38 +902,33 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	}
 	statedb, err := api.backend.StateAtBlock(ctx, block, reexec, nil, true)
 	if err != nil {
-		return nil, err
+		return nil, vm.BlockContext{}, nil, err
 	}
-	// Apply the customized state rules if required.
-	if config != nil {
-		if err := config.StateOverrides.Apply(statedb); err != nil {
-			return nil, err
-		}
+	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+	if config == nil {
+		return statedb, vmctx, nil, nil
 	}
-	// Execute the trace
-	msg, err := args.ToMessage(api.backend.RPCGasCap(), block.BaseFee())
-	if err != nil {
-		return nil, err
+	// Apply the customized state and block context rules if required.
+	if err := config.StateOverrides.Apply(statedb); err != nil {
+		return nil, vm.BlockContext{}, nil, err
 	}
-	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+	config.BlockOverrides.Apply(&vmctx)
 
-	var traceConfig *TraceConfig
-	if config != nil {
-		traceConfig = &TraceConfig{
-			LogConfig: config.LogConfig,
-			Tracer:    config.Tracer,
-			Timeout:   config.Timeout,
-			Reexec:    config.Reexec,
-		}
+	traceConfig := &TraceConfig{
+		LogConfig:    config.LogConfig,
+		Tracer:       config.Tracer,
+		TracerConfig: config.TracerConfig,
+		Timeout:      config.Timeout,
+		Reexec:       config.Reexec,
 	}
-	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	return statedb, vmctx, traceConfig, nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger or the native or JavaScript tracer
 	var (
 		tracer    vm.Tracer
 		err       error
@@ -802,16 +943,19 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 				return nil, err
 			}
 		}
-		// Construct the JavaScript tracer to execute with
-		if tracer, err = New(*config.Tracer, txctx); err != nil {
+		// Construct the native or JavaScript tracer to execute with
+		named, err := newTracer(*config.Tracer, txctx, config.TracerConfig)
+		if err != nil {
 			return nil, err
 		}
+		tracer = named
+
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
 			if deadlineCtx.Err() == context.DeadlineExceeded {
-				tracer.(*Tracer).Stop(errors.New("execution timeout"))
+				named.Stop(errors.New("execution timeout"))
 			}
 		}()
 		defer cancel()
@@ -828,6 +972,9 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	// Call Prepare to clear out the statedb access list
 	statedb.Prepare(txctx.TxHash, txctx.TxIndex)
 
+	if tracer, ok := tracer.(txStartTracer); ok {
+		tracer.CaptureTxStart(message.Gas())
+	}
 	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %w", err)
@@ -848,7 +995,7 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *Tracer:
+	case txTracer:
 		return tracer.GetResult()
 
 	default:
@@ -866,5 +1013,11 @@ func APIs(backend Backend) []rpc.API {
 			Service:   NewAPI(backend),
 			Public:    false,
 		},
+		{
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewTraceAPI(backend),
+			Public:    false,
+		},
 	}
 }
@@ -591,6 +591,175 @@ func TestTraceBlock(t *testing.T) {
 	}
 }
 
+// Tests that tracing the transactions of a block concurrently yields the same
+// results as tracing them one after the other, with each transaction depending
+// on the state left by the previous ones.
+func TestTraceBlockParallel(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts and a contract incrementing a counter on each call
+	accounts := newAccounts(3)
+	counter := common.HexToAddress("0xc0ffee")
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+		accounts[2].addr: {Balance: big.NewInt(params.Ether)},
+		counter:          {Balance: big.NewInt(0), Code: common.FromHex("0x60005460010160005500")},
+	}}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		for j := 0; j < 8; j++ {
+			from := accounts[j%len(accounts)]
+			nonce := b.TxNonce(from.addr)
+
+			var tx *types.Transaction
+			if j%2 == 0 {
+				tx = types.NewTransaction(nonce, counter, big.NewInt(int64(j)), 100000, big.NewInt(1), nil)
+			} else {
+				tx = types.NewTransaction(nonce, accounts[(j+1)%len(accounts)].addr, big.NewInt(1000), params.TxGas, big.NewInt(1), nil)
+			}
+			tx, _ = types.SignTx(tx, signer, from.key)
+			b.AddTx(tx)
+		}
+	})
+	api := NewAPI(backend)
+
+	var (
+		callTracer     = "callTracer"
+		prestateTracer = "prestateTracer"
+		fourByteTracer = "4byteTracer"
+		unigramTracer  = "unigramTracer"
+	)
+	configs := []*TraceConfig{
+		nil,
+		{Tracer: &callTracer},
+		{Tracer: &prestateTracer},
+		{Tracer: &prestateTracer, TracerConfig: json.RawMessage(`{"diffMode":true}`)},
+		{Tracer: &fourByteTracer},
+		{Tracer: &unigramTracer},
+	}
+	for _, number := range []rpc.BlockNumber{1, 2} {
+		block, err := api.blockByNumber(context.Background(), number)
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve block: %v", number, err)
+		}
+		parent, err := api.blockByNumber(context.Background(), number-1)
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve parent: %v", number, err)
+		}
+		for i, config := range configs {
+			var results [2][]*txTraceResult
+			for j, threads := range []int{0, 4} {
+				statedb, err := backend.StateAtBlock(context.Background(), parent, 0, nil, true)
+				if err != nil {
+					t.Fatalf("block %d, config %d: failed to retrieve state: %v", number, i, err)
+				}
+				if threads == 0 {
+					results[j], err = api.traceBlockSequential(context.Background(), block, statedb, config)
+				} else {
+					results[j], err = api.traceBlockParallel(context.Background(), block, statedb, config, threads)
+				}
+				if err != nil {
+					t.Fatalf("block %d, config %d, threads %d: failed to trace block: %v", number, i, threads, err)
+				}
+			}
+			sequential, parallel := normalizeTraceResults(t, results[0]), normalizeTraceResults(t, results[1])
+			if len(sequential) != len(block.Transactions()) {
+				t.Fatalf("block %d, config %d: result count mismatch: have %d, want %d", number, i, len(sequential), len(block.Transactions()))
+			}
+			for k := range sequential {
+				if sequential[k].Error != "" {
+					t.Errorf("block %d, config %d, tx %d: tracing failed: %v", number, i, k, sequential[k].Error)
+				}
+			}
+			if !reflect.DeepEqual(sequential, parallel) {
+				t.Errorf("block %d, config %d: result mismatch:\nsequential %+v\nparallel   %+v", number, i, sequential, parallel)
+			}
+		}
+	}
+}
+
+// Tests that a sequence of calls is traced on top of an evolving state, and that
+// the block context can be overridden.
+func TestTraceCallMany(t *testing.T) {
+	t.Parallel()
+
+	// Initialize a contract incrementing a counter and one returning the number
+	accounts := newAccounts(1)
+	var (
+		counter = common.HexToAddress("0xc0ffee")
+		number  = common.HexToAddress("0xbeef")
+	)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		counter:          {Balance: big.NewInt(0), Code: common.FromHex("0x60005460010160005500")},
+		number:           {Balance: big.NewInt(0), Code: common.FromHex("0x4360005260206000f3")},
+	}}
+	api := NewAPI(newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {}))
+
+	// Each call must see the counter incremented by the previous ones
+	prestateTracer := "prestateTracer"
+	calls := []ethapi.TransactionArgs{{From: &accounts[0].addr, To: &counter}, {From: &accounts[0].addr, To: &counter}, {From: &accounts[0].addr, To: &counter}}
+	results, err := api.TraceCallMany(context.Background(), calls, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &TraceCallConfig{Tracer: &prestateTracer})
+	if err != nil {
+		t.Fatalf("failed to trace calls: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(calls))
+	}
+	for i, result := range results {
+		var prestate map[common.Address]*prestateAccount
+		if err := json.Unmarshal(result.(json.RawMessage), &prestate); err != nil {
+			t.Fatalf("call %d: failed to unmarshal trace: %v", i, err)
+		}
+		if have, want := prestate[counter].Storage[common.Hash{}], common.BigToHash(big.NewInt(int64(i))); have != want {
+			t.Errorf("call %d: counter mismatch: have %x, want %x", i, have, want)
+		}
+	}
+	// The block overrides must be visible to the calls
+	callTracer := "callTracer"
+	config := &TraceCallConfig{
+		Tracer:         &callTracer,
+		BlockOverrides: &ethapi.BlockOverrides{Number: (*hexutil.Big)(big.NewInt(1000))},
+	}
+	result, err := api.TraceCall(context.Background(), ethapi.TransactionArgs{From: &accounts[0].addr, To: &number}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	var frame callFrame
+	if err := json.Unmarshal(result.(json.RawMessage), &frame); err != nil {
+		t.Fatalf("failed to unmarshal trace: %v", err)
+	}
+	if want := hexutil.Encode(common.BigToHash(big.NewInt(1000)).Bytes()); frame.Output != want {
+		t.Errorf("overridden number mismatch: have %s, want %s", frame.Output, want)
+	}
+}
+
+// normalizeTraceResults converts trace results into their generic JSON form,
+// dropping the execution time reported by the call tracer.
+func normalizeTraceResults(t *testing.T, results []*txTraceResult) []*struct {
+	Result interface{} `json:"result"`
+	Error  string      `json:"error"`
+} {
+	blob, err := json.Marshal(results)
+	if err != nil {
+		t.Fatalf("failed to marshal trace results: %v", err)
+	}
+	var normalized []*struct {
+		Result interface{} `json:"result"`
+		Error  string      `json:"error"`
+	}
+	if err := json.Unmarshal(blob, &normalized); err != nil {
+		t.Fatalf("failed to unmarshal trace results: %v", err)
+	}
+	for _, result := range normalized {
+		if res, ok := result.Result.(map[string]interface{}); ok {
+			delete(res, "time")
+		}
+	}
+	return normalized
+}
+
 type Account struct {
 	key  *ecdsa.PrivateKey
 	addr common.Address
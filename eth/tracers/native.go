@@ -0,0 +1,145 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
+)
+
+// txTracer is a tracer selectable by name through the tracing API, returning
+// its own JSON result once the transaction was executed.
+type txTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON result of the trace.
+	GetResult() (json.RawMessage, error)
+
+	// Stop aborts the tracing with the given reason, returned by GetResult.
+	Stop(err error)
+}
+
+// txStartTracer is a tracer needing the gas limit of the traced transaction,
+// which is reported before the transaction is executed.
+type txStartTracer interface {
+	CaptureTxStart(gasLimit uint64)
+}
+
+// natives contains the tracers implemented in Go by name. They produce the same
+// results as the JavaScript tracers of the same name, which they shadow.
+var natives = map[string]func(config json.RawMessage) (txTracer, error){
+	"callTracer":     newCallTracer,
+	"prestateTracer": newPrestateTracer,
+	"4byteTracer":    newFourByteTracer,
+}
+
+// builtins contains the tracers implemented in Go by name, which have no
+// JavaScript counterpart.
+var builtins = map[string]func(config json.RawMessage) (txTracer, error){
+	"gasProfiler": newGasProfiler,
+}
+
+// newTracer creates the tracer with the given name, or from the given JavaScript
+// code. Named tracers with a native implementation, and the builtin ones, are run
+// natively, with the given options.
+func newTracer(code string, ctx *Context, config json.RawMessage) (txTracer, error) {
+	if constructor, ok := natives[code]; ok {
+		return constructor(config)
+	}
+	if constructor, ok := builtins[code]; ok {
+		return constructor(config)
+	}
+	if len(config) > 0 {
+		return nil, errors.New("tracer config is only supported by native tracers")
+	}
+	return New(code, ctx)
+}
+
+// namedTracer returns whether the tracer is one of the built-in tracers referred
+// to by name, as opposed to JavaScript code.
+func namedTracer(name string) bool {
+	if _, ok := natives[name]; ok {
+		return true
+	}
+	if _, ok := builtins[name]; ok {
+		return true
+	}
+	_, ok := tracer(name)
+	return ok
+}
+
+// interrupter is embedded by the native tracers to abort tracing once stopped.
+type interrupter struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (i *interrupter) Stop(err error) {
+	i.reason = err
+	atomic.StoreUint32(&i.interrupt, 1)
+}
+
+// stopped returns whether the tracer was stopped.
+func (i *interrupter) stopped() bool {
+	return atomic.LoadUint32(&i.interrupt) > 0
+}
+
+// isPrecompiled returns whether the address is one of the precompiles.
+func isPrecompiled(precompiles []common.Address, addr common.Address) bool {
+	for _, p := range precompiles {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// peekStack returns the n-th item from the top of the stack, or zero if the
+// stack is shorter.
+func peekStack(stack *vm.Stack, n int) *uint256.Int {
+	if len(stack.Data()) <= n {
+		return new(uint256.Int)
+	}
+	return stack.Back(n)
+}
+
+// stackUint64 returns a stack item as an offset or size, saturating it if it
+// doesn't fit.
+func stackUint64(item *uint256.Int) uint64 {
+	if !item.IsUint64() {
+		return ^uint64(0)
+	}
+	return item.Uint64()
+}
+
+// memorySlice returns a copy of the memory in [offset, offset+size), or nil if
+// it is out of bounds.
+func memorySlice(mem *vm.Memory, offset, size uint64) []byte {
+	if size == 0 {
+		return []byte{}
+	}
+	if offset+size < offset || uint64(mem.Len()) < offset+size {
+		return nil
+	}
+	return mem.GetCopy(int64(offset), int64(size))
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// fourByteTracer is the native implementation of the 4byteTracer, counting the
+// 4byte method identifiers called along with the size of the supplied data, so
+// a reversed signature can be matched against the size of the data.
+type fourByteTracer struct {
+	interrupter
+
+	ids         map[string]int // Call counts by "<id>-<size>"
+	precompiles []common.Address
+	input       []byte // Input of the outer call
+}
+
+// newFourByteTracer creates a native 4byte tracer. It has no options.
+func newFourByteTracer(config json.RawMessage) (txTracer, error) {
+	return &fourByteTracer{ids: make(map[string]int)}, nil
+}
+
+// store counts a call of the given method identifier with the given data size.
+func (t *fourByteTracer) store(id []byte, size uint64) {
+	t.ids[hexutil.Encode(id)+"-"+strconv.FormatUint(size, 10)]++
+}
+
+// CaptureStart implements vm.Tracer, recording the outer call.
+func (t *fourByteTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.input = common.CopyBytes(input)
+	t.precompiles = vm.ActivePrecompiles(env.ChainConfig().Rules(env.Context.BlockNumber))
+}
+
+// CaptureState implements vm.Tracer, counting the identifiers of the inner calls.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	// Skip anything but calls, locating the input after the value if present
+	var in int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		in = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		in = 2
+	default:
+		return
+	}
+	stack := scope.Stack
+	if isPrecompiled(t.precompiles, peekStack(stack, 1).Bytes20()) {
+		return
+	}
+	if size := stackUint64(peekStack(stack, in+1)); size >= 4 {
+		t.store(memorySlice(scope.Memory, stackUint64(peekStack(stack, in)), 4), size-4)
+	}
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *fourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *fourByteTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) {
+}
+
+// GetResult returns the call counts by method identifier and data size.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	if len(t.input) >= 4 {
+		t.store(t.input[:4], uint64(len(t.input)-4))
+	}
+	res, err := json.Marshal(t.ids)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
@@ -0,0 +1,254 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// callFrame is a call of the call tracer result. Unset fields are omitted, as
+// they are by the JavaScript tracer.
+type callFrame struct {
+	Type    string       `json:"type"`
+	From    string       `json:"from,omitempty"`
+	To      string       `json:"to,omitempty"`
+	Value   string       `json:"value,omitempty"`
+	Gas     string       `json:"gas,omitempty"`
+	GasUsed string       `json:"gasUsed,omitempty"`
+	Input   string       `json:"input,omitempty"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Time    string       `json:"time,omitempty"`
+	Calls   []*callFrame `json:"calls,omitempty"`
+
+	gasIn   uint64  // Gas available before the call opcode
+	gasCost uint64  // Cost of the call opcode, including the gas passed on
+	gas     *uint64 // Gas available in the callee, if it ran any code
+	outOff  uint64  // Memory offset of the call output
+	outLen  uint64  // Memory size of the call output
+}
+
+// callTracer is the native implementation of the callTracer, reconstructing the
+// call tree of a transaction from the executed opcodes.
+type callTracer struct {
+	interrupter
+
+	callstack   []*callFrame
+	descended   bool
+	precompiles []common.Address
+
+	// Outer call, as reported on start and end
+	create  bool
+	from    common.Address
+	to      common.Address
+	input   []byte
+	gas     uint64
+	value   *big.Int
+	output  []byte
+	gasUsed uint64
+	time    time.Duration
+	err     error
+}
+
+// newCallTracer creates a native call tracer. It has no options.
+func newCallTracer(config json.RawMessage) (txTracer, error) {
+	return &callTracer{callstack: []*callFrame{{}}}, nil
+}
+
+// CaptureStart implements vm.Tracer, recording the outer call.
+func (t *callTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.create, t.from, t.to, t.input, t.gas, t.value = create, from, to, common.CopyBytes(input), gas, value
+	t.precompiles = vm.ActivePrecompiles(env.ChainConfig().Rules(env.Context.BlockNumber))
+}
+
+// CaptureState implements vm.Tracer, tracking calls entered and returned from.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	if err != nil {
+		t.fault(err)
+		return
+	}
+	stack, contract := scope.Stack, scope.Contract.Address()
+
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(contract.Bytes()),
+			Input:   hexutil.Encode(memorySlice(scope.Memory, stackUint64(stack.Back(1)), stackUint64(stack.Back(2)))),
+			Value:   hexutil.EncodeBig(stack.Back(0).ToBig()),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return
+
+	case vm.SELFDESTRUCT:
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{
+			Type:  op.String(),
+			From:  hexutil.Encode(contract.Bytes()),
+			To:    hexutil.Encode(common.Address(stack.Back(0).Bytes20()).Bytes()),
+			Value: hexutil.EncodeBig(env.StateDB.GetBalance(contract)),
+		})
+		return
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		to := common.Address(stack.Back(1).Bytes20())
+		if isPrecompiled(t.precompiles, to) {
+			return
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		call := &callFrame{
+			Type:    op.String(),
+			From:    hexutil.Encode(contract.Bytes()),
+			To:      hexutil.Encode(to.Bytes()),
+			Input:   hexutil.Encode(memorySlice(scope.Memory, stackUint64(stack.Back(2+off)), stackUint64(stack.Back(3+off)))),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  stackUint64(stack.Back(4 + off)),
+			outLen:  stackUint64(stack.Back(5 + off)),
+		}
+		if off == 1 {
+			call.Value = hexutil.EncodeBig(stack.Back(2).ToBig())
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return
+	}
+	// If we've just descended into an inner call, retrieve its true allowance
+	if t.descended {
+		if depth >= len(t.callstack) {
+			gas := gas
+			t.callstack[len(t.callstack)-1].gas = &gas
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop it off the callstack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return
+	}
+	if depth != len(t.callstack)-1 {
+		return
+	}
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	ret := stack.Back(0)
+	if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+		call.GasUsed = hexutil.EncodeUint64(call.gasIn - call.gasCost - gas)
+		if !ret.IsZero() {
+			addr := common.Address(ret.Bytes20())
+			call.To = hexutil.Encode(addr.Bytes())
+			call.Output = hexutil.Encode(env.StateDB.GetCode(addr))
+		} else if call.Error == "" {
+			call.Error = "internal failure"
+		}
+	} else {
+		if call.gas != nil {
+			call.GasUsed = hexutil.EncodeUint64(call.gasIn - call.gasCost + *call.gas - gas)
+		}
+		if !ret.IsZero() {
+			call.Output = hexutil.Encode(memorySlice(scope.Memory, call.outOff, call.outLen))
+		} else if call.Error == "" {
+			call.Error = "internal failure"
+		}
+	}
+	if call.gas != nil {
+		call.Gas = hexutil.EncodeUint64(*call.gas)
+	}
+	parent := t.callstack[len(t.callstack)-1]
+	parent.Calls = append(parent.Calls, call)
+}
+
+// CaptureFault implements vm.Tracer, failing the current call.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	t.fault(err)
+}
+
+// fault pops the failed call off the callstack, unless it already failed.
+func (t *callTracer) fault(err error) {
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	call.Error = err.Error()
+	if call.gas != nil {
+		call.Gas = hexutil.EncodeUint64(*call.gas)
+		call.GasUsed = call.Gas
+	}
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd implements vm.Tracer, recording the outcome of the outer call.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) {
+	t.output, t.gasUsed, t.time, t.err = common.CopyBytes(output), gasUsed, elapsed, err
+}
+
+// GetResult returns the call tree of the transaction.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	result := &callFrame{
+		Type:    "CALL",
+		From:    hexutil.Encode(t.from.Bytes()),
+		To:      hexutil.Encode(t.to.Bytes()),
+		Value:   hexutil.EncodeBig(t.value),
+		Gas:     hexutil.EncodeUint64(t.gas),
+		GasUsed: hexutil.EncodeUint64(t.gasUsed),
+		Input:   hexutil.Encode(t.input),
+		Output:  hexutil.Encode(t.output),
+		Time:    t.time.String(),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.create {
+		result.Type = "CREATE"
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.err != nil {
+		result.Error = t.err.Error()
+	}
+	if result.Error != "" && (result.Error != "execution reverted" || result.Output == "0x") {
+		result.Output = ""
+	}
+	res, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
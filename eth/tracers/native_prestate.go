@@ -0,0 +1,305 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// prestateAccount is an account of the prestate tracer result.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// prestateDiffAccount is an account of the prestate tracer result in diff mode,
+// holding only the fields which changed.
+type prestateDiffAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *uint64                     `json:"nonce,omitempty"`
+	Code    *hexutil.Bytes              `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// prestateDiff is the prestate tracer result in diff mode: the state of the
+// accounts modified by a transaction before and after it. Accounts empty before
+// the transaction are missing from the pre state, accounts destroyed by it are
+// missing from the post state.
+type prestateDiff struct {
+	Pre  map[common.Address]*prestateDiffAccount `json:"pre"`
+	Post map[common.Address]*prestateDiffAccount `json:"post"`
+}
+
+// prestateTracerConfig are the options of the prestate tracer.
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // Return the pre and post state of the modified accounts
+}
+
+// prestateTracer is the native implementation of the prestateTracer, collecting
+// the state of the accounts and storage slots touched by a transaction, as it
+// was before the transaction.
+type prestateTracer struct {
+	interrupter
+
+	config   prestateTracerConfig
+	env      *vm.EVM
+	prestate map[common.Address]*prestateAccount
+	created  map[common.Address]bool // Accounts empty before the transaction, in diff mode
+	gasLimit uint64                  // Gas limit of the transaction, in diff mode
+
+	// Outer call, as reported on start and end
+	create       bool
+	from         common.Address
+	to           common.Address
+	value        *big.Int
+	intrinsicGas uint64
+	gasUsed      uint64
+}
+
+// newPrestateTracer creates a native prestate tracer. It reports the state as of
+// before the transaction in a JavaScript tracer compatible way, or both the pre
+// and post state of the modified fields in diff mode.
+func newPrestateTracer(config json.RawMessage) (txTracer, error) {
+	t := &prestateTracer{
+		prestate: make(map[common.Address]*prestateAccount),
+		created:  make(map[common.Address]bool),
+	}
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &t.config); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// lookupAccount records the current state of an account, if not yet recorded.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	if t.config.DiffMode && t.env.StateDB.Empty(addr) {
+		t.created[addr] = true
+	}
+	t.prestate[addr] = &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.env.StateDB.GetBalance(addr))),
+		Nonce:   t.env.StateDB.GetNonce(addr),
+		Code:    common.CopyBytes(t.env.StateDB.GetCode(addr)),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage records the current value of a storage slot, if not yet
+// recorded.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.prestate[addr].Storage[key]; ok {
+		return
+	}
+	t.prestate[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
+}
+
+// CaptureTxStart records the gas limit of the transaction, needed to roll back
+// the gas purchase of the sender in diff mode.
+func (t *prestateTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureStart implements vm.Tracer, recording the outer call.
+func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.create, t.from, t.to, t.value = create, from, to, value
+
+	homestead := env.ChainConfig().IsHomestead(env.Context.BlockNumber)
+	istanbul := env.ChainConfig().IsIstanbul(env.Context.BlockNumber)
+	t.intrinsicGas, _ = core.IntrinsicGas(input, nil, create, homestead, istanbul)
+
+	if !t.config.DiffMode {
+		return
+	}
+	// In diff mode, record the accounts modified outside of the execution right
+	// away, rolling back the value transfer, gas purchase and nonce bump which
+	// already happened.
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	t.lookupAccount(env.Context.Coinbase)
+
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(t.gasLimit), env.TxContext.GasPrice)
+	sender := t.prestate[from]
+	sender.Balance = (*hexutil.Big)(new(big.Int).Add(sender.Balance.ToInt(), new(big.Int).Add(value, fee)))
+	sender.Nonce--
+
+	recipient := t.prestate[to]
+	recipient.Balance = (*hexutil.Big)(new(big.Int).Sub(recipient.Balance.ToInt(), value))
+	if create {
+		// The contract was already set up, with only the balance surviving
+		recipient.Nonce, recipient.Code = 0, nil
+		t.created[to] = recipient.Balance.ToInt().Sign() == 0
+	}
+}
+
+// CaptureState implements vm.Tracer, recording the accounts and storage slots
+// accessed by the opcode.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	// Failing opcodes are reported too, possibly with missing stack items
+	stack, contract := scope.Stack, scope.Contract.Address()
+	if len(t.prestate) == 0 {
+		t.lookupAccount(contract)
+	}
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(peekStack(stack, 0).Bytes20())
+
+	case vm.CREATE:
+		t.lookupAccount(crypto.CreateAddress(contract, env.StateDB.GetNonce(contract)))
+
+	case vm.CREATE2:
+		code := memorySlice(scope.Memory, stackUint64(peekStack(stack, 1)), stackUint64(peekStack(stack, 2)))
+		t.lookupAccount(crypto.CreateAddress2(contract, peekStack(stack, 3).Bytes32(), crypto.Keccak256(code)))
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(peekStack(stack, 1).Bytes20())
+
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract, peekStack(stack, 0).Bytes32())
+
+	case vm.SELFDESTRUCT:
+		// The beneficiary is only modified, not read
+		if t.config.DiffMode {
+			t.lookupAccount(peekStack(stack, 0).Bytes20())
+		}
+	}
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd implements vm.Tracer, recording the gas used by the outer call.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) {
+	t.gasUsed = gasUsed
+}
+
+// GetResult returns the prestate of the accounts touched by the transaction,
+// rolling back the value transfer, gas payment and nonce bump of the sender
+// which happened before the accounts were looked up.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.config.DiffMode {
+		res, err := json.Marshal(t.diff())
+		if err != nil {
+			return nil, err
+		}
+		return res, t.reason
+	}
+	if t.env != nil {
+		t.lookupAccount(t.from)
+		t.lookupAccount(t.to)
+
+		from, to := t.prestate[t.from], t.prestate[t.to]
+		to.Balance = (*hexutil.Big)(new(big.Int).Sub(to.Balance.ToInt(), t.value))
+
+		fee := new(big.Int).SetUint64(t.gasUsed + t.intrinsicGas)
+		fee.Mul(fee, t.env.TxContext.GasPrice)
+		from.Balance = (*hexutil.Big)(new(big.Int).Add(from.Balance.ToInt(), new(big.Int).Add(t.value, fee)))
+		from.Nonce--
+
+		if t.create {
+			delete(t.prestate, t.to)
+		}
+	}
+	res, err := json.Marshal(t.prestate)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// diff compares the recorded state of the accounts with their current one,
+// returning the fields which changed.
+func (t *prestateTracer) diff() *prestateDiff {
+	diff := &prestateDiff{
+		Pre:  make(map[common.Address]*prestateDiffAccount),
+		Post: make(map[common.Address]*prestateDiffAccount),
+	}
+	if t.env == nil {
+		return diff
+	}
+	db := t.env.StateDB
+	for addr, account := range t.prestate {
+		// Destroyed accounts are reported with their whole pre state
+		if db.HasSuicided(addr) {
+			if !t.created[addr] {
+				nonce, code := account.Nonce, account.Code
+				diff.Pre[addr] = &prestateDiffAccount{
+					Balance: account.Balance,
+					Nonce:   &nonce,
+					Code:    &code,
+					Storage: account.Storage,
+				}
+			}
+			continue
+		}
+		var (
+			pre, post = new(prestateDiffAccount), new(prestateDiffAccount)
+			modified  bool
+		)
+		if balance := db.GetBalance(addr); balance.Cmp(account.Balance.ToInt()) != 0 {
+			pre.Balance, post.Balance = account.Balance, (*hexutil.Big)(new(big.Int).Set(balance))
+			modified = true
+		}
+		if nonce := db.GetNonce(addr); nonce != account.Nonce {
+			prev := account.Nonce
+			pre.Nonce, post.Nonce = &prev, &nonce
+			modified = true
+		}
+		if code := db.GetCode(addr); !bytes.Equal(code, account.Code) {
+			prev, code := account.Code, hexutil.Bytes(common.CopyBytes(code))
+			pre.Code, post.Code = &prev, &code
+			modified = true
+		}
+		for key, val := range account.Storage {
+			if current := db.GetState(addr, key); current != val {
+				if pre.Storage == nil {
+					pre.Storage, post.Storage = make(map[common.Hash]common.Hash), make(map[common.Hash]common.Hash)
+				}
+				pre.Storage[key], post.Storage[key] = val, current
+				modified = true
+			}
+		}
+		if !modified {
+			continue
+		}
+		if !t.created[addr] {
+			diff.Pre[addr] = pre
+		}
+		diff.Post[addr] = post
+	}
+	return diff
+}
@@ -0,0 +1,313 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+const (
+	// intrinsicFrame is the leaf of the folded stack the intrinsic gas of the
+	// transaction is attributed to.
+	intrinsicFrame = "[intrinsic]"
+
+	// codeDepositFrame is the leaf of the folded stack the gas storing the code
+	// of a contract created by the transaction is attributed to.
+	codeDepositFrame = "[codedeposit]"
+)
+
+// GasProfile is the result of the gas profiler, the gas used and the execution
+// time spent in nanoseconds by each call stack, in the folded stack format of
+// flamegraph tools: one "frame;frame;...;frame value" line per stack.
+type GasProfile struct {
+	Gas  string `json:"gas"`
+	Time string `json:"time"`
+}
+
+// profileFrame is a call frame of the profiled transaction.
+type profileFrame struct {
+	stack string             // Folded stack of the frame, from the outer call
+	gas   [256]uint64        // Gas used by each opcode in the frame itself
+	time  [256]time.Duration // Time spent executing each opcode in the frame itself
+	spent uint64             // Gas used by the frame and its subcalls so far
+	call  *profiledOp        // Call or create opcode running a subcall, if any
+}
+
+// profiledOp is an executed opcode whose gas and time are yet to be attributed.
+type profiledOp struct {
+	frame *profileFrame
+	op    vm.OpCode
+	gas   uint64    // Gas available before the opcode
+	cost  uint64    // Cost of the opcode
+	burnt bool      // Whether the opcode failed, consuming all the gas left
+	start time.Time // Time the opcode started executing at
+}
+
+// used returns the gas used by an opcode that was the last one of its frame.
+func (op *profiledOp) used() uint64 {
+	if op.burnt {
+		return op.gas
+	}
+	return op.cost
+}
+
+// gasProfiler aggregates the gas used and the time spent by each opcode of a
+// transaction, by the stack of contracts and function selectors it ran in. The
+// gas of calls and creates excludes the gas used by the subcall, attributed to
+// its own frames instead. Gas is counted before refunds.
+//
+// Frames are labelled "<code address>:<selector>", the selector being replaced
+// by "fallback" for calls without one and by "constructor" for creates, so that
+// delegate calls are attributed to the code they run.
+type gasProfiler struct {
+	interrupter
+
+	gas  map[string]uint64
+	time map[string]time.Duration
+
+	root     string          // Label of the outer call
+	create   bool            // Whether the outer call is a create
+	spent    uint64          // Gas used by the code of the outer call
+	gasLimit uint64          // Gas limit of the transaction, if known
+	frames   []*profileFrame // Call frames being executed
+	pending  *profiledOp     // Last executed opcode
+	executed bool            // Whether any code was executed
+}
+
+// newGasProfiler creates a gas profiler. It has no options.
+func newGasProfiler(config json.RawMessage) (txTracer, error) {
+	return &gasProfiler{
+		gas:  make(map[string]uint64),
+		time: make(map[string]time.Duration),
+	}, nil
+}
+
+// frameLabel returns the label of a frame running the given code with the given
+// input.
+func frameLabel(addr common.Address, create bool, input []byte) string {
+	switch {
+	case create:
+		return hexutil.Encode(addr.Bytes()) + ":constructor"
+	case len(input) < 4:
+		return hexutil.Encode(addr.Bytes()) + ":fallback"
+	default:
+		return hexutil.Encode(addr.Bytes()) + ":" + hexutil.Encode(input[:4])
+	}
+}
+
+// opLabel returns the label of an opcode, undefined ones being labelled by their
+// value as their name contains spaces.
+func opLabel(op vm.OpCode) string {
+	if name := op.String(); !strings.Contains(name, " ") {
+		return name
+	}
+	return fmt.Sprintf("INVALID_%#x", byte(op))
+}
+
+// CaptureTxStart records the gas limit of the transaction, needed to attribute
+// its intrinsic gas.
+func (t *gasProfiler) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureStart implements vm.Tracer, labelling the outer call.
+func (t *gasProfiler) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.root, t.create = frameLabel(to, create, input), create
+	if t.gasLimit > gas {
+		t.gas[t.root+";"+intrinsicFrame] += t.gasLimit - gas
+	}
+}
+
+// CaptureState implements vm.Tracer, attributing the gas and time of the
+// previous opcode and tracking the frames entered and returned from.
+func (t *gasProfiler) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	now := time.Now()
+	t.settle(gas, depth, now)
+
+	if depth > len(t.frames) {
+		stack := t.root
+		if len(t.frames) > 0 {
+			parent := t.frames[len(t.frames)-1]
+			create := parent.call != nil && (parent.call.op == vm.CREATE || parent.call.op == vm.CREATE2)
+
+			addr := scope.Contract.Address()
+			if scope.Contract.CodeAddr != nil {
+				addr = *scope.Contract.CodeAddr
+			}
+			stack = parent.stack + ";" + frameLabel(addr, create, scope.Contract.Input)
+		}
+		t.frames = append(t.frames, &profileFrame{stack: stack})
+		t.executed = true
+	}
+	t.pending = &profiledOp{
+		frame: t.frames[len(t.frames)-1],
+		op:    op,
+		gas:   gas,
+		cost:  cost,
+		start: now,
+	}
+	if err != nil {
+		t.fail(err)
+	}
+}
+
+// CaptureFault implements vm.Tracer, recording whether the failed opcode
+// consumed all the gas left.
+func (t *gasProfiler) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if t.stopped() {
+		return
+	}
+	if depth == len(t.frames) {
+		t.fail(err)
+	}
+}
+
+// CaptureEnd implements vm.Tracer, attributing the last opcode.
+func (t *gasProfiler) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) {
+	if t.stopped() {
+		return
+	}
+	if op := t.pending; op != nil {
+		t.pending = nil
+		op.frame.time[op.op] += time.Since(op.start)
+		t.spend(op, op.used())
+	}
+	for len(t.frames) > 0 {
+		t.exit(0)
+	}
+	switch {
+	case !t.executed && gasUsed > 0:
+		// Calls to accounts without code, including precompiles, run no opcode
+		t.gas[t.root] += gasUsed
+	case t.create && gasUsed > t.spent:
+		// The code of the created contract is stored once its constructor ran
+		t.gas[t.root+";"+codeDepositFrame] += gasUsed - t.spent
+	}
+}
+
+// fail marks the last executed opcode as failed, consuming all the gas left
+// unless it reverted.
+func (t *gasProfiler) fail(err error) {
+	if t.pending != nil && err != vm.ErrExecutionReverted {
+		t.pending.burnt = true
+	}
+}
+
+// settle attributes the time spent by the last executed opcode, and its gas
+// unless it entered a subcall, in which case it is attributed once returning.
+func (t *gasProfiler) settle(gas uint64, depth int, now time.Time) {
+	op := t.pending
+	if op == nil {
+		return
+	}
+	t.pending = nil
+	op.frame.time[op.op] += now.Sub(op.start)
+
+	switch {
+	case depth > len(t.frames):
+		op.frame.call = op
+
+	case depth == len(t.frames):
+		if op.gas > gas {
+			t.spend(op, op.gas-gas)
+		}
+	default:
+		t.spend(op, op.used())
+		for len(t.frames) > depth {
+			t.exit(gas)
+		}
+	}
+}
+
+// spend attributes gas to an opcode.
+func (t *gasProfiler) spend(op *profiledOp, gas uint64) {
+	op.frame.gas[op.op] += gas
+	op.frame.spent += gas
+}
+
+// exit returns from the innermost frame, given the gas left in its parent,
+// attributing to the call or create opcode the gas its subcall didn't use.
+func (t *gasProfiler) exit(gas uint64) {
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	for op := range frame.gas {
+		if frame.gas[op] > 0 {
+			t.gas[frame.stack+";"+opLabel(vm.OpCode(op))] += frame.gas[op]
+		}
+		if frame.time[op] > 0 {
+			t.time[frame.stack+";"+opLabel(vm.OpCode(op))] += frame.time[op]
+		}
+	}
+	if len(t.frames) == 0 {
+		t.spent = frame.spent
+		return
+	}
+	parent := t.frames[len(t.frames)-1]
+	parent.spent += frame.spent
+
+	if call := parent.call; call != nil {
+		parent.call = nil
+		if used := call.gas - gas; call.gas > gas && used > frame.spent {
+			t.spend(call, used-frame.spent)
+		}
+	}
+}
+
+// foldStacks formats samples by stack in the folded stack format, sorted by
+// stack.
+func foldStacks(samples map[string]uint64) string {
+	stacks := make([]string, 0, len(samples))
+	for stack := range samples {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	var folded strings.Builder
+	for _, stack := range stacks {
+		fmt.Fprintf(&folded, "%s %d\n", stack, samples[stack])
+	}
+	return folded.String()
+}
+
+// GetResult returns the gas used and time spent by each stack.
+func (t *gasProfiler) GetResult() (json.RawMessage, error) {
+	times := make(map[string]uint64, len(t.time))
+	for stack, elapsed := range t.time {
+		times[stack] = uint64(elapsed)
+	}
+	res, err := json.Marshal(&GasProfile{
+		Gas:  foldStacks(t.gas),
+		Time: foldStacks(times),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
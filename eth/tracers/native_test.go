@@ -0,0 +1,353 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// runTracerTest executes the transaction of a tracer test case on top of its
+// prestate with the given tracer, returning the trace result and the state after
+// the transaction.
+func runTracerTest(t *testing.T, test *callTracerTest, tracer txTracer) (json.RawMessage, *state.StateDB) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+	txContext := vm.TxContext{
+		Origin:   origin,
+		GasPrice: tx.GasPrice(),
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	if tracer, ok := tracer.(txStartTracer); ok {
+		tracer.CaptureTxStart(tx.Gas())
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res, statedb
+}
+
+// Tests that the native tracers produce the same results as the JavaScript ones
+// of the same name, and that the native call tracer matches the expectations.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			for name, constructor := range natives {
+				js, err := New(name, new(Context))
+				if err != nil {
+					t.Fatalf("%s: failed to create JavaScript tracer: %v", name, err)
+				}
+				tracer, err := constructor(nil)
+				if err != nil {
+					t.Fatalf("%s: failed to create native tracer: %v", name, err)
+				}
+				var want, have map[string]interface{}
+				res, _ := runTracerTest(t, test, js)
+				if err := json.Unmarshal(res, &want); err != nil {
+					t.Fatalf("%s: failed to unmarshal JavaScript trace result: %v", name, err)
+				}
+				native, _ := runTracerTest(t, test, tracer)
+				if err := json.Unmarshal(native, &have); err != nil {
+					t.Fatalf("%s: failed to unmarshal native trace result: %v", name, err)
+				}
+				// The execution time naturally differs between the runs
+				delete(want, "time")
+				delete(have, "time")
+
+				if !reflect.DeepEqual(have, want) {
+					t.Errorf("%s: trace mismatch: \nhave %+v\nwant %+v", name, have, want)
+				}
+				if name == "callTracer" {
+					ret := new(callTrace)
+					if err := json.Unmarshal(native, ret); err != nil {
+						t.Fatalf("failed to unmarshal trace result: %v", err)
+					}
+					if !jsonEqual(ret, test.Result) {
+						t.Errorf("call trace mismatch: \nhave %+v\nwant %+v", ret, test.Result)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Tests that native tracers are preferred over the JavaScript ones of the same
+// name, while other tracers are still run in JavaScript.
+func TestNewTracer(t *testing.T) {
+	tracer, err := newTracer("callTracer", new(Context), nil)
+	if err != nil {
+		t.Fatalf("failed to create call tracer: %v", err)
+	}
+	if _, ok := tracer.(*callTracer); !ok {
+		t.Errorf("call tracer type mismatch: have %T, want %T", tracer, new(callTracer))
+	}
+	if tracer, err = newTracer("opcountTracer", new(Context), nil); err != nil {
+		t.Fatalf("failed to create opcount tracer: %v", err)
+	}
+	if _, ok := tracer.(*Tracer); !ok {
+		t.Errorf("opcount tracer type mismatch: have %T, want %T", tracer, new(Tracer))
+	}
+	if _, err = newTracer("opcountTracer", new(Context), json.RawMessage(`{"diffMode":true}`)); err == nil {
+		t.Errorf("JavaScript tracer accepted native tracer config")
+	}
+}
+
+// Tests that the prestate tracer in diff mode reports exactly the fields changed
+// by the transaction, with their values before and after it.
+func TestPrestateTracerDiffMode(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			tracer, err := newPrestateTracer(json.RawMessage(`{"diffMode": true}`))
+			if err != nil {
+				t.Fatalf("failed to create prestate tracer: %v", err)
+			}
+			res, statedb := runTracerTest(t, test, tracer)
+
+			diff := new(prestateDiff)
+			if err := json.Unmarshal(res, diff); err != nil {
+				t.Fatalf("failed to unmarshal trace result: %v", err)
+			}
+			if len(diff.Post) == 0 {
+				t.Fatalf("no modified accounts reported")
+			}
+			// Every modification of a preexisting account must be reported
+			for addr, account := range test.Genesis.Alloc {
+				if statedb.HasSuicided(addr) {
+					if _, ok := diff.Post[addr]; ok || diff.Pre[addr] == nil {
+						t.Errorf("account %x: destroyed account not reported", addr)
+					}
+					continue
+				}
+				if statedb.GetBalance(addr).Cmp(account.Balance) != 0 && (diff.Post[addr] == nil || diff.Post[addr].Balance == nil) {
+					t.Errorf("account %x: balance change not reported", addr)
+				}
+				if statedb.GetNonce(addr) != account.Nonce && (diff.Post[addr] == nil || diff.Post[addr].Nonce == nil) {
+					t.Errorf("account %x: nonce change not reported", addr)
+				}
+			}
+			// The reported pre state must match the genesis, the post state the final one
+			for addr, pre := range diff.Pre {
+				account, ok := test.Genesis.Alloc[addr]
+				if !ok {
+					t.Errorf("account %x: pre state reported for created account", addr)
+					continue
+				}
+				if pre.Balance != nil && pre.Balance.ToInt().Cmp(account.Balance) != 0 {
+					t.Errorf("account %x: pre balance mismatch: have %v, want %v", addr, pre.Balance, account.Balance)
+				}
+				if pre.Nonce != nil && *pre.Nonce != account.Nonce {
+					t.Errorf("account %x: pre nonce mismatch: have %d, want %d", addr, *pre.Nonce, account.Nonce)
+				}
+				for key, val := range pre.Storage {
+					if val != account.Storage[key] {
+						t.Errorf("account %x: pre slot %x mismatch: have %x, want %x", addr, key, val, account.Storage[key])
+					}
+				}
+			}
+			for addr, post := range diff.Post {
+				if post.Balance != nil && post.Balance.ToInt().Cmp(statedb.GetBalance(addr)) != 0 {
+					t.Errorf("account %x: post balance mismatch: have %v, want %v", addr, post.Balance, statedb.GetBalance(addr))
+				}
+				if post.Nonce != nil && *post.Nonce != statedb.GetNonce(addr) {
+					t.Errorf("account %x: post nonce mismatch: have %d, want %d", addr, *post.Nonce, statedb.GetNonce(addr))
+				}
+				if post.Code != nil && !bytes.Equal(*post.Code, statedb.GetCode(addr)) {
+					t.Errorf("account %x: post code mismatch", addr)
+				}
+				for key, val := range post.Storage {
+					if want := statedb.GetState(addr, key); val != want {
+						t.Errorf("account %x: post slot %x mismatch: have %x, want %x", addr, key, val, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Tests that the gas profiler attributes all the gas of a transaction to the
+// opcodes of the frames it was used in, including subcalls burning their gas.
+func TestGasProfiler(t *testing.T) {
+	var (
+		a = common.HexToAddress("0xaa")
+		b = common.HexToAddress("0xbb")
+		c = common.HexToAddress("0xcc")
+		d = common.HexToAddress("0xdd")
+
+		from = common.HexToAddress("0x01")
+	)
+	// A stores a slot, calls B loading a slot, calls C failing and delegate
+	// calls D loading the slot stored by A
+	code := common.FromHex("6001600055")
+	for _, call := range []struct {
+		op   byte
+		addr common.Address
+	}{{0xf1, b}, {0xf1, c}, {0xf4, d}} {
+		code = append(code, common.FromHex("6000600060006000")...)
+		if call.op == 0xf1 {
+			code = append(code, common.FromHex("6000")...) // value
+		}
+		code = append(append(append(code, 0x73), call.addr.Bytes()...), 0x61, 0xff, 0xff, call.op, 0x50)
+	}
+	code = append(code, 0x00)
+
+	alloc := core.GenesisAlloc{
+		a:    {Code: code, Balance: new(big.Int)},
+		b:    {Code: common.FromHex("6000545000"), Balance: new(big.Int)},
+		c:    {Code: common.FromHex("fe"), Balance: new(big.Int)},
+		d:    {Code: common.FromHex("6000545000"), Balance: new(big.Int)},
+		from: {Balance: big.NewInt(params.Ether)},
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc, false)
+
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    10000000,
+		BaseFee:     new(big.Int),
+	}
+	tracer, _ := newGasProfiler(nil)
+	evm := vm.NewEVM(context, vm.TxContext{Origin: from, GasPrice: new(big.Int)}, statedb, params.AllEthashProtocolChanges, vm.Config{Debug: true, Tracer: tracer})
+
+	msg := types.NewMessage(from, &a, 0, new(big.Int), 200000, new(big.Int), new(big.Int), new(big.Int), common.FromHex("a9059cbb"), nil, true)
+	tracer.(txStartTracer).CaptureTxStart(msg.Gas())
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	profile := new(GasProfile)
+	if err := json.Unmarshal(res, profile); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	var (
+		have  = make(map[string]uint64)
+		total uint64
+	)
+	for _, line := range strings.Split(strings.TrimSpace(profile.Gas), "\n") {
+		i := strings.LastIndexByte(line, ' ')
+		gas, err := strconv.ParseUint(line[i+1:], 10, 64)
+		if err != nil {
+			t.Fatalf("invalid folded stack line %q: %v", line, err)
+		}
+		have[line[:i]] = gas
+		total += gas
+	}
+	if total != result.UsedGas {
+		t.Errorf("total gas mismatch: have %d, want %d", total, result.UsedGas)
+	}
+	root := hexutil.Encode(a.Bytes()) + ":0xa9059cbb"
+	for stack, want := range map[string]uint64{
+		root + ";[intrinsic]":  21064,
+		root + ";SSTORE":       22100,
+		root + ";CALL":         2 * 2600,
+		root + ";DELEGATECALL": 2600,
+		root + ";" + hexutil.Encode(b.Bytes()) + ":fallback;SLOAD":        2100,
+		root + ";" + hexutil.Encode(c.Bytes()) + ":fallback;INVALID_0xfe": 0xffff,
+		root + ";" + hexutil.Encode(d.Bytes()) + ":fallback;SLOAD":        100,
+	} {
+		if have[stack] != want {
+			t.Errorf("gas of %s mismatch: have %d, want %d", stack, have[stack], want)
+		}
+	}
+	if !strings.Contains(profile.Time, root+";SSTORE ") {
+		t.Errorf("time of SSTORE missing: %s", profile.Time)
+	}
+}
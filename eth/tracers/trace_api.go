@@ -0,0 +1,708 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// traceFilterScanLimit is the maximum number of blocks trace_filter is willing
+// to trace in search of matching traces, blocks found through the trace index
+// aside.
+const traceFilterScanLimit = 1024
+
+// traceIndexBackend is implemented by backends maintaining an index of the
+// addresses appearing in the call traces of each block.
+type traceIndexBackend interface {
+	TraceIndexer() *core.ChainIndexer
+}
+
+// TraceAPI is the collection of flat, Parity style tracing APIs exposed over the
+// private trace endpoint.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the flat tracing methods of the
+// Ethereum service.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// FlatTraceAction is the action of a flat trace, describing a call, a contract
+// creation or a self destruct depending on the trace type.
+type FlatTraceAction struct {
+	CallType      string          `json:"callType,omitempty"`
+	From          *common.Address `json:"from,omitempty"`
+	To            *common.Address `json:"to,omitempty"`
+	Gas           *hexutil.Uint64 `json:"gas,omitempty"`
+	Input         *hexutil.Bytes  `json:"input,omitempty"`
+	Init          *hexutil.Bytes  `json:"init,omitempty"`
+	Value         *hexutil.Big    `json:"value,omitempty"`
+	Address       *common.Address `json:"address,omitempty"`
+	RefundAddress *common.Address `json:"refundAddress,omitempty"`
+	Balance       *hexutil.Big    `json:"balance,omitempty"`
+}
+
+// FlatTraceResult is the outcome of a successful call or contract creation.
+type FlatTraceResult struct {
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Address *common.Address `json:"address,omitempty"`
+	Code    *hexutil.Bytes  `json:"code,omitempty"`
+}
+
+// FlatTrace is a single call of a transaction, positioned within the call tree
+// by its trace address.
+type FlatTrace struct {
+	Action              *FlatTraceAction `json:"action"`
+	BlockHash           *common.Hash     `json:"blockHash,omitempty"`
+	BlockNumber         *uint64          `json:"blockNumber,omitempty"`
+	Error               string           `json:"error,omitempty"`
+	Result              *FlatTraceResult `json:"result"`
+	Subtraces           int              `json:"subtraces"`
+	TraceAddress        []int            `json:"traceAddress"`
+	TransactionHash     *common.Hash     `json:"transactionHash,omitempty"`
+	TransactionPosition *uint64          `json:"transactionPosition,omitempty"`
+	Type                string           `json:"type"`
+}
+
+// addresses returns the account initiating the traced action and the one it is
+// directed at, if any.
+func (t *FlatTrace) addresses() (from *common.Address, to *common.Address) {
+	switch t.Type {
+	case "suicide":
+		return t.Action.Address, t.Action.RefundAddress
+	case "create":
+		if t.Result != nil {
+			return t.Action.From, t.Result.Address
+		}
+		return t.Action.From, nil
+	default:
+		return t.Action.From, t.Action.To
+	}
+}
+
+// DiffValue is the change of an account field or storage slot. It marshals as
+// "=" if unchanged, {"+": to} if created, {"-": from} if destroyed and as
+// {"*": {"from": from, "to": to}} if modified.
+type DiffValue struct {
+	From interface{} // Value before the transaction, nil if created
+	To   interface{} // Value after the transaction, nil if destroyed
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d DiffValue) MarshalJSON() ([]byte, error) {
+	switch {
+	case d.From == nil && d.To == nil:
+		return json.Marshal("=")
+	case d.From == nil:
+		return json.Marshal(map[string]interface{}{"+": d.To})
+	case d.To == nil:
+		return json.Marshal(map[string]interface{}{"-": d.From})
+	default:
+		return json.Marshal(map[string]interface{}{"*": map[string]interface{}{"from": d.From, "to": d.To}})
+	}
+}
+
+// AccountDiff is the change of an account made by a transaction.
+type AccountDiff struct {
+	Balance DiffValue                 `json:"balance"`
+	Nonce   DiffValue                 `json:"nonce"`
+	Code    DiffValue                 `json:"code"`
+	Storage map[common.Hash]DiffValue `json:"storage"`
+}
+
+// StateDiff is the change of the state made by a transaction, by account.
+type StateDiff map[common.Address]*AccountDiff
+
+// newStateDiff converts the result of the prestate tracer in diff mode into a
+// state diff. Accounts missing from the pre state were created, the ones
+// missing from the post state destroyed.
+func newStateDiff(diff *prestateDiff) StateDiff {
+	res := make(StateDiff)
+	for addr, pre := range diff.Pre {
+		post, ok := diff.Post[addr]
+		if !ok {
+			res[addr] = destroyedAccountDiff(pre)
+			continue
+		}
+		account := &AccountDiff{Storage: make(map[common.Hash]DiffValue)}
+		if pre.Balance != nil && post.Balance != nil {
+			account.Balance = DiffValue{From: pre.Balance, To: post.Balance}
+		}
+		if pre.Nonce != nil && post.Nonce != nil {
+			account.Nonce = DiffValue{From: hexutil.Uint64(*pre.Nonce), To: hexutil.Uint64(*post.Nonce)}
+		}
+		if pre.Code != nil && post.Code != nil {
+			account.Code = DiffValue{From: *pre.Code, To: *post.Code}
+		}
+		for key, val := range pre.Storage {
+			account.Storage[key] = DiffValue{From: val, To: post.Storage[key]}
+		}
+		res[addr] = account
+	}
+	for addr, post := range diff.Post {
+		if _, ok := diff.Pre[addr]; !ok {
+			res[addr] = createdAccountDiff(post)
+		}
+	}
+	return res
+}
+
+// createdAccountDiff returns the diff of an account created by a transaction,
+// with all its fields set, unset ones being zero.
+func createdAccountDiff(post *prestateDiffAccount) *AccountDiff {
+	var (
+		balance = new(hexutil.Big)
+		nonce   hexutil.Uint64
+		code    = hexutil.Bytes{}
+	)
+	if post.Balance != nil {
+		balance = post.Balance
+	}
+	if post.Nonce != nil {
+		nonce = hexutil.Uint64(*post.Nonce)
+	}
+	if post.Code != nil {
+		code = *post.Code
+	}
+	account := &AccountDiff{
+		Balance: DiffValue{To: balance},
+		Nonce:   DiffValue{To: nonce},
+		Code:    DiffValue{To: code},
+		Storage: make(map[common.Hash]DiffValue),
+	}
+	for key, val := range post.Storage {
+		account.Storage[key] = DiffValue{To: val}
+	}
+	return account
+}
+
+// destroyedAccountDiff returns the diff of an account destroyed by a transaction.
+func destroyedAccountDiff(pre *prestateDiffAccount) *AccountDiff {
+	account := &AccountDiff{
+		Balance: DiffValue{From: pre.Balance},
+		Nonce:   DiffValue{From: hexutil.Uint64(*pre.Nonce)},
+		Code:    DiffValue{From: *pre.Code},
+		Storage: make(map[common.Hash]DiffValue),
+	}
+	for key, val := range pre.Storage {
+		account.Storage[key] = DiffValue{From: val}
+	}
+	return account
+}
+
+// TraceResults is the outcome of a transaction replayed with the requested trace
+// types. Virtual machine traces are not supported.
+type TraceResults struct {
+	Output          hexutil.Bytes `json:"output"`
+	StateDiff       StateDiff     `json:"stateDiff"`
+	Trace           []*FlatTrace  `json:"trace"`
+	TransactionHash common.Hash   `json:"transactionHash"`
+	VmTrace         interface{}   `json:"vmTrace"`
+}
+
+// TraceFilterArgs are the criteria of trace_filter. Traces must be sent by one
+// of the from addresses and be directed at one of the to addresses, empty lists
+// matching any address.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// matches returns whether the trace satisfies the address criteria.
+func (args *TraceFilterArgs) matches(trace *FlatTrace) bool {
+	from, to := trace.addresses()
+	return containsAddress(args.FromAddress, from) && containsAddress(args.ToAddress, to)
+}
+
+// containsAddress returns whether the address is in the list, any address being
+// contained in an empty list.
+func containsAddress(list []common.Address, addr *common.Address) bool {
+	if len(list) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	for _, a := range list {
+		if a == *addr {
+			return true
+		}
+	}
+	return false
+}
+
+// callTraceFrame is a call of the call tracer result, decoded for flattening.
+type callTraceFrame struct {
+	Type    string            `json:"type"`
+	From    common.Address    `json:"from"`
+	To      common.Address    `json:"to"`
+	Value   *hexutil.Big      `json:"value"`
+	Gas     hexutil.Uint64    `json:"gas"`
+	GasUsed hexutil.Uint64    `json:"gasUsed"`
+	Input   hexutil.Bytes     `json:"input"`
+	Output  hexutil.Bytes     `json:"output"`
+	Error   string            `json:"error"`
+	Calls   []*callTraceFrame `json:"calls"`
+}
+
+// parityErrors maps the errors of the call tracer to the ones Parity reports.
+var parityErrors = map[string]string{
+	vm.ErrExecutionReverted.Error():        "Reverted",
+	vm.ErrOutOfGas.Error():                 "Out of gas",
+	vm.ErrCodeStoreOutOfGas.Error():        "Out of gas",
+	vm.ErrInvalidJump.Error():              "Bad jump destination",
+	vm.ErrWriteProtection.Error():          "Mutable Call In Static Context",
+	vm.ErrInsufficientBalance.Error():      "Insufficient balance for transfer",
+	vm.ErrContractAddressCollision.Error(): "Contract address collision",
+}
+
+// parityError converts a call tracer error into the Parity one, if known.
+func parityError(err string) string {
+	if perr, ok := parityErrors[err]; ok {
+		return perr
+	}
+	switch {
+	case strings.HasPrefix(err, "invalid opcode"):
+		return "Bad instruction"
+	case strings.HasPrefix(err, "stack underflow"):
+		return "Stack underflow"
+	}
+	return err
+}
+
+// flattenCallFrame appends the flat traces of a call and its inner calls, in
+// depth first order, to the given ones.
+func flattenCallFrame(frame *callTraceFrame, address []int, traces []*FlatTrace) []*FlatTrace {
+	var (
+		from, to = frame.From, frame.To
+		gas      = frame.Gas
+		input    = frame.Input
+		output   = frame.Output
+		value    = frame.Value
+	)
+	if value == nil {
+		value = new(hexutil.Big)
+	}
+	trace := &FlatTrace{
+		Subtraces:    len(frame.Calls),
+		TraceAddress: address,
+	}
+	switch frame.Type {
+	case "CREATE", "CREATE2":
+		trace.Type = "create"
+		trace.Action = &FlatTraceAction{From: &from, Gas: &gas, Init: &input, Value: value}
+		if frame.Error == "" {
+			trace.Result = &FlatTraceResult{GasUsed: frame.GasUsed, Address: &to, Code: &output}
+		}
+	case "SELFDESTRUCT":
+		trace.Type = "suicide"
+		trace.Action = &FlatTraceAction{Address: &from, RefundAddress: &to, Balance: value}
+	default:
+		trace.Type = "call"
+		trace.Action = &FlatTraceAction{CallType: strings.ToLower(frame.Type), From: &from, To: &to, Gas: &gas, Input: &input, Value: value}
+		if frame.Error == "" {
+			trace.Result = &FlatTraceResult{GasUsed: frame.GasUsed, Output: &output}
+		}
+	}
+	if frame.Error != "" {
+		trace.Error = parityError(frame.Error)
+	}
+	traces = append(traces, trace)
+	for i, call := range frame.Calls {
+		traces = flattenCallFrame(call, append(append([]int{}, address...), i), traces)
+	}
+	return traces
+}
+
+// muxTracer forwards the tracing events to multiple tracers.
+type muxTracer []vm.Tracer
+
+func (t muxTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	for _, tracer := range t {
+		tracer.CaptureStart(env, from, to, create, input, gas, value)
+	}
+}
+
+func (t muxTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	for _, tracer := range t {
+		tracer.CaptureState(env, pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (t muxTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	for _, tracer := range t {
+		tracer.CaptureFault(env, pc, op, gas, cost, scope, depth, err)
+	}
+}
+
+func (t muxTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) {
+	for _, tracer := range t {
+		tracer.CaptureEnd(output, gasUsed, elapsed, err)
+	}
+}
+
+// replayedTx is the outcome of a transaction replayed with the flat tracers.
+type replayedTx struct {
+	hash   common.Hash
+	output []byte
+	traces []*FlatTrace
+	diff   StateDiff // Only set if requested
+}
+
+// replayTx executes a message on top of the given state, collecting its flat
+// call traces and, if requested, its state diff. The state is not finalised.
+func (api *TraceAPI) replayTx(ctx context.Context, msg core.Message, vmctx vm.BlockContext, statedb *state.StateDB, withDiff bool) (*replayedTx, error) {
+	calls, _ := newCallTracer(nil)
+	tracer := muxTracer{calls}
+
+	var prestate txTracer
+	if withDiff {
+		prestate, _ = newPrestateTracer(json.RawMessage(`{"diffMode":true}`))
+		prestate.(txStartTracer).CaptureTxStart(msg.Gas())
+		tracer = append(tracer, prestate)
+	}
+	vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(msg), statedb, api.api.backend.ChainConfig(), vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
+
+	// Abort the execution if the request is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			vmenv.Cancel()
+		case <-done:
+		}
+	}()
+	res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %w", err)
+	}
+	if vmenv.Cancelled() {
+		return nil, ctx.Err()
+	}
+	replayed := &replayedTx{output: res.Return()}
+	if res.Err == vm.ErrExecutionReverted {
+		replayed.output = res.Revert()
+	}
+	blob, err := calls.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	frame := new(callTraceFrame)
+	if err := json.Unmarshal(blob, frame); err != nil {
+		return nil, err
+	}
+	replayed.traces = flattenCallFrame(frame, []int{}, nil)
+
+	if withDiff {
+		blob, err := prestate.GetResult()
+		if err != nil {
+			return nil, err
+		}
+		diff := new(prestateDiff)
+		if err := json.Unmarshal(blob, diff); err != nil {
+			return nil, err
+		}
+		replayed.diff = newStateDiff(diff)
+	}
+	return replayed, nil
+}
+
+// replayBlock replays all the transactions of a block in order, collecting their
+// flat call traces and, if requested, their state diffs.
+func (api *TraceAPI) replayBlock(ctx context.Context, block *types.Block, withDiff bool) ([]*replayedTx, error) {
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent, err := api.api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := api.api.backend.StateAtBlock(ctx, parent, defaultTraceReexec, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		config   = api.api.backend.ChainConfig()
+		signer   = types.MakeSigner(config, block.Number())
+		blockCtx = core.NewEVMBlockContext(block.Header(), api.api.chainContext(ctx), nil)
+		txs      = block.Transactions()
+		results  = make([]*replayedTx, len(txs))
+	)
+	for i, tx := range txs {
+		msg, _ := tx.AsMessage(signer, block.BaseFee())
+		statedb.Prepare(tx.Hash(), i)
+
+		replayed, err := api.replayTx(ctx, msg, blockCtx, statedb, withDiff)
+		if err != nil {
+			return nil, err
+		}
+		replayed.hash = tx.Hash()
+		results[i] = replayed
+
+		// Finalize the state so any modifications are written to the trie
+		statedb.Finalise(config.IsEIP158(block.Number()))
+	}
+	return results, nil
+}
+
+// blockTraces returns the flat traces of the replayed transactions of a block,
+// annotated with their position in the chain.
+func blockTraces(block *types.Block, replayed []*replayedTx) []*FlatTrace {
+	var traces []*FlatTrace
+	for i, tx := range replayed {
+		traces = append(traces, annotateTraces(block, tx.hash, uint64(i), tx.traces)...)
+	}
+	return traces
+}
+
+// annotateTraces sets the position in the chain of the flat traces of a
+// transaction.
+func annotateTraces(block *types.Block, txHash common.Hash, position uint64, traces []*FlatTrace) []*FlatTrace {
+	hash, number := block.Hash(), block.NumberU64()
+	for _, trace := range traces {
+		trace.BlockHash, trace.BlockNumber = &hash, &number
+		trace.TransactionHash, trace.TransactionPosition = &txHash, &position
+	}
+	return traces
+}
+
+// Block returns the flat call traces of all the transactions of a block.
+func (api *TraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]*FlatTrace, error) {
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	replayed, err := api.replayBlock(ctx, block, false)
+	if err != nil {
+		return nil, err
+	}
+	traces := blockTraces(block, replayed)
+	if traces == nil {
+		traces = []*FlatTrace{}
+	}
+	return traces, nil
+}
+
+// Transaction returns the flat call traces of a transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*FlatTrace, error) {
+	tx, blockHash, blockNumber, index, err := api.api.backend.GetTransaction(ctx, hash)
+	if tx == nil {
+		// For BorTransaction, there will be no trace available
+		tx, _, _, _ = rawdb.ReadBorTransaction(api.api.backend.ChainDb(), hash)
+		if tx != nil {
+			return []*FlatTrace{}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	// It shouldn't happen in practice.
+	if blockNumber == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	block, err := api.api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	msg, vmctx, statedb, err := api.api.backend.StateAtTransaction(ctx, block, int(index), defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	statedb.Prepare(hash, int(index))
+
+	replayed, err := api.replayTx(ctx, msg, vmctx, statedb, false)
+	if err != nil {
+		return nil, err
+	}
+	return annotateTraces(block, hash, index, replayed.traces), nil
+}
+
+// ReplayBlockTransactions replays all the transactions of a block, returning the
+// requested trace types for each. Supported types are "trace" and "stateDiff".
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*TraceResults, error) {
+	var withTrace, withDiff bool
+	for _, typ := range traceTypes {
+		switch typ {
+		case "trace":
+			withTrace = true
+		case "stateDiff":
+			withDiff = true
+		default:
+			return nil, fmt.Errorf("unsupported trace type: %s", typ)
+		}
+	}
+	block, err := api.api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	replayed, err := api.replayBlock(ctx, block, withDiff)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*TraceResults, len(replayed))
+	for i, tx := range replayed {
+		results[i] = &TraceResults{
+			Output:          tx.output,
+			StateDiff:       tx.diff,
+			TransactionHash: tx.hash,
+		}
+		if withTrace {
+			results[i].Trace = tx.traces
+		}
+	}
+	return results, nil
+}
+
+// Filter returns the flat call traces of the transactions within a block range
+// matching the given addresses. Without a trace index, only a limited number of
+// blocks can be searched.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*FlatTrace, error) {
+	head, err := api.api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(number *rpc.BlockNumber, fallback uint64) uint64 {
+		switch {
+		case number == nil:
+			return fallback
+		case *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber:
+			return head.Number.Uint64()
+		case *number == rpc.EarliestBlockNumber:
+			return 0
+		}
+		return uint64(*number)
+	}
+	from, to := resolve(args.FromBlock, 0), resolve(args.ToBlock, head.Number.Uint64())
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to > head.Number.Uint64() {
+		to = head.Number.Uint64()
+	}
+	if from == 0 {
+		from = 1 // The genesis has no transactions
+	}
+	numbers, err := api.filterBlocks(&args, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		traces  = []*FlatTrace{}
+		skipped uint64
+	)
+	for _, number := range numbers {
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		replayed, err := api.replayBlock(ctx, block, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range blockTraces(block, replayed) {
+			if !args.matches(trace) {
+				continue
+			}
+			if args.After != nil && skipped < *args.After {
+				skipped++
+				continue
+			}
+			traces = append(traces, trace)
+			if args.Count != nil && uint64(len(traces)) >= *args.Count {
+				return traces, nil
+			}
+		}
+	}
+	return traces, nil
+}
+
+// filterBlocks returns the numbers of the blocks in the given range which may
+// contain traces matching the filter, looking the addresses up in the trace
+// index where available.
+func (api *TraceAPI) filterBlocks(args *TraceFilterArgs, from, to uint64) ([]uint64, error) {
+	// Find the range covered by the trace index, if addresses are filtered on
+	var indexed uint64
+	if len(args.FromAddress) > 0 || len(args.ToAddress) > 0 {
+		if backend, ok := api.api.backend.(traceIndexBackend); ok {
+			if indexer := backend.TraceIndexer(); indexer != nil {
+				sections, _, _ := indexer.Sections()
+				indexed = sections * traceIndexSectionSize
+			}
+		}
+	}
+	if from >= indexed && to-from+1 > traceFilterScanLimit {
+		return nil, fmt.Errorf("block range too large: %d blocks, max %d without trace index", to-from+1, traceFilterScanLimit)
+	}
+	if from < indexed && to >= indexed && to-indexed+1 > traceFilterScanLimit {
+		return nil, fmt.Errorf("unindexed block range too large: %d blocks, max %d", to-indexed+1, traceFilterScanLimit)
+	}
+	var numbers []uint64
+	if from < indexed {
+		last := to
+		if last >= indexed {
+			last = indexed - 1
+		}
+		// Blocks are indexed by both their senders and recipients, so it is
+		// enough to look up either list, preferring the shorter one
+		addrs := args.FromAddress
+		if len(addrs) == 0 || (len(args.ToAddress) > 0 && len(args.ToAddress) < len(addrs)) {
+			addrs = args.ToAddress
+		}
+		seen := make(map[uint64]struct{})
+		for _, addr := range addrs {
+			for _, number := range rawdb.ReadTraceIndexBlocks(api.api.backend.ChainDb(), addr, from, last) {
+				if _, ok := seen[number]; !ok {
+					seen[number] = struct{}{}
+					numbers = append(numbers, number)
+				}
+			}
+		}
+		sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+		from = indexed
+	}
+	for number := from; number <= to; number++ {
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
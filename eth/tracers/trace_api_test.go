@@ -0,0 +1,174 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Tests that call trees are flattened depth first, with the Parity style actions,
+// results and errors.
+func TestFlattenCallFrame(t *testing.T) {
+	blob := []byte(`{
+		"type": "CALL", "from": "0x00000000000000000000000000000000000000aa", "to": "0x00000000000000000000000000000000000000bb",
+		"value": "0x1", "gas": "0x100", "gasUsed": "0x80", "input": "0x01", "output": "0x02",
+		"calls": [
+			{
+				"type": "CREATE2", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000cc",
+				"value": "0x0", "gas": "0x50", "gasUsed": "0x40", "input": "0x03", "output": "0x04",
+				"calls": [
+					{"type": "SELFDESTRUCT", "from": "0x00000000000000000000000000000000000000cc", "to": "0x00000000000000000000000000000000000000aa", "value": "0x5"}
+				]
+			},
+			{
+				"type": "STATICCALL", "from": "0x00000000000000000000000000000000000000bb", "to": "0x00000000000000000000000000000000000000dd",
+				"gas": "0x10", "gasUsed": "0x10", "input": "0x", "error": "execution reverted"
+			}
+		]
+	}`)
+	frame := new(callTraceFrame)
+	if err := json.Unmarshal(blob, frame); err != nil {
+		t.Fatalf("failed to unmarshal call frame: %v", err)
+	}
+	traces := flattenCallFrame(frame, []int{}, nil)
+
+	var (
+		types     = []string{"call", "create", "suicide", "call"}
+		addresses = [][]int{{}, {0}, {0, 0}, {1}}
+		subtraces = []int{2, 1, 0, 0}
+		errs      = []string{"", "", "", "Reverted"}
+	)
+	if len(traces) != len(types) {
+		t.Fatalf("trace count mismatch: have %d, want %d", len(traces), len(types))
+	}
+	for i, trace := range traces {
+		if trace.Type != types[i] {
+			t.Errorf("trace %d: type mismatch: have %s, want %s", i, trace.Type, types[i])
+		}
+		if !reflect.DeepEqual(trace.TraceAddress, addresses[i]) {
+			t.Errorf("trace %d: trace address mismatch: have %v, want %v", i, trace.TraceAddress, addresses[i])
+		}
+		if trace.Subtraces != subtraces[i] {
+			t.Errorf("trace %d: subtraces mismatch: have %d, want %d", i, trace.Subtraces, subtraces[i])
+		}
+		if trace.Error != errs[i] {
+			t.Errorf("trace %d: error mismatch: have %q, want %q", i, trace.Error, errs[i])
+		}
+		if (trace.Error != "" || trace.Type == "suicide") != (trace.Result == nil) {
+			t.Errorf("trace %d: result presence mismatch: have %v", i, trace.Result)
+		}
+	}
+	if have := traces[0].Action.CallType; have != "call" {
+		t.Errorf("call type mismatch: have %s, want call", have)
+	}
+	if have := traces[1].Result.Address; have == nil || *have != common.HexToAddress("0xcc") {
+		t.Errorf("created address mismatch: have %v, want %x", have, common.HexToAddress("0xcc"))
+	}
+	if have := traces[2].Action; *have.Address != common.HexToAddress("0xcc") || *have.RefundAddress != common.HexToAddress("0xaa") || have.Balance.ToInt().Uint64() != 5 {
+		t.Errorf("self destruct action mismatch: have %+v", have)
+	}
+	if have := traces[3].Action.Value; have == nil || have.ToInt().Sign() != 0 {
+		t.Errorf("static call value mismatch: have %v, want 0x0", have)
+	}
+	// Addresses are those of the initiating and target accounts
+	if from, to := traces[2].addresses(); *from != common.HexToAddress("0xcc") || *to != common.HexToAddress("0xaa") {
+		t.Errorf("self destruct addresses mismatch: have %x, %x", *from, *to)
+	}
+}
+
+// Tests that prestate diffs are converted into Parity style state diffs.
+func TestNewStateDiff(t *testing.T) {
+	var (
+		one, two = uint64(1), uint64(2)
+		code     = hexutil.Bytes{0x60}
+		slot     = common.HexToHash("0x01")
+	)
+	diff := &prestateDiff{
+		Pre: map[common.Address]*prestateDiffAccount{
+			common.HexToAddress("0xaa"): {Balance: (*hexutil.Big)(hexutil.MustDecodeBig("0x10")), Nonce: &one},
+			common.HexToAddress("0xbb"): {Balance: (*hexutil.Big)(hexutil.MustDecodeBig("0x5")), Nonce: &one, Code: &code, Storage: map[common.Hash]common.Hash{slot: slot}},
+		},
+		Post: map[common.Address]*prestateDiffAccount{
+			common.HexToAddress("0xaa"): {Balance: (*hexutil.Big)(hexutil.MustDecodeBig("0x8")), Nonce: &two},
+			common.HexToAddress("0xcc"): {Balance: (*hexutil.Big)(hexutil.MustDecodeBig("0x5"))},
+		},
+	}
+	blob, err := json.Marshal(newStateDiff(diff))
+	if err != nil {
+		t.Fatalf("failed to marshal state diff: %v", err)
+	}
+	var have, want map[string]interface{}
+	if err := json.Unmarshal(blob, &have); err != nil {
+		t.Fatalf("failed to unmarshal state diff: %v", err)
+	}
+	expected := `{
+		"0x00000000000000000000000000000000000000aa": {
+			"balance": {"*": {"from": "0x10", "to": "0x8"}},
+			"nonce": {"*": {"from": "0x1", "to": "0x2"}},
+			"code": "=",
+			"storage": {}
+		},
+		"0x00000000000000000000000000000000000000bb": {
+			"balance": {"-": "0x5"},
+			"nonce": {"-": "0x1"},
+			"code": {"-": "0x60"},
+			"storage": {"0x0000000000000000000000000000000000000000000000000000000000000001": {"-": "0x0000000000000000000000000000000000000000000000000000000000000001"}}
+		},
+		"0x00000000000000000000000000000000000000cc": {
+			"balance": {"+": "0x5"},
+			"nonce": {"+": "0x0"},
+			"code": {"+": "0x"},
+			"storage": {}
+		}
+	}`
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		t.Fatalf("failed to unmarshal expected state diff: %v", err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("state diff mismatch:\nhave %s\nwant %s", blob, expected)
+	}
+}
+
+// Tests that trace filters match the senders and the recipients of the traces.
+func TestTraceFilterMatches(t *testing.T) {
+	var (
+		a, b, c = common.HexToAddress("0xaa"), common.HexToAddress("0xbb"), common.HexToAddress("0xcc")
+		trace   = &FlatTrace{Type: "call", Action: &FlatTraceAction{From: &a, To: &b}}
+	)
+	tests := []struct {
+		from, to []common.Address
+		match    bool
+	}{
+		{nil, nil, true},
+		{[]common.Address{a}, nil, true},
+		{nil, []common.Address{b}, true},
+		{[]common.Address{c, a}, []common.Address{b}, true},
+		{[]common.Address{b}, nil, false},
+		{[]common.Address{a}, []common.Address{c}, false},
+	}
+	for i, tt := range tests {
+		args := &TraceFilterArgs{FromAddress: tt.from, ToAddress: tt.to}
+		if have := args.matches(trace); have != tt.match {
+			t.Errorf("test %d: match mismatch: have %v, want %v", i, have, tt.match)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// traceCacheChanSize is the size of the channels listening to chain events.
+	traceCacheChanSize = 16
+
+	// traceCacheQueueSize is the number of new blocks waiting to be traced in the
+	// background, newer blocks being skipped while it is full.
+	traceCacheQueueSize = 16
+)
+
+// traceCacheBackend is implemented by backends caching trace results.
+type traceCacheBackend interface {
+	TraceCache() *TraceCache
+}
+
+// traceCacheChain is the chain the trace cache follows to trace the new blocks
+// and evict the reorged ones.
+type traceCacheChain interface {
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+	SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription
+}
+
+// TraceCacheConfig are the options of the trace result cache.
+type TraceCacheConfig struct {
+	Tracers  []string // Tracers to trace each new canonical block with in the background
+	Blocks   uint64   // Number of recent blocks to retain the trace results of, zero for all
+	TxBlocks bool     // Whether to trace the whole block of a transaction missing from the cache
+}
+
+// TraceCache is a persistent cache of the results of block traces, keyed by the
+// block hash and the tracer configuration. It is populated whenever a block is
+// traced with a named built-in tracer, optionally by tracing each new canonical
+// block in the background, and by tracing the whole block of the transactions
+// traced if enabled. Results of blocks reorged out are evicted.
+//
+// Struct logger results are not cached, being too large to be worth storing, nor
+// are the ones of JavaScript code, which would be keyed by arbitrary code.
+type TraceCache struct {
+	config TraceCacheConfig
+	db     ethdb.Database
+	api    *API
+
+	queue  chan *types.Block // New blocks to trace in the background
+	ctx    context.Context   // Context cancelling background tracing on stop
+	cancel context.CancelFunc
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewTraceCache creates a trace result cache, storing the results into the
+// database of the backend.
+func NewTraceCache(backend Backend, config TraceCacheConfig) *TraceCache {
+	// Only the results of named tracers are cached, don't trace with the others
+	tracers := make([]string, 0, len(config.Tracers))
+	for _, tracer := range config.Tracers {
+		if !namedTracer(tracer) {
+			log.Warn("Skipping background tracing with unknown tracer", "tracer", tracer)
+			continue
+		}
+		tracers = append(tracers, tracer)
+	}
+	config.Tracers = tracers
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TraceCache{
+		config: config,
+		db:     backend.ChainDb(),
+		api:    NewAPI(backend),
+		queue:  make(chan *types.Block, traceCacheQueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start starts following the chain, tracing the new blocks in the background and
+// evicting the results of the reorged ones.
+func (c *TraceCache) Start(chain traceCacheChain) {
+	var (
+		chainCh = make(chan core.ChainEvent, traceCacheChanSize)
+		sideCh  = make(chan core.ChainSideEvent, traceCacheChanSize)
+	)
+	chainSub := chain.SubscribeChainEvent(chainCh)
+	sideSub := chain.SubscribeChainSideEvent(sideCh)
+
+	c.wg.Add(2)
+	go c.eventLoop(chainCh, sideCh, chainSub, sideSub)
+	go c.traceLoop()
+}
+
+// Stop stops following the chain, aborting any background trace.
+func (c *TraceCache) Stop() {
+	c.cancel()
+	close(c.quit)
+	c.wg.Wait()
+}
+
+// eventLoop evicts the results of the blocks reorged out and of the blocks past
+// the retention limit, and schedules the new blocks for background tracing.
+func (c *TraceCache) eventLoop(chainCh chan core.ChainEvent, sideCh chan core.ChainSideEvent, chainSub, sideSub event.Subscription) {
+	defer c.wg.Done()
+	defer chainSub.Unsubscribe()
+	defer sideSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-chainCh:
+			number := ev.Block.NumberU64()
+			if c.config.Blocks > 0 && number >= c.config.Blocks {
+				rawdb.DeleteTraceResultsBelow(c.db, number-c.config.Blocks+1)
+			}
+			if len(c.config.Tracers) == 0 {
+				continue
+			}
+			select {
+			case c.queue <- ev.Block:
+			default:
+				log.Debug("Skipping background trace of busy block", "number", number, "hash", ev.Hash)
+			}
+
+		case ev := <-sideCh:
+			rawdb.DeleteTraceResults(c.db, ev.Block.NumberU64(), ev.Block.Hash())
+
+		case <-chainSub.Err():
+			return
+		case <-sideSub.Err():
+			return
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// traceLoop traces the scheduled blocks with the configured tracers, caching
+// the results.
+func (c *TraceCache) traceLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case block := <-c.queue:
+			for _, tracer := range c.config.Tracers {
+				tracer := tracer
+				if _, err := c.api.traceBlock(c.ctx, block, &TraceConfig{Tracer: &tracer}); err != nil {
+					log.Debug("Failed to trace block in the background", "number", block.NumberU64(), "hash", block.Hash(), "tracer", tracer, "err", err)
+				}
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// traceConfigHash returns the hash identifying the results of a trace
+// configuration, or false if they are not cached.
+func traceConfigHash(config *TraceConfig) (common.Hash, bool) {
+	if config == nil || config.Tracer == nil || !namedTracer(*config.Tracer) {
+		return common.Hash{}, false
+	}
+	// Only the tracer and its options affect the results
+	var tracerConfig bytes.Buffer
+	if len(config.TracerConfig) > 0 {
+		if err := json.Compact(&tracerConfig, config.TracerConfig); err != nil {
+			return common.Hash{}, false
+		}
+	}
+	blob, err := json.Marshal(&struct {
+		Tracer       string          `json:"tracer"`
+		TracerConfig json.RawMessage `json:"tracerConfig,omitempty"`
+	}{*config.Tracer, tracerConfig.Bytes()})
+	if err != nil {
+		return common.Hash{}, false
+	}
+	return crypto.Keccak256Hash(blob), true
+}
+
+// cachedTraceResult is a trace result as stored in the cache.
+type cachedTraceResult struct {
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// get retrieves the cached trace results of a block, if any.
+func (c *TraceCache) get(block *types.Block, config *TraceConfig) ([]*txTraceResult, bool) {
+	hash, ok := traceConfigHash(config)
+	if !ok {
+		return nil, false
+	}
+	blob := rawdb.ReadTraceResults(c.db, block.NumberU64(), block.Hash(), hash)
+	if len(blob) == 0 {
+		return nil, false
+	}
+	var cached []*cachedTraceResult
+	if err := json.Unmarshal(blob, &cached); err != nil {
+		log.Warn("Failed to decode cached trace results", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+		return nil, false
+	}
+	results := make([]*txTraceResult, len(cached))
+	for i, result := range cached {
+		results[i] = &txTraceResult{Result: result.Result}
+	}
+	return results, true
+}
+
+// put caches the trace results of a block, unless tracing any of its
+// transactions failed.
+func (c *TraceCache) put(block *types.Block, config *TraceConfig, results []*txTraceResult) {
+	hash, ok := traceConfigHash(config)
+	if !ok {
+		return
+	}
+	for _, result := range results {
+		if result.Error != "" {
+			return
+		}
+	}
+	blob, err := json.Marshal(results)
+	if err != nil {
+		log.Warn("Failed to encode trace results", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+		return
+	}
+	rawdb.WriteTraceResults(c.db, block.NumberU64(), block.Hash(), hash, blob)
+}
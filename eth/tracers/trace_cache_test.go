@@ -0,0 +1,105 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that only the results of named tracers are cached, identified by the
+// tracer and its options.
+func TestTraceConfigHash(t *testing.T) {
+	var (
+		call    = "callTracer"
+		opcount = "opcountTracer"
+		code    = "{count: 0, step: function() { this.count++ }, fault: function() {}, result: function() { return this.count }}"
+		timeout = "10s"
+		reexec  = uint64(1000)
+	)
+	if _, ok := traceConfigHash(nil); ok {
+		t.Errorf("struct logger results cacheable")
+	}
+	if _, ok := traceConfigHash(&TraceConfig{Timeout: &timeout}); ok {
+		t.Errorf("struct logger results cacheable")
+	}
+	if _, ok := traceConfigHash(&TraceConfig{Tracer: &code}); ok {
+		t.Errorf("javascript code results cacheable")
+	}
+	if _, ok := traceConfigHash(&TraceConfig{Tracer: &opcount}); !ok {
+		t.Errorf("javascript built-in tracer results not cacheable")
+	}
+	base, ok := traceConfigHash(&TraceConfig{Tracer: &call})
+	if !ok {
+		t.Fatalf("tracer results not cacheable")
+	}
+	if hash, _ := traceConfigHash(&TraceConfig{Tracer: &call, Timeout: &timeout, Reexec: &reexec}); hash != base {
+		t.Errorf("execution options changed the config hash")
+	}
+	diffA, _ := traceConfigHash(&TraceConfig{Tracer: &call, TracerConfig: json.RawMessage(`{"diffMode": true}`)})
+	diffB, _ := traceConfigHash(&TraceConfig{Tracer: &call, TracerConfig: json.RawMessage(`{"diffMode":true}`)})
+	if diffA != diffB {
+		t.Errorf("tracer config formatting changed the config hash")
+	}
+	if diffA == base {
+		t.Errorf("tracer config didn't change the config hash")
+	}
+}
+
+// Tests that trace results are cached per block and tracer, unless tracing any
+// transaction failed.
+func TestTraceCacheGetPut(t *testing.T) {
+	var (
+		cache   = &TraceCache{db: rawdb.NewMemoryDatabase()}
+		blockA  = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+		blockB  = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Extra: []byte{1}})
+		call    = "callTracer"
+		four    = "4byteTracer"
+		config  = &TraceConfig{Tracer: &call}
+		results = []*txTraceResult{{Result: json.RawMessage(`{"type":"CALL"}`)}, {Result: map[string]int{"a": 1}}}
+	)
+	cache.put(blockA, config, results)
+	cache.put(blockA, nil, results)
+	cache.put(blockB, config, []*txTraceResult{{Error: "execution timeout"}})
+
+	have, ok := cache.get(blockA, config)
+	if !ok {
+		t.Fatalf("cached results not found")
+	}
+	want, _ := json.Marshal(results)
+	if blob, _ := json.Marshal(have); string(blob) != string(want) {
+		t.Errorf("cached results mismatch: have %s, want %s", blob, want)
+	}
+	if _, ok := cache.get(blockA, &TraceConfig{Tracer: &four}); ok {
+		t.Errorf("results of another tracer returned")
+	}
+	if _, ok := cache.get(blockA, nil); ok {
+		t.Errorf("struct logger results cached")
+	}
+	if _, ok := cache.get(blockB, config); ok {
+		t.Errorf("failed results cached")
+	}
+	// Evicting the block on reorg must drop its results
+	rawdb.DeleteTraceResults(cache.db, blockA.NumberU64(), blockA.Hash())
+	if _, ok := cache.get(blockA, config); ok {
+		t.Errorf("evicted results returned")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// traceIndexSectionSize is the number of blocks in a trace index section.
+	traceIndexSectionSize = 256
+
+	// traceIndexConfirms is the number of confirmation blocks before a trace
+	// index section is considered final.
+	traceIndexConfirms = 128
+
+	// traceIndexThrottling is the time to wait between processing two consecutive
+	// index sections, as tracing them is expensive.
+	traceIndexThrottling = 100 * time.Millisecond
+)
+
+// TraceIndexer implements a core.ChainIndexer, indexing the blocks by the
+// addresses appearing in their call traces, permitting trace_filter to search
+// the whole chain. It needs the historical state to be available.
+//
+// Entries of blocks reorged out are not removed, as they only cause a block to
+// be searched needlessly.
+type TraceIndexer struct {
+	api   *TraceAPI
+	db    ethdb.Database // database instance to write index data into
+	batch ethdb.Batch    // batch of index entries of the section being processed
+}
+
+// NewTraceIndexer returns a chain indexer that generates the trace index of the
+// canonical chain.
+func NewTraceIndexer(backend Backend) *core.ChainIndexer {
+	indexer := &TraceIndexer{
+		api: NewTraceAPI(backend),
+		db:  backend.ChainDb(),
+	}
+	table := rawdb.NewTable(backend.ChainDb(), string(rawdb.TraceIndexPrefix))
+
+	return core.NewChainIndexer(backend.ChainDb(), table, indexer, traceIndexSectionSize, traceIndexConfirms, traceIndexThrottling, "traces")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new trace index section.
+func (t *TraceIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	t.batch = t.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, tracing the block of a header and
+// adding the addresses of its traces into the index.
+func (t *TraceIndexer) Process(ctx context.Context, header *types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	block, err := t.api.api.backend.BlockByHash(ctx, header.Hash())
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return fmt.Errorf("block #%d %x not found", number, header.Hash())
+	}
+	replayed, err := t.api.replayBlock(ctx, block, false)
+	if err != nil {
+		return err
+	}
+	seen := make(map[common.Address]struct{})
+	for _, tx := range replayed {
+		for _, trace := range tx.traces {
+			from, to := trace.addresses()
+			for _, addr := range []*common.Address{from, to} {
+				if addr == nil {
+					continue
+				}
+				if _, ok := seen[*addr]; !ok {
+					seen[*addr] = struct{}{}
+					rawdb.WriteTraceIndexEntry(t.batch, *addr, number)
+				}
+			}
+		}
+	}
+	if t.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := t.batch.Write(); err != nil {
+			return err
+		}
+		t.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the remaining entries of
+// the section into the database.
+func (t *TraceIndexer) Commit() error {
+	return t.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (t *TraceIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -41,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
@@ -81,11 +82,68 @@ func (s *PublicEthereumAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.
 	return (*hexutil.Big)(tipcap), err
 }
 
+// feeRecommendation is a fee setting suggested for dynamic fee transactions.
+type feeRecommendation struct {
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+}
+
+type gasPriceRecommendations struct {
+	BlockNumber     hexutil.Uint64     `json:"blockNumber"`
+	BaseFee         *hexutil.Big       `json:"baseFeePerGas,omitempty"`
+	BaseFeeForecast []*hexutil.Big     `json:"baseFeeForecast,omitempty"`
+	MinTipCap       *hexutil.Big       `json:"minPriorityFeePerGas,omitempty"`
+	Slow            *feeRecommendation `json:"slow"`
+	Standard        *feeRecommendation `json:"standard"`
+	Fast            *feeRecommendation `json:"fast"`
+}
+
+// GasPriceRecommendations returns the fees suggested for dynamic fee transactions
+// to be included slowly, in a standard time or fast. The priority fees are the
+// percentiles of those paid by recent transactions, never below the minimum
+// validators accept, and the fee caps cover the base fees forecast for the next
+// blocks should they all be full.
+func (s *PublicEthereumAPI) GasPriceRecommendations(ctx context.Context) (*gasPriceRecommendations, error) {
+	recs, err := s.b.GasPriceRecommendations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recommend := func(rec gasprice.Recommendation) *feeRecommendation {
+		return &feeRecommendation{
+			MaxPriorityFeePerGas: (*hexutil.Big)(rec.TipCap),
+			MaxFeePerGas:         (*hexutil.Big)(rec.FeeCap),
+		}
+	}
+	results := &gasPriceRecommendations{
+		BlockNumber:     hexutil.Uint64(recs.Number),
+		BaseFee:         (*hexutil.Big)(recs.BaseFee),
+		BaseFeeForecast: toHexBigs(recs.BaseFeeForecast),
+		MinTipCap:       (*hexutil.Big)(recs.MinTipCap),
+		Slow:            recommend(recs.Slow),
+		Standard:        recommend(recs.Standard),
+		Fast:            recommend(recs.Fast),
+	}
+	return results, nil
+}
+
+// toHexBigs converts a list of big integers for JSON encoding.
+func toHexBigs(values []*big.Int) []*hexutil.Big {
+	if values == nil {
+		return nil
+	}
+	results := make([]*hexutil.Big, len(values))
+	for i, v := range values {
+		results[i] = (*hexutil.Big)(v)
+	}
+	return results
+}
+
 type feeHistoryResult struct {
-	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
-	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
-	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
-	GasUsedRatio []float64        `json:"gasUsedRatio"`
+	OldestBlock     *hexutil.Big     `json:"oldestBlock"`
+	Reward          [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee         []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio    []float64        `json:"gasUsedRatio"`
+	BaseFeeForecast []*hexutil.Big   `json:"baseFeeForecast,omitempty"`
 }
 
 func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount rpc.DecimalOrHex, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
@@ -111,6 +169,15 @@ func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount rpc.Decim
 		for i, v := range baseFee {
 			results.BaseFee[i] = (*hexutil.Big)(v)
 		}
+		// Extend the base fees past the block following the newest one, with the
+		// upper bounds reached if the next blocks are all full
+		newest := oldest.Uint64() + uint64(len(gasUsed)) - 1
+		if header, _ := s.b.HeaderByNumber(ctx, rpc.BlockNumber(newest)); header != nil {
+			forecast := gasprice.ForecastBaseFees(s.b.ChainConfig(), header, gasprice.BaseFeeForecastBlocks+1)
+			if len(forecast) > 1 {
+				results.BaseFeeForecast = toHexBigs(forecast[1:])
+			}
+		}
 	}
 	return results, nil
 }
@@ -161,7 +228,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -169,7 +236,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -185,14 +252,14 @@ func (s *PublicTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string
 	// Build the pending transactions
 	dump := make(map[string]*RPCTransaction, len(pending))
 	for _, tx := range pending {
-		dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+		dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
 	}
 	content["pending"] = dump
 
 	// Build the queued transactions
 	dump = make(map[string]*RPCTransaction, len(queue))
 	for _, tx := range queue {
-		dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+		dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
 	}
 	content["queued"] = dump
 
@@ -243,6 +310,67 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 
+// RPCDroppedTransaction is the notification of a transaction removed from the
+// transaction pool without being included into a block.
+type RPCDroppedTransaction struct {
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	Reason     string         `json:"reason"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+}
+
+// NewRPCDroppedTransaction returns a dropped transaction notification that will
+// serialize to the RPC representation.
+func NewRPCDroppedTransaction(drop core.TxDropEvent, signer types.Signer) *RPCDroppedTransaction {
+	from, _ := types.Sender(signer, drop.Tx)
+	result := &RPCDroppedTransaction{
+		Hash:   drop.Tx.Hash(),
+		From:   from,
+		Nonce:  hexutil.Uint64(drop.Tx.Nonce()),
+		Reason: string(drop.Reason),
+	}
+	if drop.Replacement != nil {
+		hash := drop.Replacement.Hash()
+		result.ReplacedBy = &hash
+	}
+	return result
+}
+
+// Dropped creates a subscription that is triggered each time a transaction is
+// removed from the transaction pool without being included, with the reason:
+// replaced, underpriced, evicted, expired or invalidated.
+func (s *PublicTxPoolAPI) Dropped(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		var (
+			signer  = types.LatestSigner(s.b.ChainConfig())
+			drops   = make(chan core.TxDropEvent, 128)
+			dropSub = s.b.SubscribeDropTxsEvent(drops)
+		)
+		defer dropSub.Unsubscribe()
+
+		for {
+			select {
+			case drop := <-drops:
+				notifier.Notify(rpcSub.ID, NewRPCDroppedTransaction(drop, signer))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-dropSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -968,7 +1096,64 @@ func (diff *StateOverride) Apply(state *state.StateDB) error {
 	return nil
 }
 
-func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
+// BlockOverrides is the collection of block context fields overridden during the
+// execution of message calls.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	Time       *hexutil.Uint64 `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+	BaseFee    *hexutil.Big    `json:"baseFee"`
+}
+
+// Apply overrides the specified fields of the given block context.
+func (diff *BlockOverrides) Apply(blockCtx *vm.BlockContext) {
+	if diff == nil {
+		return
+	}
+	if diff.Number != nil {
+		blockCtx.BlockNumber = diff.Number.ToInt()
+	}
+	if diff.Difficulty != nil {
+		blockCtx.Difficulty = diff.Difficulty.ToInt()
+	}
+	if diff.Time != nil {
+		blockCtx.Time = new(big.Int).SetUint64(uint64(*diff.Time))
+	}
+	if diff.GasLimit != nil {
+		blockCtx.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		blockCtx.Coinbase = *diff.Coinbase
+	}
+	if diff.BaseFee != nil {
+		blockCtx.BaseFee = diff.BaseFee.ToInt()
+	}
+}
+
+// baseFee returns the base fee the calls are executed with.
+func (diff *BlockOverrides) baseFee(header *types.Header) *big.Int {
+	if diff != nil && diff.BaseFee != nil {
+		return diff.BaseFee.ToInt()
+	}
+	return header.BaseFee
+}
+
+// newCallEVM creates an EVM executing a message call on top of the given state
+// and header, with the block context overridden if requested.
+func newCallEVM(ctx context.Context, b Backend, msg core.Message, state *state.StateDB, header *types.Header, blockOverrides *BlockOverrides) (*vm.EVM, func() error, error) {
+	evm, vmError, err := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true})
+	if err != nil || blockOverrides == nil {
+		return evm, vmError, err
+	}
+	// Recreate the EVM so that the chain rules follow the overridden number
+	blockCtx := evm.Context
+	blockOverrides.Apply(&blockCtx)
+	return vm.NewEVM(blockCtx, evm.TxContext, state, b.ChainConfig(), evm.Config), vmError, nil
+}
+
+func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
 	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
@@ -991,11 +1176,11 @@ func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash
 	defer cancel()
 
 	// Get a new instance of the EVM.
-	msg, err := args.ToMessage(globalGasCap, header.BaseFee)
+	msg, err := args.ToMessage(globalGasCap, blockOverrides.baseFee(header))
 	if err != nil {
 		return nil, err
 	}
-	evm, vmError, err := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true})
+	evm, vmError, err := newCallEVM(ctx, b, msg, state, header, blockOverrides)
 	if err != nil {
 		return nil, err
 	}
@@ -1055,12 +1240,13 @@ func (e *revertError) ErrorData() interface{} {
 
 // Call executes the given transaction on the state for the given block number.
 //
-// Additionally, the caller can specify a batch of contract for fields overriding.
+// Additionally, the caller can specify a batch of contract for fields overriding,
+// as well as block context fields to override.
 //
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
-	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, 5*time.Second, s.b.RPCGasCap())
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) (hexutil.Bytes, error) {
+	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, blockOverrides, 5*time.Second, s.b.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
@@ -1071,6 +1257,124 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args TransactionArgs, bl
 	return result.Return(), result.Err
 }
 
+// CallResult is the outcome of a single call of CallMany.
+type CallResult struct {
+	ReturnValue hexutil.Bytes  `json:"returnValue"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// maxCallManyCalls is the maximum number of calls executed in a sequence.
+const maxCallManyCalls = 1024
+
+// DoCallMany executes the given calls one after the other on top of the state of
+// the given block, each call seeing the state changes of the previous ones. A
+// failing call doesn't abort the sequence, its error being reported instead.
+//
+// The whole sequence runs within the timeout, and the calls share the global
+// gas cap, the gas used by each being deducted from the allowance of the next.
+func DoCallMany(ctx context.Context, b Backend, calls []TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides, timeout time.Duration, globalGasCap uint64) ([]*CallResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Executing EVM calls finished", "calls", len(calls), "runtime", time.Since(start))
+	}(time.Now())
+
+	if len(calls) > maxCallManyCalls {
+		return nil, fmt.Errorf("too many calls: %d > %d", len(calls), maxCallManyCalls)
+	}
+	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled the calls have completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		evm     *vm.EVM
+		vmError func() error
+		gasCap  = globalGasCap
+		results = make([]*CallResult, len(calls))
+	)
+	for i, args := range calls {
+		if globalGasCap != 0 && gasCap == 0 {
+			return nil, fmt.Errorf("call %d: gas cap %d exhausted", i, globalGasCap)
+		}
+		msg, err := args.ToMessage(gasCap, blockOverrides.baseFee(header))
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		// Execute all the calls with the same EVM, aborted once the time is up
+		if evm == nil {
+			if evm, vmError, err = newCallEVM(ctx, b, msg, state, header, blockOverrides); err != nil {
+				return nil, err
+			}
+			go func() {
+				<-ctx.Done()
+				evm.Cancel()
+			}()
+		} else {
+			evm.Reset(core.NewEVMTxContext(msg), state)
+		}
+		result, err := doSequentialCall(evm, vmError, msg, state, i, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		if globalGasCap != 0 {
+			gasCap -= uint64(result.GasUsed)
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// doSequentialCall executes the call with the given index of a sequence on top of
+// the state left by the previous calls.
+func doSequentialCall(evm *vm.EVM, vmError func() error, msg core.Message, state *state.StateDB, index int, timeout time.Duration) (*CallResult, error) {
+	state.Prepare(common.Hash{}, index)
+
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	if err := vmError(); err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+	}
+	if err != nil {
+		return &CallResult{Error: fmt.Sprintf("err: %v (supplied gas %d)", err, msg.Gas())}, nil
+	}
+	// Finalize the state so the following calls see the changes
+	state.Finalise(evm.ChainConfig().IsEIP158(evm.Context.BlockNumber))
+
+	res := &CallResult{ReturnValue: result.Return(), GasUsed: hexutil.Uint64(result.UsedGas)}
+	if len(result.Revert()) > 0 {
+		res.ReturnValue = result.Revert()
+		res.Error = newRevertError(result).Error()
+	} else if result.Err != nil {
+		res.Error = result.Err.Error()
+	}
+	return res, nil
+}
+
+// CallMany executes the given calls one after the other on the state of the given
+// block, each call seeing the state changes of the previous ones. The state and
+// block overrides apply to the whole sequence, which shares the gas cap and the
+// timeout of a single call.
+//
+// Note, this function doesn't make and changes in the state/blockchain and is
+// useful to simulate multi-step interactions.
+func (s *PublicBlockChainAPI) CallMany(ctx context.Context, calls []TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, blockOverrides *BlockOverrides) ([]*CallResult, error) {
+	return DoCallMany(ctx, s.b, calls, blockNrOrHash, overrides, blockOverrides, 5*time.Second, s.b.RPCGasCap())
+}
+
 func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
@@ -1145,7 +1449,7 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
 		args.Gas = (*hexutil.Uint64)(&gas)
 
-		result, err := DoCall(ctx, b, args, blockNrOrHash, nil, 0, gasCap)
+		result, err := DoCall(ctx, b, args, blockNrOrHash, nil, nil, 0, gasCap)
 		if err != nil {
 			if errors.Is(err, core.ErrIntrinsicGas) {
 				return true, nil, nil // Special case, raise gas limit
@@ -1428,8 +1732,8 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction, current *types.Header, config *params.ChainConfig) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction, current *types.Header, config *params.ChainConfig) *RPCTransaction {
 	var baseFee *big.Int
 	if current != nil {
 		baseFee = misc.CalcBaseFee(config, current)
@@ -1680,7 +1984,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx, s.b.CurrentHeader(), s.b.ChainConfig()), nil
+		return NewRPCPendingTransaction(tx, s.b.CurrentHeader(), s.b.ChainConfig()), nil
 	}
 
 	// Transaction unknown, return as such
@@ -1795,6 +2099,16 @@ func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transacti
 
 // SubmitTransaction is a helper function that submits tx to txPool and logs a message.
 func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	return submitTransaction(ctx, b, tx, false)
+}
+
+// SubmitPrivateTransaction is a helper function that submits tx to txPool, to be
+// kept out of the network until its private lifetime expires, and logs a message.
+func SubmitPrivateTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	return submitTransaction(ctx, b, tx, true)
+}
+
+func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, private bool) (common.Hash, error) {
 	// If the transaction fee cap is already specified, ensure the
 	// fee of the given transaction is _reasonable_.
 	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
@@ -1804,7 +2118,11 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
 		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
 	}
-	if err := b.SendTx(ctx, tx); err != nil {
+	send := b.SendTx
+	if private {
+		send = b.SendPrivateTx
+	}
+	if err := send(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
 	// Print a log with full tx details for manual investigations and interventions
@@ -1816,9 +2134,9 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 
 	if tx.To() == nil {
 		addr := crypto.CreateAddress(from, tx.Nonce())
-		log.Info("Submitted contract creation", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "contract", addr.Hex(), "value", tx.Value())
+		log.Info("Submitted contract creation", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "contract", addr.Hex(), "value", tx.Value(), "private", private)
 	} else {
-		log.Info("Submitted transaction", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value())
+		log.Info("Submitted transaction", "hash", tx.Hash().Hex(), "from", from, "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value(), "private", private)
 	}
 	return tx.Hash(), nil
 }
@@ -1882,6 +2200,45 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, input
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendPrivateRawTransaction will add the signed transaction to the transaction
+// pool without broadcasting it to the network, keeping it minable by the local
+// node only. Once the configured private lifetime expires the transaction is
+// either dropped or broadcast, depending on the node's configuration.
+func (s *PublicTransactionPoolAPI) SendPrivateRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	return SubmitPrivateTransaction(ctx, s.b, tx)
+}
+
+// SendRawTransactionConditional will add the signed transaction to the
+// transaction pool along with preconditions on the block including it: block
+// number and timestamp ranges, and the expected storage of some accounts, which
+// must match the pending state. The transaction is kept out of the network, only
+// included while its expected storage matches, and dropped once its block number
+// or timestamp range ends.
+func (s *PublicTransactionPoolAPI) SendRawTransactionConditional(ctx context.Context, input hexutil.Bytes, options types.TransactionConditional) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := options.Validate(); err != nil {
+		return common.Hash{}, err
+	}
+	if len(options.KnownAccounts) > 0 {
+		state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.PendingBlockNumber)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if err := state.ValidateKnownAccounts(options.KnownAccounts); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	tx.SetConditional(&options)
+	return SubmitTransaction(ctx, s.b, tx)
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -1963,7 +2320,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		from, _ := types.Sender(s.signer, tx)
 		if _, exists := accounts[from]; exists {
-			transactions = append(transactions, newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig()))
+			transactions = append(transactions, NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig()))
 		}
 	}
 	return transactions, nil
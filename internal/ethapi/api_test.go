@@ -0,0 +1,338 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// counterCode adds its calldata word to the value in slot 0, storing and
+	// returning the sum.
+	counterCode = common.FromHex("0x6000356000540160005560005460005260206000f3")
+
+	// revertCode reverts unconditionally.
+	revertCode = common.FromHex("0x60006000fd")
+
+	// loopCode loops until running out of gas.
+	loopCode = common.FromHex("0x5b600056")
+
+	// contextCode returns the number, time, coinbase, difficulty, gas limit and
+	// base fee of the block it's executed in.
+	contextCode = common.FromHex("0x43600052426020524160405244606052456080524860a05260c06000f3")
+
+	// logCode emits a log without topics nor data.
+	logCode = common.FromHex("0x60006000a000")
+)
+
+var (
+	counterAddr = common.HexToAddress("0xaa")
+	revertAddr  = common.HexToAddress("0xbb")
+	loopAddr    = common.HexToAddress("0xcc")
+	contextAddr = common.HexToAddress("0xdd")
+	logAddr     = common.HexToAddress("0xee")
+)
+
+// testBackend is a Backend serving a chain kept in memory. Only the methods used
+// by the tested APIs are implemented, the others panicking.
+type testBackend struct {
+	Backend
+
+	chainConfig *params.ChainConfig
+	engine      consensus.Engine
+	chain       *core.BlockChain
+	gasCap      uint64
+}
+
+func newTestBackend(t *testing.T, config *params.ChainConfig, engine consensus.Engine, n int, alloc core.GenesisAlloc, generator func(i int, b *core.BlockGen)) *testBackend {
+	var (
+		gspec   = &core.Genesis{Config: config, Alloc: alloc, GasLimit: 30_000_000}
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(gendb)
+	)
+	blocks, _ := core.GenerateChain(config, genesis, engine, gendb, n, generator)
+
+	// Import the chain, keeping the state of all its blocks
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	return &testBackend{chainConfig: config, engine: engine, chain: chain, gasCap: 25_000_000}
+}
+
+func (b *testBackend) RPCGasCap() uint64                { return b.gasCap }
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.chainConfig }
+func (b *testBackend) Engine() consensus.Engine         { return b.engine }
+func (b *testBackend) CurrentHeader() *types.Header     { return b.chain.CurrentHeader() }
+
+func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.PendingBlockNumber || number == rpc.LatestBlockNumber {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	var header *types.Header
+	if number, ok := blockNrOrHash.Number(); ok {
+		header, _ = b.HeaderByNumber(ctx, number)
+	} else if hash, ok := blockNrOrHash.Hash(); ok {
+		header, _ = b.HeaderByHash(ctx, hash)
+	}
+	if header == nil {
+		return nil, nil, errors.New("header not found")
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	txContext := core.NewEVMTxContext(msg)
+	context := core.NewEVMBlockContext(header, b.chain, nil)
+	return vm.NewEVM(context, txContext, state, b.chainConfig, *vmConfig), func() error { return nil }, nil
+}
+
+// newCallTestBackend creates a backend of a short chain holding the contracts
+// called by the tests, and an account funded with the given balance.
+func newCallTestBackend(t *testing.T, funded common.Address, balance *big.Int) *testBackend {
+	alloc := core.GenesisAlloc{
+		funded:      {Balance: balance},
+		counterAddr: {Code: counterCode, Balance: common.Big0},
+		revertAddr:  {Code: revertCode, Balance: common.Big0},
+		loopAddr:    {Code: loopCode, Balance: common.Big0},
+		contextAddr: {Code: contextCode, Balance: common.Big0},
+		logAddr:     {Code: logCode, Balance: common.Big0},
+	}
+	// Enable London, so that the blocks have a base fee
+	config := *params.TestChainConfig
+	config.LondonBlock = common.Big0
+
+	return newTestBackend(t, &config, ethash.NewFaker(), 2, alloc, nil)
+}
+
+// word returns the 32 byte big endian encoding of a number.
+func word(n uint64) hexutil.Bytes {
+	return common.BigToHash(new(big.Int).SetUint64(n)).Bytes()
+}
+
+// Tests that eth_call executes with the block context fields overridden.
+func TestCallBlockOverrides(t *testing.T) {
+	t.Parallel()
+
+	var (
+		backend = newCallTestBackend(t, common.Address{1}, big.NewInt(params.Ether))
+		api     = NewPublicBlockChainAPI(backend)
+		latest  = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		head    = backend.CurrentHeader()
+	)
+	call := func(overrides *BlockOverrides) []byte {
+		t.Helper()
+		res, err := api.Call(context.Background(), TransactionArgs{To: &contextAddr}, latest, nil, overrides)
+		if err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+		if len(res) != 6*32 {
+			t.Fatalf("result length mismatch: have %d, want %d", len(res), 6*32)
+		}
+		return res
+	}
+	// Without overrides, the call executes in the context of the block
+	coinbase := common.BytesToHash(head.Coinbase.Bytes())
+	want := bytes.Join([][]byte{
+		word(head.Number.Uint64()), word(head.Time), coinbase[:],
+		common.BigToHash(head.Difficulty).Bytes(), word(head.GasLimit), common.BigToHash(head.BaseFee).Bytes(),
+	}, nil)
+	if res := call(nil); !bytes.Equal(res, want) {
+		t.Errorf("block context mismatch: have %x, want %x", res, want)
+	}
+	// Overridden fields replace the ones of the block
+	var (
+		number     = hexutil.Big(*big.NewInt(100))
+		difficulty = hexutil.Big(*big.NewInt(7))
+		time       = hexutil.Uint64(12345)
+		gasLimit   = hexutil.Uint64(5_000_000)
+		miner      = common.HexToAddress("0xc0ffee")
+		baseFee    = hexutil.Big(*big.NewInt(0))
+	)
+	overridden := common.BytesToHash(miner.Bytes())
+	want = bytes.Join([][]byte{word(100), word(12345), overridden[:], word(7), word(5_000_000), word(0)}, nil)
+
+	res := call(&BlockOverrides{
+		Number:     &number,
+		Difficulty: &difficulty,
+		Time:       &time,
+		GasLimit:   &gasLimit,
+		Coinbase:   &miner,
+		BaseFee:    &baseFee,
+	})
+	if !bytes.Equal(res, want) {
+		t.Errorf("overridden block context mismatch: have %x, want %x", res, want)
+	}
+	// Fields not overridden are left untouched
+	want = bytes.Join([][]byte{
+		word(head.Number.Uint64()), word(12345), coinbase[:],
+		common.BigToHash(head.Difficulty).Bytes(), word(head.GasLimit), common.BigToHash(head.BaseFee).Bytes(),
+	}, nil)
+	if res := call(&BlockOverrides{Time: &time}); !bytes.Equal(res, want) {
+		t.Errorf("partially overridden block context mismatch: have %x, want %x", res, want)
+	}
+}
+
+// Tests that eth_callMany executes the calls on top of each other, reporting the
+// failing calls without aborting the sequence.
+func TestCallMany(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		backend = newCallTestBackend(t, sender, big.NewInt(params.Ether))
+		api     = NewPublicBlockChainAPI(backend)
+		latest  = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	)
+	add := func(n uint64) TransactionArgs {
+		data := word(n)
+		return TransactionArgs{From: &sender, To: &counterAddr, Data: &data}
+	}
+	// The value sent by the first call is spent, the second one lacking funds
+	var (
+		value     = (*hexutil.Big)(big.NewInt(params.Ether / 2))
+		overspent = (*hexutil.Big)(big.NewInt(params.Ether/2 + 1))
+		recipient = common.HexToAddress("0x1234")
+	)
+	calls := []TransactionArgs{
+		add(1),
+		{From: &sender, To: &recipient, Value: value},
+		add(2),
+		{From: &sender, To: &recipient, Value: overspent},
+		{From: &sender, To: &revertAddr},
+		add(3),
+	}
+
+	results, err := api.CallMany(context.Background(), calls, latest, nil, nil)
+	if err != nil {
+		t.Fatalf("calls failed: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(calls))
+	}
+	for i, want := range map[int]uint64{0: 1, 2: 3, 5: 6} {
+		if results[i].Error != "" {
+			t.Errorf("call %d: unexpected error: %v", i, results[i].Error)
+		}
+		if !bytes.Equal(results[i].ReturnValue, word(want)) {
+			t.Errorf("call %d: return value mismatch: have %x, want %x", i, results[i].ReturnValue, word(want))
+		}
+		if results[i].GasUsed == 0 {
+			t.Errorf("call %d: no gas used", i)
+		}
+	}
+	if results[1].Error != "" || results[1].GasUsed != hexutil.Uint64(params.TxGas) {
+		t.Errorf("transfer: have error %q, gas %d, want success with gas %d", results[1].Error, results[1].GasUsed, params.TxGas)
+	}
+	if !strings.Contains(results[3].Error, core.ErrInsufficientFunds.Error()) || results[3].GasUsed != 0 {
+		t.Errorf("overspending transfer: have error %q, gas %d, want insufficient funds", results[3].Error, results[3].GasUsed)
+	}
+	if !strings.HasPrefix(results[4].Error, "execution reverted") {
+		t.Errorf("reverting call: have error %q, want revert", results[4].Error)
+	}
+	// The state of the block is left untouched
+	results, err = api.CallMany(context.Background(), []TransactionArgs{add(0)}, latest, nil, nil)
+	if err != nil {
+		t.Fatalf("calls failed: %v", err)
+	}
+	if !bytes.Equal(results[0].ReturnValue, word(0)) {
+		t.Errorf("state changed by the calls: have %x, want %x", results[0].ReturnValue, word(0))
+	}
+	// Sequences above the limit are rejected
+	if _, err := api.CallMany(context.Background(), make([]TransactionArgs, maxCallManyCalls+1), latest, nil, nil); err == nil {
+		t.Errorf("too many calls executed")
+	}
+}
+
+// Tests that the calls of eth_callMany share the global gas cap, and a single
+// deadline.
+func TestCallManyLimits(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sender  = common.Address{1}
+		backend = newCallTestBackend(t, sender, big.NewInt(params.Ether))
+		latest  = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		data    = word(1)
+		add     = TransactionArgs{From: &sender, To: &counterAddr, Data: &data}
+	)
+	// The gas used by the first call is deducted from the allowance of the next
+	gasCap := uint64(50_000)
+	results, err := DoCallMany(context.Background(), backend, []TransactionArgs{add, add}, latest, nil, nil, time.Second, gasCap)
+	if err != nil {
+		t.Fatalf("calls failed: %v", err)
+	}
+	if results[0].Error != "" {
+		t.Fatalf("first call failed: %v", results[0].Error)
+	}
+	supplied := fmt.Sprintf("supplied gas %d", gasCap-uint64(results[0].GasUsed))
+	if !strings.Contains(results[1].Error, supplied) {
+		t.Errorf("second call: have error %q, want %q", results[1].Error, supplied)
+	}
+	// No call is executed once the allowance is spent
+	gas := hexutil.Uint64(gasCap)
+	loop := TransactionArgs{From: &sender, To: &loopAddr, Gas: &gas}
+	if _, err := DoCallMany(context.Background(), backend, []TransactionArgs{loop, add}, latest, nil, nil, time.Second, gasCap); err == nil || !strings.Contains(err.Error(), "exhausted") {
+		t.Errorf("calls beyond the gas cap: have error %v, want exhausted", err)
+	}
+	// The timeout applies to the whole sequence
+	loop.Gas = nil
+	calls := []TransactionArgs{add, add, loop, add}
+	start := time.Now()
+	if _, err := DoCallMany(context.Background(), backend, calls, latest, nil, nil, 100*time.Millisecond, 0); err == nil || !strings.Contains(err.Error(), "call 2: execution aborted") {
+		t.Errorf("unbounded calls: have error %v, want aborted", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("calls not aborted in time: took %v", elapsed)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // GetRootHash returns root hash for given start and end block
@@ -20,6 +22,14 @@ func (s *PublicBlockChainAPI) GetBorBlockReceipt(ctx context.Context, hash commo
 	return s.b.GetBorBlockReceipt(ctx, hash)
 }
 
+// StateAndHeaderByNumber returns the state and header of the given block, which
+// may be the pending one, from the backend of the API. It gives the consensus
+// engine access to the backend, and is a function rather than a method so that
+// it's never registered over RPC.
+func StateAndHeaderByNumber(ctx context.Context, api *PublicBlockChainAPI, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return api.b.StateAndHeaderByNumber(ctx, number)
+}
+
 //
 // Bor transaction utils
 //
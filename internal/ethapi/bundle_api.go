@@ -0,0 +1,247 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// callBundleTimeout is the time a bundle simulation may run for.
+	callBundleTimeout = 5 * time.Second
+
+	// maxCallBundleTxs is the maximum number of transactions in a simulated
+	// bundle, as many as the bundle pool accepts by default.
+	maxCallBundleTxs = 16
+)
+
+// PublicBundleAPI provides an API to submit and simulate bundles, lists of
+// transactions to be included atomically and in order.
+type PublicBundleAPI struct {
+	b Backend
+}
+
+// NewPublicBundleAPI creates a new bundle API.
+func NewPublicBundleAPI(b Backend) *PublicBundleAPI {
+	return &PublicBundleAPI{b}
+}
+
+// decodeBundleTxs decodes the signed transactions of a bundle.
+func decodeBundleTxs(encoded []hexutil.Bytes) (types.Transactions, error) {
+	if len(encoded) == 0 {
+		return nil, core.ErrBundleEmpty
+	}
+	txs := make(types.Transactions, len(encoded))
+	for i, input := range encoded {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		txs[i] = tx
+	}
+	return txs, nil
+}
+
+// SendBundleArgs represents the arguments of a bundle submission.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp      *hexutil.Uint64 `json:"minTimestamp"`
+	MaxTimestamp      *hexutil.Uint64 `json:"maxTimestamp"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundle queues a bundle for inclusion into the given block. The bundle is
+// included whole and at the top of the block, or not at all: it's dropped if
+// any of its transactions can't be included, or fails execution without being
+// listed as allowed to revert. It returns the hash of the bundle.
+func (s *PublicBundleAPI) SendBundle(ctx context.Context, args SendBundleArgs) (common.Hash, error) {
+	txs, err := decodeBundleTxs(args.Txs)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	bundle := &core.Bundle{
+		Txs:               txs,
+		BlockNumber:       new(big.Int).SetUint64(uint64(args.BlockNumber)),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+	}
+	if err := s.b.SendBundle(ctx, bundle); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted bundle", "hash", bundle.Hash(), "block", bundle.BlockNumber, "txs", len(txs))
+	return bundle.Hash(), nil
+}
+
+// CallBundleArgs represents the arguments of a bundle simulation.
+type CallBundleArgs struct {
+	Txs              []hexutil.Bytes       `json:"txs"`
+	BlockNumber      hexutil.Uint64        `json:"blockNumber"`
+	StateBlockNumber rpc.BlockNumberOrHash `json:"stateBlockNumber"`
+	Coinbase         *common.Address       `json:"coinbase"`
+	Timestamp        *hexutil.Uint64       `json:"timestamp"`
+}
+
+// CallBundleTxResult is the outcome of a transaction of a simulated bundle.
+type CallBundleTxResult struct {
+	TxHash      common.Hash     `json:"txHash"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to"`
+	GasUsed     hexutil.Uint64  `json:"gasUsed"`
+	GasPrice    *hexutil.Big    `json:"gasPrice"`
+	ReturnValue hexutil.Bytes   `json:"returnValue,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// CallBundleResult is the outcome of a bundle simulation.
+type CallBundleResult struct {
+	BundleHash       common.Hash           `json:"bundleHash"`
+	StateBlockNumber hexutil.Uint64        `json:"stateBlockNumber"`
+	GasUsed          hexutil.Uint64        `json:"gasUsed"`
+	CoinbaseDiff     *hexutil.Big          `json:"coinbaseDiff"`
+	Results          []*CallBundleTxResult `json:"results"`
+}
+
+// CallBundle simulates a bundle on top of the given state, as the transactions
+// of a block with the given number, returning the outcome of each transaction
+// and the payment received by the block's coinbase. Transactions reverting are
+// reported, the simulation only fails if one can't be executed at all.
+func (s *PublicBundleAPI) CallBundle(ctx context.Context, args CallBundleArgs) (*CallBundleResult, error) {
+	if len(args.Txs) > maxCallBundleTxs {
+		return nil, fmt.Errorf("%w: %d transactions, max %d", core.ErrBundleTooLarge, len(args.Txs), maxCallBundleTxs)
+	}
+	txs, err := decodeBundleTxs(args.Txs)
+	if err != nil {
+		return nil, err
+	}
+	state, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, args.StateBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if args.BlockNumber == 0 {
+		return nil, errors.New("missing bundle target block")
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).SetUint64(uint64(args.BlockNumber)),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+		Difficulty: parent.Difficulty,
+		Coinbase:   parent.Coinbase,
+	}
+	if args.Timestamp != nil {
+		header.Time = uint64(*args.Timestamp)
+	}
+	if args.Coinbase != nil {
+		header.Coinbase = *args.Coinbase
+	}
+	if s.b.ChainConfig().IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(s.b.ChainConfig(), parent)
+	}
+	ctx, cancel := context.WithTimeout(ctx, callBundleTimeout)
+	defer cancel()
+
+	var (
+		signer   = types.MakeSigner(s.b.ChainConfig(), header.Number)
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		coinbase = state.GetBalance(header.Coinbase)
+		bundle   = &core.Bundle{Txs: txs}
+		evm      *vm.EVM
+		vmError  func() error
+		result   = &CallBundleResult{
+			BundleHash:       bundle.Hash(),
+			StateBlockNumber: hexutil.Uint64(parent.Number.Uint64()),
+		}
+	)
+	for i, tx := range txs {
+		msg, err := tx.AsMessage(signer, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		state.Prepare(tx.Hash(), i)
+
+		// Execute all the transactions with the same EVM, aborted once the time
+		// is up
+		if evm == nil {
+			if evm, vmError, err = s.b.GetEVM(ctx, msg, state, header, &vm.Config{}); err != nil {
+				return nil, err
+			}
+			// The coinbase is paid as configured, not as recovered from a seal
+			evm.Context.Coinbase = header.Coinbase
+			go func() {
+				<-ctx.Done()
+				evm.Cancel()
+			}()
+		} else {
+			evm.Reset(core.NewEVMTxContext(msg), state)
+		}
+		res, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", callBundleTimeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		state.Finalise(true)
+
+		price := tx.GasPrice()
+		if header.BaseFee != nil {
+			tip, _ := tx.EffectiveGasTip(header.BaseFee)
+			price = new(big.Int).Add(tip, header.BaseFee)
+		}
+		txResult := &CallBundleTxResult{
+			TxHash:   tx.Hash(),
+			From:     msg.From(),
+			To:       tx.To(),
+			GasUsed:  hexutil.Uint64(res.UsedGas),
+			GasPrice: (*hexutil.Big)(price),
+		}
+		if res.Err != nil {
+			txResult.Error = res.Err.Error()
+			if len(res.Revert()) > 0 {
+				txResult.Error = newRevertError(res).Error()
+			}
+		} else {
+			txResult.ReturnValue = res.Return()
+		}
+		result.Results = append(result.Results, txResult)
+		result.GasUsed += hexutil.Uint64(res.UsedGas)
+	}
+	result.CoinbaseDiff = (*hexutil.Big)(new(big.Int).Sub(state.GetBalance(header.Coinbase), coinbase))
+	return result, nil
+}
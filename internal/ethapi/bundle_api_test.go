@@ -0,0 +1,95 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that bundles are simulated as the transactions of a block, each seeing
+// the state changes of the previous ones, and that oversized bundles are
+// rejected.
+func TestCallBundle(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		backend = newCallTestBackend(t, sender, big.NewInt(params.Ether))
+		api     = NewPublicBundleAPI(backend)
+		signer  = types.LatestSigner(backend.ChainConfig())
+		miner   = common.HexToAddress("0xc0ffee")
+		price   = big.NewInt(2 * params.GWei)
+	)
+	sign := func(nonce uint64, to common.Address, data []byte) hexutil.Bytes {
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &to, Gas: 100000, GasPrice: price, Data: data})
+		enc, _ := tx.MarshalBinary()
+		return enc
+	}
+	args := CallBundleArgs{
+		Txs:              []hexutil.Bytes{sign(0, counterAddr, word(1)), sign(1, counterAddr, word(2)), sign(2, revertAddr, nil)},
+		BlockNumber:      hexutil.Uint64(backend.CurrentHeader().Number.Uint64() + 1),
+		StateBlockNumber: rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber),
+		Coinbase:         &miner,
+	}
+	result, err := api.CallBundle(context.Background(), args)
+	if err != nil {
+		t.Fatalf("bundle simulation failed: %v", err)
+	}
+	if len(result.Results) != len(args.Txs) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(result.Results), len(args.Txs))
+	}
+	for i, want := range []uint64{1, 3} {
+		if res := result.Results[i]; res.Error != "" || !bytes.Equal(res.ReturnValue, word(want)) {
+			t.Errorf("transaction %d: have return value %x, error %q, want %x", i, res.ReturnValue, res.Error, word(want))
+		}
+	}
+	if res := result.Results[2]; !strings.HasPrefix(res.Error, "execution reverted") {
+		t.Errorf("reverting transaction: have error %q, want revert", res.Error)
+	}
+	var gasUsed uint64
+	for _, res := range result.Results {
+		gasUsed += uint64(res.GasUsed)
+	}
+	if uint64(result.GasUsed) != gasUsed {
+		t.Errorf("gas used mismatch: have %d, want %d", result.GasUsed, gasUsed)
+	}
+	if result.CoinbaseDiff.ToInt().Sign() <= 0 {
+		t.Errorf("coinbase not paid: have %v", result.CoinbaseDiff)
+	}
+	// Bundles larger than the pool accepts aren't simulated
+	args.Txs = make([]hexutil.Bytes, maxCallBundleTxs+1)
+	for i := range args.Txs {
+		args.Txs[i] = sign(uint64(i), counterAddr, word(1))
+	}
+	if _, err := api.CallBundle(context.Background(), args); !errors.Is(err, core.ErrBundleTooLarge) {
+		t.Errorf("oversized bundle error mismatch: have %v, want %v", err, core.ErrBundleTooLarge)
+	}
+}
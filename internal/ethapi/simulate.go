@@ -0,0 +1,464 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// simulateBlocksTimeout is the time a block simulation may run for.
+	simulateBlocksTimeout = 10 * time.Second
+
+	// maxSimulateBlocks is the maximum number of blocks simulated at once.
+	maxSimulateBlocks = 256
+)
+
+// StateSyncEvent is a state-sync event committed by a simulated sprint start
+// block, as it would have been fetched from Heimdall.
+type StateSyncEvent struct {
+	ID       hexutil.Uint64  `json:"id"`
+	Contract common.Address  `json:"contract"`
+	Data     hexutil.Bytes   `json:"data"`
+	TxHash   common.Hash     `json:"txHash"`
+	LogIndex hexutil.Uint64  `json:"logIndex"`
+	Time     *hexutil.Uint64 `json:"time"` // Defaults to the time of the block
+}
+
+// SimulatedFinalizer is implemented by consensus engines running system logic
+// when finalizing blocks, so that simulated blocks can reproduce it.
+type SimulatedFinalizer interface {
+	// FinalizeSimulated runs the system logic finalizing the given simulated
+	// block on top of its state, committing the supplied state-sync events
+	// instead of fetching them.
+	FinalizeSimulated(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, events []*StateSyncEvent) error
+}
+
+// SimBlock is a block to simulate: the calls it's made of, along with the state
+// and block context overrides applied before executing them.
+type SimBlock struct {
+	BlockOverrides *BlockOverrides   `json:"blockOverrides"`
+	StateOverrides *StateOverride    `json:"stateOverrides"`
+	Calls          []TransactionArgs `json:"calls"`
+	StateSyncs     []*StateSyncEvent `json:"stateSyncs"` // Committed if the block is a sprint start
+}
+
+// SimulateOptions are the options of a block simulation.
+type SimulateOptions struct {
+	// SystemLogic runs the system logic of the consensus engine when finalizing
+	// each block, which on Bor commits the supplied state-sync events at sprint
+	// starts.
+	SystemLogic bool `json:"systemLogic"`
+}
+
+// SimBlockResult is the outcome of a simulated block.
+type SimBlockResult struct {
+	Number     hexutil.Uint64   `json:"number"`
+	Hash       common.Hash      `json:"hash"`
+	ParentHash common.Hash      `json:"parentHash"`
+	Timestamp  hexutil.Uint64   `json:"timestamp"`
+	Miner      common.Address   `json:"miner"`
+	GasLimit   hexutil.Uint64   `json:"gasLimit"`
+	GasUsed    hexutil.Uint64   `json:"gasUsed"`
+	BaseFee    *hexutil.Big     `json:"baseFeePerGas,omitempty"`
+	StateRoot  common.Hash      `json:"stateRoot"`
+	Calls      []*CallResult    `json:"calls"`
+	Receipts   []*types.Receipt `json:"receipts"`
+	BorReceipt *types.Receipt   `json:"borReceipt,omitempty"` // Logs of the system calls, if any
+	Logs       []*types.Log     `json:"logs"`
+}
+
+// simulatedChain serves the headers of the chain along with those of the blocks
+// simulated on top of it, so that BLOCKHASH and the consensus engine see them.
+type simulatedChain struct {
+	ctx     context.Context
+	b       Backend
+	headers map[common.Hash]*types.Header
+	numbers map[uint64]*types.Header
+}
+
+func newSimulatedChain(ctx context.Context, b Backend) *simulatedChain {
+	return &simulatedChain{
+		ctx:     ctx,
+		b:       b,
+		headers: make(map[common.Hash]*types.Header),
+		numbers: make(map[uint64]*types.Header),
+	}
+}
+
+// add makes a simulated header available, superseding any canonical one with
+// the same number.
+func (c *simulatedChain) add(header *types.Header) {
+	c.headers[header.Hash()] = header
+	c.numbers[header.Number.Uint64()] = header
+}
+
+func (c *simulatedChain) Config() *params.ChainConfig  { return c.b.ChainConfig() }
+func (c *simulatedChain) CurrentHeader() *types.Header { return c.b.CurrentHeader() }
+func (c *simulatedChain) Engine() consensus.Engine     { return c.b.Engine() }
+
+func (c *simulatedChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := c.headers[hash]; ok {
+		return header
+	}
+	header, _ := c.b.HeaderByHash(c.ctx, hash)
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+func (c *simulatedChain) GetHeaderByNumber(number uint64) *types.Header {
+	if header, ok := c.numbers[number]; ok {
+		return header
+	}
+	header, _ := c.b.HeaderByNumber(c.ctx, rpc.BlockNumber(number))
+	return header
+}
+
+func (c *simulatedChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header, ok := c.headers[hash]; ok {
+		return header
+	}
+	header, _ := c.b.HeaderByHash(c.ctx, hash)
+	return header
+}
+
+// simulatedEVM tracks the EVM executing the calls of a simulation, so that a
+// single routine can abort it once the time is up.
+type simulatedEVM struct {
+	ctx context.Context
+	evm atomic.Value // *vm.EVM executing the calls of the current block
+}
+
+func newSimulatedEVM(ctx context.Context) *simulatedEVM {
+	s := &simulatedEVM{ctx: ctx}
+	go func() {
+		<-ctx.Done()
+		if evm, ok := s.evm.Load().(*vm.EVM); ok {
+			evm.Cancel()
+		}
+	}()
+	return s
+}
+
+// track makes the given EVM the one aborted once the time is up, aborting it
+// right away if it already is.
+func (s *simulatedEVM) track(evm *vm.EVM) {
+	s.evm.Store(evm)
+	if s.ctx.Err() != nil {
+		evm.Cancel()
+	}
+}
+
+// simulatedHeader returns the header of a block simulated on top of the given
+// parent, with the requested overrides. Unless overridden, the block follows
+// its parent at the chain's block period.
+func simulatedHeader(config *params.ChainConfig, parent *types.Header, coinbase common.Address, overrides *BlockOverrides) (*types.Header, error) {
+	period := uint64(1)
+	if config.Bor != nil && config.Bor.Period > 0 {
+		period = config.Bor.Period
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   coinbase,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Difficulty: parent.Difficulty,
+	}
+	// Blocks skipped over having been simulated, the number can only be the next
+	if overrides != nil && overrides.Number != nil && overrides.Number.ToInt().Cmp(header.Number) != 0 {
+		return nil, fmt.Errorf("block number %v not above %v", overrides.Number, parent.Number)
+	}
+	header.Time = parent.Time + period
+
+	if config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(config, parent)
+	}
+	if overrides != nil {
+		if overrides.Difficulty != nil {
+			header.Difficulty = overrides.Difficulty.ToInt()
+		}
+		if overrides.Time != nil {
+			header.Time = uint64(*overrides.Time)
+		}
+		if overrides.GasLimit != nil {
+			header.GasLimit = uint64(*overrides.GasLimit)
+		}
+		if overrides.Coinbase != nil {
+			header.Coinbase = *overrides.Coinbase
+		}
+		if overrides.BaseFee != nil {
+			header.BaseFee = overrides.BaseFee.ToInt()
+		}
+	}
+	return header, nil
+}
+
+// simulatedTx returns the unsigned transaction standing for a simulated call.
+func simulatedTx(args TransactionArgs, msg types.Message, chainID *big.Int) *types.Transaction {
+	nonce, gas := hexutil.Uint64(msg.Nonce()), hexutil.Uint64(msg.Gas())
+	args.Nonce, args.Gas, args.ChainID = &nonce, &gas, (*hexutil.Big)(chainID)
+	if args.GasPrice == nil && args.MaxFeePerGas == nil {
+		args.GasPrice = (*hexutil.Big)(msg.GasPrice())
+	}
+	if args.MaxFeePerGas != nil && args.MaxPriorityFeePerGas == nil {
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(msg.GasTipCap())
+	}
+	return args.toTransaction()
+}
+
+// SimulateBlocks executes the given blocks one after the other on top of the
+// state of the given block, returning the receipts, logs and gas used of each.
+// Unless overridden, blocks follow each other at the chain's block period, so
+// that timing dependent contracts can be exercised. Blocks skipped over by a
+// number override are simulated empty, and reported as well.
+//
+// Calls are executed as unsigned transactions from their sender, with nonces
+// not checked. They share the gas limit of their block, and a call which can't
+// be included (e.g. lacking the funds to pay for its gas) fails the simulation.
+//
+// If requested, the system logic of the consensus engine is run when finalizing
+// each block. On Bor, sprint start blocks commit the state-sync events supplied
+// with them, their logs being reported in a bor receipt, while span commits are
+// not simulated. Fee transfer logs are emitted by the calls themselves.
+//
+// Note, this function doesn't make any changes in the state/blockchain.
+func (s *PublicBlockChainAPI) SimulateBlocks(ctx context.Context, blocks []SimBlock, blockNrOrHash rpc.BlockNumberOrHash, options *SimulateOptions) ([]*SimBlockResult, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no blocks to simulate")
+	}
+	if len(blocks) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks to simulate: %d > %d", len(blocks), maxSimulateBlocks)
+	}
+	var finalizer SimulatedFinalizer
+	if options != nil && options.SystemLogic {
+		var ok bool
+		if finalizer, ok = s.b.Engine().(SimulatedFinalizer); !ok {
+			return nil, errors.New("consensus engine has no system logic to simulate")
+		}
+	}
+	state, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, simulateBlocksTimeout)
+	defer cancel()
+
+	// The coinbase defaults to the author of the base block, which sealed it
+	coinbase, err := s.b.Engine().Author(parent)
+	if err != nil {
+		coinbase = parent.Coinbase
+	}
+	var (
+		chain   = newSimulatedChain(ctx, s.b)
+		evms    = newSimulatedEVM(ctx)
+		results []*SimBlockResult
+	)
+	simulate := func(block SimBlock) error {
+		result, header, err := s.simulateBlock(ctx, chain, evms, finalizer, state, parent, coinbase, block)
+		if err != nil {
+			return err
+		}
+		chain.add(header)
+		results, parent = append(results, result), header
+		return nil
+	}
+	for i, block := range blocks {
+		if finalizer == nil && len(block.StateSyncs) > 0 {
+			return nil, fmt.Errorf("block %d: state-sync events require system logic", i)
+		}
+		// Blocks skipped over are simulated empty, leaving no gap in the chain
+		distance := big.NewInt(1)
+		if block.BlockOverrides != nil && block.BlockOverrides.Number != nil {
+			distance.Sub(block.BlockOverrides.Number.ToInt(), parent.Number)
+		}
+		if distance.Sign() > 0 && (!distance.IsUint64() || uint64(len(results))+distance.Uint64() > maxSimulateBlocks) {
+			return nil, fmt.Errorf("block %d: too many blocks to simulate, the limit is %d", i, maxSimulateBlocks)
+		}
+		for ; distance.Cmp(common.Big1) > 0; distance.Sub(distance, common.Big1) {
+			if err := simulate(SimBlock{}); err != nil {
+				return nil, fmt.Errorf("block %d: %w", i, err)
+			}
+		}
+		if err := simulate(block); err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+	}
+	return results, nil
+}
+
+// simulateBlock executes a simulated block on top of the state left by the
+// previous ones, returning its outcome and header.
+func (s *PublicBlockChainAPI) simulateBlock(ctx context.Context, chain *simulatedChain, evms *simulatedEVM, finalizer SimulatedFinalizer, state *state.StateDB, parent *types.Header, coinbase common.Address, block SimBlock) (*SimBlockResult, *types.Header, error) {
+	config := s.b.ChainConfig()
+
+	header, err := simulatedHeader(config, parent, coinbase, block.BlockOverrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := block.StateOverrides.Apply(state); err != nil {
+		return nil, nil, err
+	}
+	var (
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		txs      = make(types.Transactions, len(block.Calls))
+		receipts = make(types.Receipts, len(block.Calls))
+		calls    = make([]*CallResult, len(block.Calls))
+		gasUsed  uint64
+		evm      *vm.EVM
+		vmError  func() error
+	)
+	for i, args := range block.Calls {
+		// Calls default to the gas left in the block
+		if args.Gas == nil {
+			gas := hexutil.Uint64(gp.Gas())
+			args.Gas = &gas
+		}
+		msg, err := args.ToMessage(s.b.RPCGasCap(), header.BaseFee)
+		if err != nil {
+			return nil, nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		from := msg.From()
+		nonce := state.GetNonce(from)
+		msg = types.NewMessage(from, msg.To(), nonce, msg.Value(), msg.Gas(), msg.GasPrice(), msg.GasFeeCap(), msg.GasTipCap(), msg.Data(), msg.AccessList(), true)
+
+		tx := simulatedTx(args, msg, config.ChainID)
+		state.Prepare(tx.Hash(), i)
+
+		// Execute all the calls of the block with the same EVM
+		if evm == nil {
+			if evm, vmError, err = s.b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true}); err != nil {
+				return nil, nil, err
+			}
+			// The coinbase is paid as configured, not as recovered from a seal,
+			// and the previous blocks are the simulated ones
+			evm.Context.Coinbase = header.Coinbase
+			evm.Context.GetHash = core.GetHashFn(header, chain)
+			evms.track(evm)
+		} else {
+			evm.Reset(core.NewEVMTxContext(msg), state)
+		}
+		// Calls paying no fees aren't charged the base fee either
+		evm.Context.BaseFee = header.BaseFee
+		if msg.GasFeeCap().BitLen() == 0 {
+			evm.Context.BaseFee = new(big.Int)
+		}
+		res, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, nil, err
+		}
+		if evm.Cancelled() {
+			return nil, nil, fmt.Errorf("execution aborted (timeout = %v)", simulateBlocksTimeout)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		state.Finalise(config.IsEIP158(header.Number))
+		gasUsed += res.UsedGas
+
+		receipt := &types.Receipt{
+			Type:              tx.Type(),
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: gasUsed,
+			TxHash:            tx.Hash(),
+			GasUsed:           res.UsedGas,
+			TransactionIndex:  uint(i),
+		}
+		if res.Failed() {
+			receipt.Status = types.ReceiptStatusFailed
+		}
+		if msg.To() == nil {
+			receipt.ContractAddress = crypto.CreateAddress(from, nonce)
+		}
+		receipt.Logs = state.GetLogs(tx.Hash(), common.Hash{})
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		call := &CallResult{ReturnValue: res.Return(), GasUsed: hexutil.Uint64(res.UsedGas)}
+		if len(res.Revert()) > 0 {
+			call.ReturnValue = res.Revert()
+			call.Error = newRevertError(res).Error()
+		} else if res.Err != nil {
+			call.Error = res.Err.Error()
+		}
+		txs[i], receipts[i], calls[i] = tx, receipt, call
+	}
+	header.GasUsed = gasUsed
+
+	// Run the system logic, its logs being collected apart as on Bor
+	borTxHash := types.GetDerivedBorTxHash(types.BorReceiptKey(header.Number.Uint64(), header.ParentHash))
+	if finalizer != nil {
+		state.Prepare(borTxHash, len(receipts))
+		if err := finalizer.FinalizeSimulated(chain, header, state, block.StateSyncs); err != nil {
+			return nil, nil, err
+		}
+	}
+	header.Root = state.IntermediateRoot(config.IsEIP158(header.Number))
+
+	// Seal the block and fill in the fields derived from its hash
+	sealed := types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
+	header = sealed.Header()
+	hash := header.Hash()
+
+	result := &SimBlockResult{
+		Number:     hexutil.Uint64(header.Number.Uint64()),
+		Hash:       hash,
+		ParentHash: header.ParentHash,
+		Timestamp:  hexutil.Uint64(header.Time),
+		Miner:      header.Coinbase,
+		GasLimit:   hexutil.Uint64(header.GasLimit),
+		GasUsed:    hexutil.Uint64(header.GasUsed),
+		BaseFee:    (*hexutil.Big)(header.BaseFee),
+		StateRoot:  header.Root,
+		Calls:      calls,
+		Receipts:   receipts,
+		Logs:       []*types.Log{},
+	}
+	for _, receipt := range receipts {
+		receipt.BlockHash, receipt.BlockNumber = hash, header.Number
+		for _, log := range receipt.Logs {
+			log.BlockHash, log.BlockNumber, log.Index = hash, header.Number.Uint64(), uint(len(result.Logs))
+			result.Logs = append(result.Logs, log)
+		}
+	}
+	if logs := state.GetLogs(borTxHash, hash); len(logs) > 0 {
+		receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, Logs: logs}
+		types.DeriveFieldsForBorReceipt(receipt, hash, header.Number.Uint64(), receipts)
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		result.BorReceipt = receipt
+		result.Logs = append(result.Logs, logs...)
+	}
+	return result, header, nil
+}
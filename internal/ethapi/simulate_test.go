@@ -0,0 +1,305 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testFinalizer is a consensus engine with system logic committing state-sync
+// events at sprint starts, each by emitting a log of the event data from the
+// receiving contract.
+type testFinalizer struct {
+	consensus.Engine
+	sprint uint64
+}
+
+func (f *testFinalizer) FinalizeSimulated(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, events []*StateSyncEvent) error {
+	if header.Number.Uint64()%f.sprint != 0 {
+		if len(events) > 0 {
+			return fmt.Errorf("state-sync events in block %d, not a sprint start", header.Number)
+		}
+		return nil
+	}
+	for _, event := range events {
+		state.AddLog(&types.Log{Address: event.Contract, Data: event.Data})
+	}
+	return nil
+}
+
+// Tests that simulated blocks follow each other at the block period unless
+// overridden, and that the blocks skipped over by a number override are
+// simulated empty.
+func TestSimulateBlocks(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sender  = common.Address{1}
+		backend = newCallTestBackend(t, sender, big.NewInt(params.Ether))
+		api     = NewPublicBlockChainAPI(backend)
+		latest  = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		head    = backend.CurrentHeader()
+	)
+	add := func(n uint64) TransactionArgs {
+		data := word(n)
+		return TransactionArgs{From: &sender, To: &counterAddr, Data: &data}
+	}
+	var (
+		number = hexutil.Big(*new(big.Int).Add(head.Number, big.NewInt(4)))
+		time   = hexutil.Uint64(head.Time + 100)
+	)
+	blocks := []SimBlock{
+		{Calls: []TransactionArgs{add(1)}},
+		{Calls: []TransactionArgs{add(2)}, BlockOverrides: &BlockOverrides{Number: &number}},
+		{Calls: []TransactionArgs{add(3), {From: &sender, To: &logAddr}}, BlockOverrides: &BlockOverrides{Time: &time}},
+		{},
+	}
+	results, err := api.SimulateBlocks(context.Background(), blocks, latest, nil)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("block count mismatch: have %d, want %d", len(results), 6)
+	}
+	var (
+		parent = head.Hash()
+		times  = []uint64{head.Time + 1, head.Time + 2, head.Time + 3, head.Time + 4, head.Time + 100, head.Time + 101}
+		sums   = map[int]uint64{0: 1, 3: 3, 4: 6}
+	)
+	for i, result := range results {
+		if want := head.Number.Uint64() + uint64(i) + 1; uint64(result.Number) != want {
+			t.Errorf("block %d: number mismatch: have %d, want %d", i, result.Number, want)
+		}
+		if result.ParentHash != parent {
+			t.Errorf("block %d: parent hash mismatch: have %x, want %x", i, result.ParentHash, parent)
+		}
+		if uint64(result.Timestamp) != times[i] {
+			t.Errorf("block %d: timestamp mismatch: have %d, want %d", i, result.Timestamp, times[i])
+		}
+		if result.BorReceipt != nil {
+			t.Errorf("block %d: bor receipt without system logic", i)
+		}
+		if len(result.Calls) != len(result.Receipts) {
+			t.Fatalf("block %d: receipt count mismatch: have %d, want %d", i, len(result.Receipts), len(result.Calls))
+		}
+		var gasUsed uint64
+		for j, receipt := range result.Receipts {
+			if receipt.BlockHash != result.Hash || receipt.BlockNumber.Uint64() != uint64(result.Number) {
+				t.Errorf("block %d, call %d: receipt not in the block", i, j)
+			}
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				t.Errorf("block %d, call %d: call failed: %v", i, j, result.Calls[j].Error)
+			}
+			gasUsed += receipt.GasUsed
+		}
+		if uint64(result.GasUsed) != gasUsed {
+			t.Errorf("block %d: gas used mismatch: have %d, want %d", i, result.GasUsed, gasUsed)
+		}
+		if sum, ok := sums[i]; ok {
+			if !bytes.Equal(result.Calls[0].ReturnValue, word(sum)) {
+				t.Errorf("block %d: return value mismatch: have %x, want %x", i, result.Calls[0].ReturnValue, word(sum))
+			}
+		} else if len(result.Calls) != 0 || result.GasUsed != 0 {
+			t.Errorf("block %d: gap block not empty", i)
+		}
+		parent = result.Hash
+	}
+	// The logs of the calls are reported with the fields of the block
+	if logs := results[4].Logs; len(logs) != 1 {
+		t.Errorf("log count mismatch: have %d, want %d", len(logs), 1)
+	} else if logs[0].Address != logAddr || logs[0].BlockHash != results[4].Hash || logs[0].TxIndex != 1 {
+		t.Errorf("log fields mismatch: have %+v", logs[0])
+	}
+	// Blocks can't go backwards, nor skip over more blocks than simulated at once
+	past := hexutil.Big(*head.Number)
+	if _, err := api.SimulateBlocks(context.Background(), []SimBlock{{BlockOverrides: &BlockOverrides{Number: &past}}}, latest, nil); err == nil {
+		t.Errorf("block number below the head simulated")
+	}
+	far := hexutil.Big(*new(big.Int).Add(head.Number, big.NewInt(maxSimulateBlocks+1)))
+	if _, err := api.SimulateBlocks(context.Background(), []SimBlock{{BlockOverrides: &BlockOverrides{Number: &far}}}, latest, nil); err == nil {
+		t.Errorf("too many blocks simulated")
+	}
+}
+
+// Tests that the header of a simulated block follows its parent at the block
+// period of the chain, unless overridden.
+func TestSimulatedHeader(t *testing.T) {
+	t.Parallel()
+
+	var (
+		config   = *params.TestChainConfig
+		coinbase = common.Address{1}
+		parent   = &types.Header{Number: big.NewInt(10), Time: 1000, GasLimit: 30_000_000, Difficulty: big.NewInt(1)}
+	)
+	header, err := simulatedHeader(&config, parent, coinbase, nil)
+	if err != nil {
+		t.Fatalf("failed to create header: %v", err)
+	}
+	if header.Number.Uint64() != 11 || header.Time != 1001 || header.ParentHash != parent.Hash() || header.Coinbase != coinbase {
+		t.Errorf("header mismatch: have number %d, time %d", header.Number, header.Time)
+	}
+	// On Bor, blocks follow each other at the configured period
+	config.Bor = &params.BorConfig{Period: 2, Sprint: 16}
+	if header, _ = simulatedHeader(&config, parent, coinbase, nil); header.Time != 1002 {
+		t.Errorf("bor time mismatch: have %d, want %d", header.Time, 1002)
+	}
+	time := hexutil.Uint64(1500)
+	if header, _ = simulatedHeader(&config, parent, coinbase, &BlockOverrides{Time: &time}); header.Time != 1500 {
+		t.Errorf("overridden time mismatch: have %d, want %d", header.Time, 1500)
+	}
+	// Only the number of the next block can be requested
+	next := hexutil.Big(*big.NewInt(12))
+	if _, err := simulatedHeader(&config, parent, coinbase, &BlockOverrides{Number: &next}); err == nil {
+		t.Errorf("block number skipping over blocks accepted")
+	}
+}
+
+// Tests that reverting calls are reported as failed, while calls which can't be
+// included in their block fail the simulation.
+func TestSimulateBlocksFailingCalls(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sender  = common.Address{1}
+		backend = newCallTestBackend(t, sender, big.NewInt(params.Ether))
+		api     = NewPublicBlockChainAPI(backend)
+		latest  = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		data    = word(1)
+		add     = TransactionArgs{From: &sender, To: &counterAddr, Data: &data}
+	)
+	blocks := []SimBlock{
+		{Calls: []TransactionArgs{{From: &sender, To: &revertAddr}, add}},
+		{Calls: []TransactionArgs{add}},
+	}
+	results, err := api.SimulateBlocks(context.Background(), blocks, latest, nil)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if call, receipt := results[0].Calls[0], results[0].Receipts[0]; !strings.HasPrefix(call.Error, "execution reverted") || receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("reverting call: have error %q, status %d", call.Error, receipt.Status)
+	}
+	if !bytes.Equal(results[0].Calls[1].ReturnValue, word(1)) || !bytes.Equal(results[1].Calls[0].ReturnValue, word(2)) {
+		t.Errorf("calls following the revert not executed")
+	}
+	// Calls lacking the funds, or the gas left in the block, can't be included
+	var (
+		value    = (*hexutil.Big)(big.NewInt(params.Ether + 1))
+		gasLimit = hexutil.Uint64(50_000)
+	)
+	for _, tt := range []struct {
+		blocks []SimBlock
+		err    string
+	}{
+		{
+			blocks: []SimBlock{{}, {Calls: []TransactionArgs{add, {From: &sender, To: &logAddr, Value: value}}}},
+			err:    "block 1: call 1",
+		},
+		{
+			blocks: []SimBlock{{Calls: []TransactionArgs{add, add}, BlockOverrides: &BlockOverrides{GasLimit: &gasLimit}}},
+			err:    "block 0: call 1",
+		},
+	} {
+		if _, err := api.SimulateBlocks(context.Background(), tt.blocks, latest, nil); err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("have error %v, want %q", err, tt.err)
+		}
+	}
+}
+
+// Tests that the system logic commits the state-sync events at sprint starts,
+// their logs being reported in a bor receipt following the ones of the calls.
+func TestSimulateBlocksSystemLogic(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sender  = common.Address{1}
+		backend = newCallTestBackend(t, sender, big.NewInt(params.Ether))
+		api     = NewPublicBlockChainAPI(backend)
+		latest  = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		options = &SimulateOptions{SystemLogic: true}
+		call    = TransactionArgs{From: &sender, To: &logAddr}
+		events  = []*StateSyncEvent{
+			{ID: 1, Contract: common.HexToAddress("0x1001"), Data: []byte{0x01}},
+			{ID: 2, Contract: common.HexToAddress("0x1002"), Data: []byte{0x02}},
+		}
+	)
+	// The system logic can't be simulated without an engine running it, and
+	// state-sync events can't be committed without the system logic
+	if _, err := api.SimulateBlocks(context.Background(), []SimBlock{{}}, latest, options); err == nil {
+		t.Errorf("system logic simulated without an engine running it")
+	}
+	backend.engine = &testFinalizer{Engine: backend.engine, sprint: 4}
+
+	if _, err := api.SimulateBlocks(context.Background(), []SimBlock{{StateSyncs: events}}, latest, nil); err == nil {
+		t.Errorf("state-sync events committed without system logic")
+	}
+	// The head being block 2, the state-syncs can only be committed by block 4
+	if _, err := api.SimulateBlocks(context.Background(), []SimBlock{{StateSyncs: events}}, latest, options); err == nil {
+		t.Errorf("state-sync events committed outside a sprint start")
+	}
+	blocks := []SimBlock{
+		{Calls: []TransactionArgs{call}},
+		{Calls: []TransactionArgs{call, call}, StateSyncs: events},
+		{Calls: []TransactionArgs{call}},
+	}
+	results, err := api.SimulateBlocks(context.Background(), blocks, latest, options)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if results[0].BorReceipt != nil || results[2].BorReceipt != nil {
+		t.Errorf("bor receipt outside a sprint start")
+	}
+	result := results[1]
+	if uint64(result.Number) != 4 {
+		t.Fatalf("sprint start number mismatch: have %d, want %d", result.Number, 4)
+	}
+	receipt := result.BorReceipt
+	if receipt == nil {
+		t.Fatalf("no bor receipt at the sprint start")
+	}
+	txHash := types.GetDerivedBorTxHash(types.BorReceiptKey(4, result.Hash))
+	if receipt.TxHash != txHash || receipt.BlockHash != result.Hash || receipt.TransactionIndex != 2 {
+		t.Errorf("bor receipt fields mismatch: have %+v", receipt)
+	}
+	if len(receipt.Logs) != len(events) {
+		t.Fatalf("bor log count mismatch: have %d, want %d", len(receipt.Logs), len(events))
+	}
+	for i, log := range receipt.Logs {
+		if log.Address != events[i].Contract || !bytes.Equal(log.Data, events[i].Data) {
+			t.Errorf("bor log %d: state-sync mismatch: have %x %x", i, log.Address, log.Data)
+		}
+		if log.TxHash != txHash || log.BlockHash != result.Hash || log.Index != uint(i+2) {
+			t.Errorf("bor log %d: fields mismatch: have %+v", i, log)
+		}
+	}
+	// The bor logs follow the ones of the calls in the block
+	if len(result.Logs) != 4 || result.Logs[2] != receipt.Logs[0] || result.Logs[3] != receipt.Logs[1] {
+		t.Errorf("block logs mismatch: have %d logs", len(result.Logs))
+	}
+}
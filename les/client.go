@@ -167,6 +167,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*LightEthereum, error) {
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
 	}
+	if gpoParams.MinTipCap == nil {
+		gpoParams.MinTipCap = gasprice.ChainMinTipCap(chainConfig)
+	}
 	leth.ApiBackend.gpo = gasprice.NewOracle(leth.ApiBackend, gpoParams)
 
 	leth.handler = newClientHandler(config.UltraLightServers, config.UltraLightFraction, checkpoint, leth)
@@ -39,6 +39,7 @@ import (
 type Backend interface {
 	BlockChain() *core.BlockChain
 	TxPool() *core.TxPool
+	BundlePool() *core.BundlePool
 }
 
 // Config is the configuration parameters of mining.
@@ -51,6 +52,9 @@ type Config struct {
 	GasCeil    uint64         // Target gas ceiling for mined blocks.
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
+	SlotMargin time.Duration  // Time reserved at the end of a producer slot to seal and propagate the block (slotted engines only).
+	FillBudget time.Duration  // Time allowed to fill a block prepared past its slot deadline, none leaving it empty (slotted engines only).
+	TxOrdering string         `toml:",omitempty"` // Transaction ordering policy used to fill blocks (default = price)
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
 }
 
@@ -35,8 +35,9 @@ import (
 )
 
 type mockBackend struct {
-	bc     *core.BlockChain
-	txPool *core.TxPool
+	bc         *core.BlockChain
+	txPool     *core.TxPool
+	bundlePool *core.BundlePool
 }
 
 func NewMockBackend(bc *core.BlockChain, txPool *core.TxPool) *mockBackend {
@@ -54,6 +55,10 @@ func (m *mockBackend) TxPool() *core.TxPool {
 	return m.txPool
 }
 
+func (m *mockBackend) BundlePool() *core.BundlePool {
+	return m.bundlePool
+}
+
 type testBlockChain struct {
 	statedb       *state.StateDB
 	gasLimit      uint64
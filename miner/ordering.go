@@ -0,0 +1,200 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Names of the built-in transaction ordering policies.
+const (
+	PriceNonceOrdering = "price" // Highest miner tip first, the default
+	FIFOOrdering       = "fifo"  // Earliest arrival first
+	FairOrdering       = "fair"  // Round-robin across senders, highest tip first in a round
+)
+
+// TxIterator is a cursor over the pending transactions being included into a
+// block. Transactions of the same account are always returned in nonce order.
+type TxIterator interface {
+	// Peek returns the next transaction to include, nil once exhausted.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same
+	// account, after the current one was processed.
+	Shift()
+
+	// Pop removes the current transaction along with all the following ones from
+	// the same account, after the current one couldn't be included.
+	Pop()
+}
+
+// TxOrderingPolicy decides the order in which pending transactions are offered
+// to the block being built.
+type TxOrderingPolicy interface {
+	// Name returns the name the policy is selected with.
+	Name() string
+
+	// Order returns an iterator over the given per-account, nonce sorted pending
+	// transactions. The map is reowned by the iterator. Accounts whose next
+	// transaction can't pay the base fee are skipped.
+	Order(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxIterator
+}
+
+// NewTxOrderingPolicy returns the built-in ordering policy with the given name,
+// the empty name selecting the default price-and-nonce one.
+func NewTxOrderingPolicy(name string) (TxOrderingPolicy, error) {
+	switch name {
+	case "", PriceNonceOrdering:
+		return priceNonceOrdering{}, nil
+	case FIFOOrdering:
+		return &headOrdering{name: FIFOOrdering, less: arrivedBefore}, nil
+	case FairOrdering:
+		return &headOrdering{name: FairOrdering, less: fairBefore}, nil
+	default:
+		return nil, fmt.Errorf("unknown transaction ordering policy %q", name)
+	}
+}
+
+// priceNonceOrdering is the stock go-ethereum ordering, highest tip first.
+type priceNonceOrdering struct{}
+
+func (priceNonceOrdering) Name() string { return PriceNonceOrdering }
+
+func (priceNonceOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxIterator {
+	return types.NewTransactionsByPriceAndNonce(signer, txs, baseFee)
+}
+
+// accountHead is the next includable transaction of an account.
+type accountHead struct {
+	from  common.Address
+	tx    *types.Transaction
+	tip   *big.Int // Miner tip at the block's base fee
+	taken int      // Number of transactions of the account already shifted out
+}
+
+// headOrdering is an ordering policy picking the best account head according
+// to a comparison function, each account's transactions being nonce sorted.
+type headOrdering struct {
+	name string
+	less func(a, b *accountHead) bool
+}
+
+func (o *headOrdering) Name() string { return o.name }
+
+func (o *headOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxIterator {
+	it := &headIterator{
+		heads:   accountHeads{less: o.less},
+		txs:     txs,
+		signer:  signer,
+		baseFee: baseFee,
+	}
+	for from, accTxs := range txs {
+		// Drop the account if the sender doesn't match or it can't pay the base fee
+		acc, _ := types.Sender(signer, accTxs[0])
+		tip, err := accTxs[0].EffectiveGasTip(baseFee)
+		if acc != from || err != nil {
+			delete(txs, from)
+			continue
+		}
+		it.heads.items = append(it.heads.items, &accountHead{from: from, tx: accTxs[0], tip: tip})
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&it.heads)
+	return it
+}
+
+// arrivedBefore orders transactions by arrival time, then by tip.
+func arrivedBefore(a, b *accountHead) bool {
+	if !a.tx.Time().Equal(b.tx.Time()) {
+		return a.tx.Time().Before(b.tx.Time())
+	}
+	return a.tip.Cmp(b.tip) > 0
+}
+
+// fairBefore prefers the accounts with the least transactions included so far,
+// then orders by tip and arrival time, so every sender gets a transaction in
+// before anyone gets a second one.
+func fairBefore(a, b *accountHead) bool {
+	if a.taken != b.taken {
+		return a.taken < b.taken
+	}
+	if cmp := a.tip.Cmp(b.tip); cmp != 0 {
+		return cmp > 0
+	}
+	return a.tx.Time().Before(b.tx.Time())
+}
+
+// headIterator iterates over the account heads of a headOrdering.
+type headIterator struct {
+	heads   accountHeads
+	txs     map[common.Address]types.Transactions
+	signer  types.Signer
+	baseFee *big.Int
+}
+
+func (it *headIterator) Peek() *types.Transaction {
+	if len(it.heads.items) == 0 {
+		return nil
+	}
+	return it.heads.items[0].tx
+}
+
+func (it *headIterator) Shift() {
+	head := it.heads.items[0]
+	if txs := it.txs[head.from]; len(txs) > 0 {
+		if tip, err := txs[0].EffectiveGasTip(it.baseFee); err == nil {
+			head.tx, head.tip = txs[0], tip
+			head.taken++
+			it.txs[head.from] = txs[1:]
+			heap.Fix(&it.heads, 0)
+			return
+		}
+	}
+	heap.Pop(&it.heads)
+}
+
+func (it *headIterator) Pop() {
+	heap.Pop(&it.heads)
+}
+
+// accountHeads is a heap of account heads sorted by the policy's comparison.
+type accountHeads struct {
+	items []*accountHead
+	less  func(a, b *accountHead) bool
+}
+
+func (h accountHeads) Len() int           { return len(h.items) }
+func (h accountHeads) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+func (h accountHeads) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *accountHeads) Push(x interface{}) {
+	h.items = append(h.items, x.(*accountHead))
+}
+
+func (h *accountHeads) Pop() interface{} {
+	old := h.items
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	h.items = old[0 : n-1]
+	return x
+}
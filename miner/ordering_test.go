@@ -0,0 +1,211 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// txPoolSnapshot is the set of pending transactions of a transaction pool,
+// along with the chain they are pending on.
+type txPoolSnapshot struct {
+	chain   *core.BlockChain
+	pending map[common.Address]types.Transactions
+}
+
+// newTxPoolSnapshot funds the senders of the given transactions, feeds them
+// into a transaction pool and snapshots its pending set. Arrival times set on
+// the transactions are preserved by the pool.
+func newTxPoolSnapshot(t *testing.T, txs []*types.Transaction) *txPoolSnapshot {
+	signer := types.LatestSigner(params.TestChainConfig)
+
+	alloc := make(core.GenesisAlloc)
+	for _, tx := range txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			t.Fatalf("failed to derive sender: %v", err)
+		}
+		alloc[from] = core.GenesisAccount{Balance: testBankFunds}
+	}
+	db := rawdb.NewMemoryDatabase()
+	gspec := core.Genesis{Config: params.TestChainConfig, Alloc: alloc}
+	gspec.MustCommit(db)
+
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	pool := core.NewTxPool(testTxPoolConfig, gspec.Config, chain)
+	defer pool.Stop()
+
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	pending, err := pool.Pending(false)
+	if err != nil {
+		t.Fatalf("failed to retrieve pending transactions: %v", err)
+	}
+	return &txPoolSnapshot{chain: chain, pending: pending}
+}
+
+// worker creates a worker using the given ordering policy, building a block of
+// the given gas limit on top of the snapshot's chain.
+func (s *txPoolSnapshot) worker(t *testing.T, policy TxOrderingPolicy, gasLimit uint64) *worker {
+	w := &worker{
+		config:      testConfig,
+		chainConfig: s.chain.Config(),
+		engine:      s.chain.Engine(),
+		chain:       s.chain,
+		ordering:    policy,
+	}
+	parent := s.chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   gasLimit,
+		Time:       parent.Time() + 1,
+		Difficulty: parent.Difficulty(),
+		BaseFee:    misc.CalcBaseFee(s.chain.Config(), parent.Header()),
+	}
+	if err := w.makeCurrent(parent, header); err != nil {
+		t.Fatalf("failed to create block environment: %v", err)
+	}
+	t.Cleanup(func() { w.current.state.StopPrefetcher() })
+	return w
+}
+
+// replay fills a block of the given gas limit from the snapshot with a worker
+// using the given ordering policy, returning the included transactions.
+func (s *txPoolSnapshot) replay(t *testing.T, policy TxOrderingPolicy, gasLimit uint64) []*types.Transaction {
+	w := s.worker(t, policy, gasLimit)
+
+	// The iterator reowns the pending set, hand it a copy
+	pending := make(map[common.Address]types.Transactions, len(s.pending))
+	for addr, txs := range s.pending {
+		pending[addr] = append(types.Transactions{}, txs...)
+	}
+	w.commitTransactions(policy.Order(w.current.signer, pending, w.current.header.BaseFee), testBankAddress, nil)
+	return w.current.txs
+}
+
+func TestTxOrderingPolicies(t *testing.T) {
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		start  = time.Unix(1600000000, 0)
+		whale  = mustKey(t, 0)
+		small  = []*ecdsa.PrivateKey{mustKey(t, 1), mustKey(t, 2), mustKey(t, 3), mustKey(t, 4), mustKey(t, 5)}
+		labels = map[common.Address]string{crypto.PubkeyToAddress(whale.PublicKey): "whale"}
+		txs    []*types.Transaction
+	)
+	transfer := func(key *ecdsa.PrivateKey, nonce uint64, gwei int64, arrival int) *types.Transaction {
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1),
+			Gas:      params.TxGas,
+			GasPrice: new(big.Int).Mul(big.NewInt(gwei), big.NewInt(params.GWei)),
+		})
+		tx.SetTime(start.Add(time.Duration(arrival) * time.Second))
+		return tx
+	}
+	// Five small senders queue three cheap transactions each first, then a
+	// whale floods the pool with expensive ones
+	for nonce := 0; nonce < 3; nonce++ {
+		for i, key := range small {
+			labels[crypto.PubkeyToAddress(key.PublicKey)] = fmt.Sprintf("small%d", i)
+			txs = append(txs, transfer(key, uint64(nonce), 2, len(txs)))
+		}
+	}
+	for nonce := 0; nonce < 20; nonce++ {
+		txs = append(txs, transfer(whale, uint64(nonce), 50, len(txs)))
+	}
+	snapshot := newTxPoolSnapshot(t, txs)
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{
+			// The whale takes the whole block
+			PriceNonceOrdering,
+			[]string{"whale/0", "whale/1", "whale/2", "whale/3", "whale/4", "whale/5", "whale/6", "whale/7", "whale/8", "whale/9"},
+		},
+		{
+			// First come, first served
+			FIFOOrdering,
+			[]string{"small0/0", "small1/0", "small2/0", "small3/0", "small4/0", "small0/1", "small1/1", "small2/1", "small3/1", "small4/1"},
+		},
+		{
+			// Everyone gets one in before anyone gets a second, by price then arrival
+			FairOrdering,
+			[]string{"whale/0", "small0/0", "small1/0", "small2/0", "small3/0", "small4/0", "whale/1", "small0/1", "small1/1", "small2/1"},
+		},
+	}
+	for _, tt := range tests {
+		policy, err := NewTxOrderingPolicy(tt.policy)
+		if err != nil {
+			t.Fatalf("%s: failed to create policy: %v", tt.policy, err)
+		}
+		included := snapshot.replay(t, policy, 10*params.TxGas)
+
+		have := make([]string, len(included))
+		nonces := make(map[common.Address]uint64)
+		for i, tx := range included {
+			from, _ := types.Sender(signer, tx)
+			if tx.Nonce() != nonces[from] {
+				t.Fatalf("%s: transaction %d of %s out of nonce order: have %d, want %d", tt.policy, i, labels[from], tx.Nonce(), nonces[from])
+			}
+			nonces[from]++
+			have[i] = fmt.Sprintf("%s/%d", labels[from], tx.Nonce())
+		}
+		if fmt.Sprint(have) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: inclusion order mismatch:\nhave %v\nwant %v", tt.policy, have, tt.want)
+		}
+	}
+}
+
+func TestUnknownTxOrderingPolicy(t *testing.T) {
+	if _, err := NewTxOrderingPolicy("lottery"); err == nil {
+		t.Fatal("unknown policy accepted")
+	}
+	policy, err := NewTxOrderingPolicy("")
+	if err != nil || policy.Name() != PriceNonceOrdering {
+		t.Fatalf("default policy mismatch: have %v (%v), want %s", policy, err, PriceNonceOrdering)
+	}
+}
+
+// mustKey derives a deterministic private key from a seed.
+func mustKey(t *testing.T, seed byte) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte{seed}))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	return key
+}
@@ -19,6 +19,7 @@ package miner
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"math/big"
 	"sync"
 	"sync/atomic"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 )
@@ -77,6 +79,15 @@ const (
 	staleThreshold = 7
 )
 
+var (
+	buildTimer        = metrics.NewRegisteredTimer("miner/build", nil)        // Time taken to fill and assemble a block
+	slotDeadlineMeter = metrics.NewRegisteredMeter("miner/slotdeadline", nil) // Blocks cut short by their slot deadline
+	lateResubmitMeter = metrics.NewRegisteredMeter("miner/lateresubmit", nil) // Resubmits skipped past the slot deadline
+
+	bundleIncludedMeter = metrics.NewRegisteredMeter("miner/bundle/included", nil)
+	bundleSkippedMeter  = metrics.NewRegisteredMeter("miner/bundle/skipped", nil) // Bundles failing on top of the pending state
+)
+
 // environment is the worker's current environment and holds all of the current state information.
 type environment struct {
 	signer types.Signer
@@ -91,6 +102,7 @@ type environment struct {
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+	deadline time.Time // Time to stop adding transactions by, zero if none
 }
 
 // task contains all information for consensus engine sealing and result submitting.
@@ -128,6 +140,7 @@ type worker struct {
 	engine      consensus.Engine
 	eth         Backend
 	chain       *core.BlockChain
+	ordering    TxOrderingPolicy // Order pending transactions are offered to blocks in
 
 	// Feeds
 	pendingLogsFeed event.Feed
@@ -220,6 +233,14 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	worker.chainHeadSub = eth.BlockChain().SubscribeChainHeadEvent(worker.chainHeadCh)
 	worker.chainSideSub = eth.BlockChain().SubscribeChainSideEvent(worker.chainSideCh)
 
+	// Sanitize the transaction ordering policy, falling back to the default one
+	ordering, err := NewTxOrderingPolicy(worker.config.TxOrdering)
+	if err != nil {
+		log.Warn("Sanitizing invalid miner transaction ordering", "provided", worker.config.TxOrdering, "updated", PriceNonceOrdering)
+		ordering, _ = NewTxOrderingPolicy(PriceNonceOrdering)
+	}
+	worker.ordering = ordering
+
 	// Sanitize recommit interval if the user-specified one is too short.
 	recommit := worker.config.Recommit
 	if recommit < minRecommitInterval {
@@ -318,6 +339,29 @@ func (w *worker) isRunning() bool {
 	return atomic.LoadInt32(&w.running) == 1
 }
 
+// isZeroPeriod returns whether the consensus engine is a 0 period clique or bor,
+// which only seals blocks containing transactions.
+func (w *worker) isZeroPeriod() bool {
+	return (w.chainConfig.Clique != nil && w.chainConfig.Clique.Period == 0) ||
+		(w.chainConfig.Bor != nil && w.chainConfig.Bor.Period == 0)
+}
+
+// fillDeadline returns the time to stop adding transactions to the block of a
+// prepared header by, leaving the configured margin to seal and propagate it
+// before the end of the producer's slot. The zero time is returned if the
+// consensus engine doesn't assign slots.
+func (w *worker) fillDeadline(header *types.Header) time.Time {
+	engine, ok := w.engine.(consensus.Slotted)
+	if !ok {
+		return time.Time{}
+	}
+	slot, ok := engine.SlotDeadline(header)
+	if !ok {
+		return time.Time{}
+	}
+	return slot.Add(-w.config.SlotMargin)
+}
+
 // close terminates all background threads maintained by the worker.
 // Note the worker does not support being closed multiple times.
 func (w *worker) close() {
@@ -401,7 +445,7 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 		case <-timer.C:
 			// If mining is running resubmit a new work cycle periodically to pull in
 			// higher priced transactions. Disable this overhead for pending blocks.
-			if w.isRunning() && (w.chainConfig.Clique == nil || w.chainConfig.Clique.Period > 0) {
+			if w.isRunning() && !w.isZeroPeriod() {
 				// Short circuit if no new transaction arrives.
 				if atomic.LoadInt32(&w.newTxs) == 0 {
 					timer.Reset(recommit)
@@ -522,7 +566,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
+				txset := w.ordering.Order(w.current.signer, txs, w.current.header.BaseFee)
 				tcount := w.current.tcount
 				w.commitTransactions(txset, coinbase, nil)
 				// Only update the snapshot if any new transactons were added
@@ -531,10 +575,10 @@ func (w *worker) mainLoop() {
 					w.updateSnapshot()
 				}
 			} else {
-				// Special case, if the consensus engine is 0 period clique(dev mode),
+				// Special case, if the consensus engine is 0 period clique or bor (dev mode),
 				// submit mining work here since all empty submission will be rejected
-				// by clique. Of course the advance sealing(empty submission) is disabled.
-				if w.chainConfig.Clique != nil && w.chainConfig.Clique.Period == 0 {
+				// by them. Of course the advance sealing(empty submission) is disabled.
+				if w.isZeroPeriod() {
 					w.commitNewWork(nil, true, time.Now().Unix())
 				}
 			}
@@ -773,7 +817,80 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+// commitBundles includes the bundles targeting the current block, in arrival
+// order, each one either whole or not at all. It returns the number of bundles
+// included.
+func (w *worker) commitBundles(coinbase common.Address) int {
+	pool := w.eth.BundlePool()
+	if w.current == nil || pool == nil {
+		return 0
+	}
+	// Rolling back a bundle needs the state to be finalised, not hashed, between
+	// transactions
+	if !w.chainConfig.IsByzantium(w.current.header.Number) {
+		return 0
+	}
+	bundles := pool.Bundles(w.current.header.Number, w.current.header.Time)
+	if len(bundles) == 0 {
+		return 0
+	}
+	if w.current.gasPool == nil {
+		w.current.gasPool = new(core.GasPool).AddGas(w.current.header.GasLimit)
+	}
+	var included int
+	for _, bundle := range bundles {
+		if err := w.commitBundle(bundle, coinbase); err != nil {
+			log.Trace("Skipping bundle", "hash", bundle.Hash(), "err", err)
+			bundleSkippedMeter.Mark(1)
+			continue
+		}
+		included++
+	}
+	bundleIncludedMeter.Mark(int64(included))
+	return included
+}
+
+// commitBundle applies all the transactions of a bundle on top of the current
+// block, rolling the block back if any of them can't be included or reverts
+// without being allowed to.
+func (w *worker) commitBundle(bundle *core.Bundle, coinbase common.Address) error {
+	var (
+		snap    = w.current.state.MultiTxSnapshot()
+		gasPool = *w.current.gasPool
+		gasUsed = w.current.header.GasUsed
+		tcount  = w.current.tcount
+		txs     = len(w.current.txs)
+	)
+	rollback := func() {
+		w.current.state.RevertToSnapshot(snap)
+		*w.current.gasPool = gasPool
+		w.current.header.GasUsed = gasUsed
+		w.current.tcount = tcount
+		w.current.txs = w.current.txs[:txs]
+		w.current.receipts = w.current.receipts[:txs]
+	}
+	for _, tx := range bundle.Txs {
+		if tx.Protected() && !w.chainConfig.IsEIP155(w.current.header.Number) {
+			rollback()
+			return fmt.Errorf("transaction %x: replay protection not active", tx.Hash())
+		}
+		w.current.state.Prepare(tx.Hash(), w.current.tcount)
+		if _, err := w.commitTransaction(tx, coinbase); err != nil {
+			rollback()
+			return fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+		}
+		w.current.tcount++
+
+		if receipt := w.current.receipts[len(w.current.receipts)-1]; receipt.Status == types.ReceiptStatusFailed && !bundle.CanRevert(tx.Hash()) {
+			rollback()
+			return fmt.Errorf("transaction %x reverted", tx.Hash())
+		}
+	}
+	w.current.state.DiscardMultiTxSnapshot(snap)
+	return nil
+}
+
+func (w *worker) commitTransactions(txs TxIterator, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -807,6 +924,12 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			}
 			return atomic.LoadInt32(interrupt) == commitInterruptNewHead
 		}
+		// Leave the rest of the slot to sealing and propagating the block
+		if !w.current.deadline.IsZero() && time.Now().After(w.current.deadline) {
+			log.Debug("Slot deadline reached, sealing block", "number", w.current.header.Number, "txs", w.current.tcount)
+			slotDeadlineMeter.Mark(1)
+			break
+		}
 		// If we don't have enough gas for any further transactions then we're done
 		if w.current.gasPool.Gas() < params.TxGas {
 			log.Trace("Not enough gas for further transactions", "have", w.current.gasPool, "want", params.TxGas)
@@ -830,6 +953,18 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			txs.Pop()
 			continue
 		}
+		// Skip the account if the transaction's preconditions aren't met by the block
+		if conditional := tx.Conditional(); conditional != nil {
+			err := conditional.CheckBlock(w.current.header.Number, w.current.header.Time)
+			if err == nil {
+				err = w.current.state.ValidateKnownAccounts(conditional.KnownAccounts)
+			}
+			if err != nil {
+				log.Trace("Skipping conditional transaction", "sender", from, "hash", tx.Hash(), "err", err)
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		w.current.state.Prepare(tx.Hash(), w.current.tcount)
 
@@ -931,6 +1066,19 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		log.Error("Failed to prepare header for mining", "err", err)
 		return
 	}
+	deadline := w.fillDeadline(header)
+	if !deadline.IsZero() && tstart.After(deadline) {
+		// Rebuilding past the deadline would only replace the work being sealed
+		// with an emptier block
+		if noempty {
+			log.Debug("Skipping resubmit past slot deadline", "number", header.Number)
+			lateResubmitMeter.Mark(1)
+			return
+		}
+		// Late producers still get some transactions in rather than going empty,
+		// unless they have no time budget for it
+		deadline = tstart.Add(w.config.FillBudget)
+	}
 	// If we are care about TheDAO hard-fork check whether to override the extra-data or not
 	if daoBlock := w.chainConfig.DAOForkBlock; daoBlock != nil {
 		// Check whether the block is among the fork extra-override range
@@ -952,6 +1100,7 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	}
 	// Create the current work task and check any fork transitions needed
 	env := w.current
+	env.deadline = deadline
 	if w.chainConfig.DAOForkSupport && w.chainConfig.DAOForkBlock != nil && w.chainConfig.DAOForkBlock.Cmp(header.Number) == 0 {
 		misc.ApplyDAOHardFork(env.state)
 	}
@@ -986,6 +1135,9 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		w.commit(uncles, nil, false, tstart)
 	}
 
+	// Bundles go at the top of the block, ahead of the pending transactions
+	bundles := w.commitBundles(w.coinbase)
+
 	// Fill the block with all available pending transactions.
 	pending, err := w.eth.TxPool().Pending(true)
 	if err != nil {
@@ -995,7 +1147,7 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	// Short circuit if there is no available pending transactions.
 	// But if we disable empty precommit already, ignore it. Since
 	// empty block is necessary to keep the liveness of the network.
-	if len(pending) == 0 && atomic.LoadUint32(&w.noempty) == 0 {
+	if len(pending) == 0 && bundles == 0 && atomic.LoadUint32(&w.noempty) == 0 {
 		w.updateSnapshot()
 		return
 	}
@@ -1008,13 +1160,13 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs, header.BaseFee)
+		txs := w.ordering.Order(w.current.signer, localTxs, header.BaseFee)
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs, header.BaseFee)
+		txs := w.ordering.Order(w.current.signer, remoteTxs, header.BaseFee)
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
@@ -1038,6 +1190,9 @@ func (w *worker) commit(uncles []*types.Header, interval func(), update bool, st
 		}
 		select {
 		case w.taskCh <- &task{receipts: receipts, state: s, block: block, createdAt: time.Now()}:
+			if update {
+				buildTimer.UpdateSince(start)
+			}
 			w.unconfirmed.Shift(block.NumberU64() - 1)
 			log.Info("Commit new mining work", "number", block.Number(), "sealhash", w.engine.SealHash(block.Header()),
 				"uncles", len(uncles), "txs", w.current.tcount,
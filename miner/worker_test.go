@@ -17,6 +17,7 @@
 package miner
 
 import (
+	"crypto/ecdsa"
 	"math/big"
 	"math/rand"
 	"sync/atomic"
@@ -108,6 +109,7 @@ func init() {
 type testWorkerBackend struct {
 	db         ethdb.Database
 	txPool     *core.TxPool
+	bundlePool *core.BundlePool
 	chain      *core.BlockChain
 	testTxFeed event.Feed
 	genesis    *core.Genesis
@@ -127,7 +129,7 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 		e.Authorize(testBankAddress, func(account accounts.Account, s string, data []byte) ([]byte, error) {
 			return crypto.Sign(crypto.Keccak256(data), testBankKey)
 		})
-	case *ethash.Ethash:
+	case *ethash.Ethash, *slottedEngine:
 	default:
 		t.Fatalf("unexpected consensus engine type: %T", engine)
 	}
@@ -157,6 +159,7 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 		db:         db,
 		chain:      chain,
 		txPool:     txpool,
+		bundlePool: core.NewBundlePool(core.DefaultBundlePoolConfig, chainConfig, chain),
 		genesis:    &gspec,
 		uncleBlock: blocks[0],
 	}
@@ -164,6 +167,7 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 
 func (b *testWorkerBackend) BlockChain() *core.BlockChain { return b.chain }
 func (b *testWorkerBackend) TxPool() *core.TxPool         { return b.txPool }
+func (b *testWorkerBackend) BundlePool() *core.BundlePool { return b.bundlePool }
 
 func (b *testWorkerBackend) newRandomUncle() *types.Block {
 	var parent *types.Block
@@ -519,3 +523,148 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+// slottedEngine is a consensus engine giving each block a slot ending at a fixed
+// offset from the time its header is prepared.
+type slottedEngine struct {
+	consensus.Engine
+	offset time.Duration
+}
+
+func (e *slottedEngine) SlotDeadline(header *types.Header) (time.Time, bool) {
+	return time.Now().Add(e.offset), true
+}
+
+func TestSlotDeadline(t *testing.T) {
+	// Plenty of time left in the slot, the pending transaction gets in
+	testSlotDeadline(t, time.Hour, 0, 1)
+	// Slot already over, the block is sealed without filling it
+	testSlotDeadline(t, -time.Hour, 0, 0)
+	// Slot already over, but there's a budget to fill the block with
+	testSlotDeadline(t, -time.Hour, time.Hour, 1)
+}
+
+func testSlotDeadline(t *testing.T, offset time.Duration, budget time.Duration, want int) {
+	engine := &slottedEngine{Engine: ethash.NewFaker(), offset: offset}
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	config := *testConfig
+	config.FillBudget = budget
+	w.config = &config
+
+	taskCh := make(chan *task, 2)
+	w.newTaskHook = func(task *task) {
+		if task.block.NumberU64() == 1 {
+			select {
+			case taskCh <- task:
+			default:
+			}
+		}
+	}
+	w.skipSealHook = func(task *task) bool { return true }
+	w.start()
+
+	// The first task is the empty one, the second the filled one
+	for i := 0; i < 2; i++ {
+		select {
+		case task := <-taskCh:
+			if i == 1 && len(task.receipts) != want {
+				t.Errorf("slot offset %v: receipt number mismatch: have %d, want %d", offset, len(task.receipts), want)
+			}
+		case <-time.NewTimer(3 * time.Second).C:
+			t.Fatalf("slot offset %v: new task timeout", offset)
+		}
+	}
+}
+
+// Tests that transactions whose preconditions aren't met by the block being
+// built are skipped along with the following ones from the same account.
+func TestConditionalTransactions(t *testing.T) {
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		keys   = []*ecdsa.PrivateKey{mustKey(t, 1), mustKey(t, 2), mustKey(t, 3)}
+		slot   = common.HexToHash("0x01")
+		txs    []*types.Transaction
+	)
+	for _, key := range keys {
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			txs = append(txs, types.MustSignNewTx(key, signer, &types.LegacyTx{
+				Nonce:    nonce,
+				To:       &testUserAddress,
+				Value:    big.NewInt(1),
+				Gas:      params.TxGas,
+				GasPrice: big.NewInt(params.GWei),
+			}))
+		}
+	}
+	// The first account waits for a later block, the second one expects the
+	// current storage, the third one a storage change which didn't happen
+	txs[0].SetConditional(&types.TransactionConditional{BlockNumberMin: big.NewInt(5)})
+	txs[2].SetConditional(&types.TransactionConditional{KnownAccounts: types.KnownAccounts{
+		testUserAddress: {StorageSlots: map[common.Hash]common.Hash{slot: {}}},
+	}})
+	snapshot := newTxPoolSnapshot(t, txs)
+
+	// Tighten the preconditions after admission, as if the storage changed
+	// within the block being built
+	txs[4].SetConditional(&types.TransactionConditional{KnownAccounts: types.KnownAccounts{
+		testUserAddress: {StorageSlots: map[common.Hash]common.Hash{slot: common.HexToHash("0x01")}},
+	}})
+	included := snapshot.replay(t, priceNonceOrdering{}, 10*params.TxGas)
+	if len(included) != 2 || included[0].Hash() != txs[2].Hash() || included[1].Hash() != txs[3].Hash() {
+		t.Fatalf("included transactions mismatch: have %v, want %x and %x", included, txs[2].Hash(), txs[3].Hash())
+	}
+}
+
+// Tests that bundles are included whole at the top of the block, or not at all.
+func TestCommitBundles(t *testing.T) {
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		keys   = []*ecdsa.PrivateKey{mustKey(t, 1), mustKey(t, 2), mustKey(t, 3)}
+	)
+	transfer := func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(params.GWei),
+		})
+	}
+	// Fund the senders, their first transactions being pending
+	snapshot := newTxPoolSnapshot(t, []*types.Transaction{transfer(keys[0], 0), transfer(keys[1], 0), transfer(keys[2], 0)})
+
+	pool := core.NewBundlePool(core.DefaultBundlePoolConfig, params.TestChainConfig, snapshot.chain)
+	var (
+		good   = &core.Bundle{Txs: types.Transactions{transfer(keys[0], 0), transfer(keys[1], 0)}, BlockNumber: big.NewInt(1)}
+		broken = &core.Bundle{Txs: types.Transactions{transfer(keys[2], 0), transfer(keys[0], 5)}, BlockNumber: big.NewInt(1)}
+		later  = &core.Bundle{Txs: types.Transactions{transfer(keys[2], 0)}, BlockNumber: big.NewInt(2)}
+	)
+	for _, bundle := range []*core.Bundle{broken, good, later} {
+		if err := pool.Add(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	w := snapshot.worker(t, priceNonceOrdering{}, 10*params.TxGas)
+	w.eth = &mockBackend{bc: snapshot.chain, bundlePool: pool}
+
+	if included := w.commitBundles(testBankAddress); included != 1 {
+		t.Fatalf("included bundles mismatch: have %d, want %d", included, 1)
+	}
+	if len(w.current.txs) != 2 || w.current.txs[0].Hash() != good.Txs[0].Hash() || w.current.txs[1].Hash() != good.Txs[1].Hash() {
+		t.Fatalf("included transactions mismatch: have %d transactions, want bundle %x", len(w.current.txs), good.Hash())
+	}
+	if len(w.current.receipts) != 2 || w.current.tcount != 2 || w.current.header.GasUsed != 2*params.TxGas {
+		t.Fatalf("block accounting mismatch: receipts %d, count %d, gas %d", len(w.current.receipts), w.current.tcount, w.current.header.GasUsed)
+	}
+	// The broken bundle left no trace in the state
+	if nonce := w.current.state.GetNonce(crypto.PubkeyToAddress(keys[2].PublicKey)); nonce != 0 {
+		t.Fatalf("rolled back sender nonce mismatch: have %d, want %d", nonce, 0)
+	}
+	if gas := w.current.gasPool.Gas(); gas != 8*params.TxGas {
+		t.Fatalf("gas pool mismatch: have %d, want %d", gas, 8*params.TxGas)
+	}
+}
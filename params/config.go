@@ -433,6 +433,8 @@ type BorConfig struct {
 
 	OverrideStateSyncRecords map[string]int         `json:"overrideStateSyncRecords"` // override state records count
 	BlockAlloc               map[string]interface{} `json:"blockAlloc"`
+
+	MinTipCap *big.Int `json:"minTipCap,omitempty"` // Minimum gas tip cap validators accept, flooring gas price suggestions
 }
 
 // String implements the stringer interface, returning the consensus engine details.
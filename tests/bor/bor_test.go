@@ -1,6 +1,7 @@
 package bor
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"math/big"
@@ -10,10 +11,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/bor"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/tests/bor/mocks"
 )
 
@@ -174,8 +177,7 @@ func TestOutOfTurnSigning(t *testing.T) {
 	// This account is one the out-of-turn validators for 1st (0-indexed) span
 	signer := "c8deb0bea5c41afe8e37b4d1bd84e31adff11b09c8c96ff4b605003cce067cd9"
 	signerKey, _ := hex.DecodeString(signer)
-	key, _ = crypto.HexToECDSA(signer)
-	addr = crypto.PubkeyToAddress(key.PublicKey)
+	setSigner(t, signer)
 	expectedSuccessionNumber := 2
 
 	block = buildNextBlock(t, _bor, chain, block, signerKey, init.genesis.Config.Bor)
@@ -216,8 +218,7 @@ func TestSignerNotFound(t *testing.T) {
 	// random signer account that is not a part of the validator set
 	signer := "3714d99058cd64541433d59c6b391555b2fd9b54629c2b717a6c9c00d1127b6b"
 	signerKey, _ := hex.DecodeString(signer)
-	key, _ = crypto.HexToECDSA(signer)
-	addr = crypto.PubkeyToAddress(key.PublicKey)
+	setSigner(t, signer)
 
 	block = buildNextBlock(t, _bor, chain, block, signerKey, init.genesis.Config.Bor)
 	_, err := chain.InsertChain([]*types.Block{block})
@@ -268,3 +269,71 @@ func getSampleEventRecord(t *testing.T) *bor.EventRecordWithTime {
 	_eventRecords[0].Time = time.Unix(1, 0)
 	return _eventRecords[0]
 }
+
+func TestDevHeimdallStateSync(t *testing.T) {
+	init := buildEthereumInstance(t, rawdb.NewMemoryDatabase())
+	chain := init.ethereum.BlockChain()
+	engine := init.ethereum.Engine()
+	_bor := engine.(*bor.Bor)
+
+	h := bor.NewDevHeimdallClient(init.genesis.Config.ChainID, sprintSize, []*bor.Validator{bor.NewValidator(addr, 10)})
+	_bor.SetHeimdallClient(h)
+
+	// Backdate the injected event so it falls before the first sprint's reference block
+	sample := getSampleEventRecord(t)
+	h.InjectStateSyncAt(sample.Contract, sample.Data, time.Unix(1, 0))
+
+	db := init.ethereum.ChainDb()
+	block := init.genesis.ToBlock(db)
+	for i := uint64(1); i <= sprintSize; i++ {
+		block = buildNextBlock(t, _bor, chain, block, nil, init.genesis.Config.Bor)
+		insertNewBlock(t, chain, block)
+	}
+	lastStateID, _ := _bor.GenesisContractsClient.LastStateId(sprintSize)
+	assert.Equal(t, uint64(1), lastStateID.Uint64())
+}
+
+func TestSimulateSprintEnd(t *testing.T) {
+	init := buildEthereumInstance(t, rawdb.NewMemoryDatabase())
+	chain := init.ethereum.BlockChain()
+	engine := init.ethereum.Engine()
+	_bor := engine.(*bor.Bor)
+
+	h := bor.NewDevHeimdallClient(init.genesis.Config.ChainID, sprintSize, []*bor.Validator{bor.NewValidator(addr, 10)})
+	_bor.SetHeimdallClient(h)
+
+	sample := getSampleEventRecord(t)
+	h.InjectStateSyncAt(sample.Contract, sample.Data, time.Unix(1, 0))
+
+	// Stop right before the sprint start and simulate it on top of the head
+	db := init.ethereum.ChainDb()
+	block := init.genesis.ToBlock(db)
+	for i := uint64(1); i < sprintSize; i++ {
+		block = buildNextBlock(t, _bor, chain, block, nil, init.genesis.Config.Bor)
+		insertNewBlock(t, chain, block)
+	}
+	sim, err := _bor.SimulateSprintEnd(context.Background(), chain, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to simulate sprint end: %v", err)
+	}
+	assert.Equal(t, hexutil.Uint64(sprintSize), sim.Number)
+	assert.Equal(t, block.Hash(), sim.ParentHash)
+	assert.Equal(t, 1, len(sim.StateSyncs))
+
+	var commits int
+	for _, call := range sim.SystemCalls {
+		if call.Method == "commitState" {
+			commits++
+			// The sample receiver has no code, which the state receiver reports
+			assert.True(t, call.Failed)
+			assert.Empty(t, call.Error)
+			assert.NotZero(t, call.GasUsed)
+		}
+	}
+	assert.Equal(t, 1, commits)
+	assert.Contains(t, sim.StateDiff, common.HexToAddress(init.genesis.Config.Bor.StateReceiverContract))
+
+	// Nothing may have been persisted
+	lastStateID, _ := _bor.GenesisContractsClient.LastStateId(sprintSize - 1)
+	assert.Equal(t, uint64(0), lastStateID.Uint64())
+}
@@ -170,3 +170,14 @@ func getSignerKey(number uint64) []byte {
 	_key, _ := hex.DecodeString(signerKey)
 	return _key
 }
+
+// setSigner swaps the package level signer for the given key until the end of
+// the test.
+func setSigner(t *testing.T, hexkey string) {
+	prevKey, prevAddr := key, addr
+	t.Cleanup(func() {
+		key, addr = prevKey, prevAddr
+	})
+	key, _ = crypto.HexToECDSA(hexkey)
+	addr = crypto.PubkeyToAddress(key.PublicKey)
+}
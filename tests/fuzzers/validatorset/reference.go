@@ -0,0 +1,224 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package validatorset
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+)
+
+// refValidator is a validator of the reference model. Priorities are tracked
+// with arbitrary precision so that any clipping in the implementation under
+// test shows up as a divergence.
+type refValidator struct {
+	address  common.Address
+	power    int64
+	priority *big.Int
+}
+
+// refValidatorSet is a straightforward model of the Tendermint proposer
+// selection (as specified for Tendermint v0.32, which Heimdall runs), written
+// independently of the bor implementation to be differentially tested with it.
+type refValidatorSet struct {
+	validators []*refValidator // Sorted by address
+	proposer   common.Address
+}
+
+// newRefValidatorSet creates a reference set from the initial validators and
+// selects its first proposer, mirroring bor.NewValidatorSet.
+func newRefValidatorSet(changes []*bor.Validator) (*refValidatorSet, error) {
+	set := new(refValidatorSet)
+	if err := set.update(changes, false); err != nil {
+		return nil, err
+	}
+	if len(set.validators) > 0 {
+		set.increment(1)
+	}
+	return set, nil
+}
+
+// total returns the sum of all voting powers in the set.
+func (s *refValidatorSet) total() int64 {
+	var total int64
+	for _, val := range s.validators {
+		total += val.power
+	}
+	return total
+}
+
+// find returns the validator with the given address, or nil.
+func (s *refValidatorSet) find(addr common.Address) *refValidator {
+	for _, val := range s.validators {
+		if val.address == addr {
+			return val
+		}
+	}
+	return nil
+}
+
+// update applies a changeset: zero powers remove validators, others update or
+// add them. Newly added validators are penalised with a priority of -1.125
+// times the total power after the updates (but before the removals). The set
+// is left untouched on error.
+func (s *refValidatorSet) update(changes []*bor.Validator, allowDeletes bool) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	sorted := make([]*bor.Validator, len(changes))
+	copy(sorted, changes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Address[:], sorted[j].Address[:]) < 0
+	})
+	var updates, removals []*bor.Validator
+	for i, change := range sorted {
+		switch {
+		case i > 0 && sorted[i-1].Address == change.Address:
+			return errors.New("duplicate change")
+		case change.Address == (common.Address{}):
+			// Not part of Tendermint, but bor's duplicate check is seeded with
+			// the zero address, rejecting it. Harmless as it can't sign.
+			return errors.New("zero address")
+		case change.VotingPower < 0:
+			return errors.New("negative power")
+		case change.VotingPower > bor.MaxTotalVotingPower:
+			return errors.New("power too high")
+		case change.VotingPower == 0:
+			removals = append(removals, change)
+		default:
+			updates = append(updates, change)
+		}
+	}
+	if !allowDeletes && len(removals) > 0 {
+		return errors.New("removals not allowed")
+	}
+	for _, removal := range removals {
+		if s.find(removal.Address) == nil {
+			return errors.New("removing unknown validator")
+		}
+	}
+	// The total power limit is enforced after each update, in address order
+	total, added := s.total(), 0
+	for _, change := range updates {
+		if val := s.find(change.Address); val != nil {
+			total += change.VotingPower - val.power
+		} else {
+			total += change.VotingPower
+			added++
+		}
+		if total > bor.MaxTotalVotingPower {
+			return errors.New("total power too high")
+		}
+	}
+	if added == 0 && len(removals) == len(s.validators) {
+		return errors.New("empty validator set")
+	}
+	// All checks passed, apply the changes
+	penalty := big.NewInt(-(total + total/8))
+	for _, change := range updates {
+		if val := s.find(change.Address); val != nil {
+			val.power = change.VotingPower
+		} else {
+			s.validators = append(s.validators, &refValidator{
+				address:  change.Address,
+				power:    change.VotingPower,
+				priority: new(big.Int).Set(penalty),
+			})
+		}
+	}
+	for _, removal := range removals {
+		for i, val := range s.validators {
+			if val.address == removal.Address {
+				s.validators = append(s.validators[:i], s.validators[i+1:]...)
+				break
+			}
+		}
+	}
+	sort.Slice(s.validators, func(i, j int) bool {
+		return bytes.Compare(s.validators[i].address[:], s.validators[j].address[:]) < 0
+	})
+	s.rescale()
+	s.center()
+	return nil
+}
+
+// rescale divides all priorities by ceil(spread / (2 * total)) if the spread of
+// the priorities exceeds twice the total power, rounding towards zero.
+func (s *refValidatorSet) rescale() {
+	window := new(big.Int).Mul(big.NewInt(bor.PriorityWindowSizeFactor), big.NewInt(s.total()))
+	if window.Sign() <= 0 {
+		return
+	}
+	min, max := s.validators[0].priority, s.validators[0].priority
+	for _, val := range s.validators {
+		if val.priority.Cmp(min) < 0 {
+			min = val.priority
+		}
+		if val.priority.Cmp(max) > 0 {
+			max = val.priority
+		}
+	}
+	spread := new(big.Int).Sub(max, min)
+	if spread.Cmp(window) <= 0 {
+		return
+	}
+	ratio := new(big.Int).Add(spread, window)
+	ratio.Sub(ratio, big.NewInt(1))
+	ratio.Quo(ratio, window)
+
+	for _, val := range s.validators {
+		val.priority = new(big.Int).Quo(val.priority, ratio)
+	}
+}
+
+// center subtracts the average priority, rounded towards negative infinity,
+// from all priorities.
+func (s *refValidatorSet) center() {
+	sum := new(big.Int)
+	for _, val := range s.validators {
+		sum.Add(sum, val.priority)
+	}
+	avg := sum.Div(sum, big.NewInt(int64(len(s.validators))))
+	for _, val := range s.validators {
+		val.priority = new(big.Int).Sub(val.priority, avg)
+	}
+}
+
+// increment runs the given number of proposer selection rounds: everyone's
+// priority grows by their power, then the validator with the highest priority
+// (lowest address on ties) is elected and pays back the total power.
+func (s *refValidatorSet) increment(times int) {
+	s.rescale()
+	s.center()
+
+	total := big.NewInt(s.total())
+	for i := 0; i < times; i++ {
+		var elected *refValidator
+		for _, val := range s.validators {
+			val.priority = new(big.Int).Add(val.priority, big.NewInt(val.power))
+			if elected == nil || val.priority.Cmp(elected.priority) > 0 {
+				elected = val
+			}
+		}
+		elected.priority = new(big.Int).Sub(elected.priority, total)
+		s.proposer = elected.address
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package validatorset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bor"
+)
+
+const (
+	maxValidators = 16 // Maximum number of validators in the initial set
+	maxChanges    = 6  // Maximum number of entries in a changeset
+	maxRounds     = 64 // Maximum number of proposer rounds in a single increment
+	addressSpace  = 24 // Number of distinct addresses, small to hit updates and duplicates
+)
+
+type fuzzer struct {
+	input     io.Reader
+	exhausted bool
+}
+
+func (f *fuzzer) read(size int) []byte {
+	out := make([]byte, size)
+	if _, err := f.input.Read(out); err != nil {
+		f.exhausted = true
+	}
+	return out
+}
+
+func (f *fuzzer) readUint64(min, max uint64) uint64 {
+	if min == max {
+		return min
+	}
+	var a uint64
+	if err := binary.Read(f.input, binary.LittleEndian, &a); err != nil {
+		f.exhausted = true
+	}
+	return min + a%(max-min)
+}
+
+func (f *fuzzer) readAddress() common.Address {
+	// Index 0 maps to the zero address, which bor rejects
+	return common.BytesToAddress([]byte{byte(f.readUint64(0, addressSpace))})
+}
+
+// readPower returns a voting power, mostly small ones to exercise the selection
+// order, but also large ones to exercise rescaling, clipping and the limits.
+// Unless invalid powers are requested, removals and negative powers are skipped.
+func (f *fuzzer) readPower(invalid bool) int64 {
+	kind := f.read(1)[0] % 8
+	if !invalid && kind < 2 {
+		kind += 4
+	}
+	switch kind {
+	case 0:
+		return 0
+	case 1:
+		return -int64(f.readUint64(1, 1000))
+	case 2:
+		return int64(f.readUint64(1, uint64(bor.MaxTotalVotingPower)/maxValidators))
+	case 3:
+		return int64(f.readUint64(uint64(bor.MaxTotalVotingPower)/maxValidators, uint64(bor.MaxTotalVotingPower)+2))
+	default:
+		return int64(f.readUint64(1, 1000))
+	}
+}
+
+func (f *fuzzer) readChanges(min, max uint64, invalid bool) []*bor.Validator {
+	changes := make([]*bor.Validator, f.readUint64(min, max))
+	for i := range changes {
+		changes[i] = bor.NewValidator(f.readAddress(), f.readPower(invalid))
+	}
+	return changes
+}
+
+// The function must return
+// 1 if the fuzzer should increase priority of the
+//    given input during subsequent fuzzing (for example, the input is lexically
+//    correct and was parsed successfully);
+// -1 if the input must not be added to corpus even if gives new coverage; and
+// 0  otherwise
+// other values are reserved for future use.
+func Fuzz(data []byte) int {
+	f := fuzzer{
+		input:     bytes.NewReader(data),
+		exhausted: false,
+	}
+	return f.fuzz()
+}
+
+func (f *fuzzer) fuzz() int {
+	// Create the initial set, bor panics where the reference model errors
+	changes := f.readChanges(1, maxValidators+1, false)
+	if f.exhausted {
+		return -1
+	}
+	ref, err := newRefValidatorSet(changes)
+	if err != nil {
+		return 0
+	}
+	set := bor.NewValidatorSet(changes)
+	compare("init", set, ref, true)
+
+	// Run proposer rounds and changesets against both implementations
+	for op := 0; !f.exhausted; op++ {
+		if f.read(1)[0]%2 == 0 {
+			times := int(f.readUint64(1, maxRounds+1))
+			set.IncrementProposerPriority(times)
+			ref.increment(times)
+			compare(fmt.Sprintf("op %d: increment %d", op, times), set, ref, true)
+		} else {
+			changes := f.readChanges(1, maxChanges+1, true)
+			refErr := ref.update(changes, true)
+			setErr := set.UpdateWithChangeSet(changes)
+			if (setErr == nil) != (refErr == nil) {
+				panic(fmt.Sprintf("op %d: changeset error mismatch: have %v, want %v", op, setErr, refErr))
+			}
+			compare(fmt.Sprintf("op %d: update", op), set, ref, false)
+		}
+	}
+	return 1
+}
+
+// compare panics if the bor validator set diverged from the reference model,
+// or if it violates the invariants of the proposer selection.
+func compare(ctx string, set *bor.ValidatorSet, ref *refValidatorSet, proposer bool) {
+	if len(set.Validators) != len(ref.validators) {
+		panic(fmt.Sprintf("%s: validator count mismatch: have %d, want %d", ctx, len(set.Validators), len(ref.validators)))
+	}
+	for i, val := range set.Validators {
+		want := ref.validators[i]
+		if val.Address != want.address || val.VotingPower != want.power {
+			panic(fmt.Sprintf("%s: validator %d mismatch: have %x/%d, want %x/%d", ctx, i, val.Address, val.VotingPower, want.address, want.power))
+		}
+		if !want.priority.IsInt64() || val.ProposerPriority != want.priority.Int64() {
+			panic(fmt.Sprintf("%s: validator %x priority mismatch: have %d, want %v", ctx, val.Address, val.ProposerPriority, want.priority))
+		}
+	}
+	if set.TotalVotingPower() != ref.total() {
+		panic(fmt.Sprintf("%s: total power mismatch: have %d, want %d", ctx, set.TotalVotingPower(), ref.total()))
+	}
+	if !proposer {
+		return
+	}
+	if have := set.GetProposer().Address; have != ref.proposer {
+		panic(fmt.Sprintf("%s: proposer mismatch: have %x, want %x", ctx, have, ref.proposer))
+	}
+	// Selection rounds conserve the sum of priorities, which centering leaves
+	// within [0, n) as the average is rounded down
+	sum := new(big.Int)
+	for _, val := range set.Validators {
+		sum.Add(sum, big.NewInt(val.ProposerPriority))
+	}
+	if sum.Sign() < 0 || sum.Cmp(big.NewInt(int64(len(set.Validators)))) >= 0 {
+		panic(fmt.Sprintf("%s: priority sum %d out of bounds", ctx, sum))
+	}
+}